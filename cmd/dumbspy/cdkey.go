@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/netip"
+
+	"github.com/dogclan/dumbspy/internal"
+	"github.com/dogclan/dumbspy/internal/metrics"
+	"github.com/dogclan/dumbspy/pkg/gamespy"
+	"github.com/dogclan/dumbspy/pkg/gamespy/cdkey"
+
+	"github.com/rs/zerolog/log"
+)
+
+const serviceCDKey = "cdkey"
+
+// cdkeyServer Validates the CD keys of players joining game servers.
+type cdkeyServer struct {
+	validator internal.CDKeyValidator
+}
+
+// serve Handles packets received on conn until ctx is done.
+func (s *cdkeyServer) serve(ctx context.Context, conn *net.UDPConn) {
+	servePackets(ctx, serviceCDKey, conn, s.handlePacket)
+}
+
+// handlePacket Handles a packet received from addr. Returns the reply to send, if any.
+func (s *cdkeyServer) handlePacket(addr netip.AddrPort, b []byte) ([]byte, error) {
+	packet, err := cdkey.Decode(b)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Debug().
+		Bytes(logKeyData, packet.Bytes()).
+		Str(logKeyRemote, addr.String()).
+		Msg("Received cd key packet")
+
+	switch cmd := command(packet); cmd {
+	case "auth", "resp":
+		var req internal.GamespyCDKeyAuthRequest
+		if err = packet.Bind(&req); err != nil {
+			return nil, err
+		}
+
+		res, err2 := s.handleAuth(req)
+		if err2 != nil {
+			return nil, err2
+		}
+		return cdkey.Encode(res), nil
+	case "ka":
+		return cdkey.Encode(gamespy.NewPacket(gamespy.KeyValuePair{Key: "ka"})), nil
+	default:
+		// Includes \disc\, sent when players leave the server, which requires no reply
+		log.Debug().
+			Str("command", cmd).
+			Str(logKeyRemote, addr.String()).
+			Msg("Ignoring unsupported cd key packet")
+		return nil, nil
+	}
+}
+
+// handleAuth Returns the response telling the game server whether the player's CD key is valid.
+func (s *cdkeyServer) handleAuth(req internal.GamespyCDKeyAuthRequest) (*gamespy.Packet, error) {
+	hash := req.CDKeyHash()
+	if hash == "" {
+		return nil, fmt.Errorf("response too short to contain cd key hash")
+	}
+
+	res := internal.GamespyCDKeyAuthResponse{
+		CDKeyHash:  hash,
+		SessionKey: req.SessionKey,
+	}
+	if err := s.validator.Validate(hash); err != nil {
+		log.Info().
+			Err(err).
+			Str("hash", hash).
+			Str("ip", req.IP).
+			Msg("Rejected cd key")
+		metrics.CDKeyChecks.With("rejected").Inc()
+
+		res.NotOK = internal.ToPointer("")
+		res.ErrorMessage = internal.ToPointer("Invalid CD Key")
+	} else {
+		metrics.CDKeyChecks.With("accepted").Inc()
+		res.OK = internal.ToPointer("")
+	}
+
+	return gamespy.Marshal(res)
+}
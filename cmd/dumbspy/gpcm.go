@@ -0,0 +1,406 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"net"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/dogclan/dumbspy/internal"
+	"github.com/dogclan/dumbspy/internal/metrics"
+	"github.com/dogclan/dumbspy/internal/presence"
+	"github.com/dogclan/dumbspy/internal/ratelimit"
+	"github.com/dogclan/dumbspy/pkg/gamespy"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	serviceGPCM = "gpcm"
+
+	// handshakeTimeout is the time a client has to respond to the challenge prompt
+	handshakeTimeout = time.Second
+
+	// redactedValue replaces secrets in logged packets
+	redactedValue = "***"
+)
+
+type gpcmServer struct {
+	keepAliveInterval time.Duration
+	idleTimeout       time.Duration
+	maxPacketSize     int
+	authenticator     internal.Authenticator
+	players           *internal.PlayerRegistry
+	profiles          *internal.ProfileStore
+	presence          *presence.Hub
+	messageInterval   time.Duration
+	messageBurst      int
+}
+
+func (s *gpcmServer) handleRequest(ctx context.Context, c net.Conn) {
+	conn := newPacketConn(c, serviceGPCM, s.maxPacketSize)
+	remoteAddr := conn.RemoteAddr().String()
+	defer func(conn net.Conn) {
+		err := conn.Close()
+		if err != nil {
+			log.Error().
+				Err(err).
+				Str(logKeyRemote, remoteAddr).
+				Msg("Failed to close connection")
+		}
+	}(conn)
+
+	start := time.Now()
+	challenge := gamespy.RandString(10)
+	prompt, err := gamespy.Marshal(internal.GamespyLoginChallenge{
+		LoginCode: 1,
+		Challenge: challenge,
+		ID:        1,
+	})
+	if err != nil {
+		log.Error().
+			Err(err).
+			Str(logKeyRemote, remoteAddr).
+			Msg("Failed to marshal challenge")
+		return
+	}
+
+	log.Debug().
+		Bytes(logKeyData, prompt.Bytes()).
+		Str(logKeyRemote, remoteAddr).
+		Msg("Sending challenge prompt")
+	if err = conn.write(prompt); err != nil {
+		log.Error().
+			Err(err).
+			Str(logKeyRemote, remoteAddr).
+			Msg("Failed to send challenge")
+		return
+	}
+
+	log.Debug().
+		Str(logKeyRemote, remoteAddr).
+		Msg("Reading login request")
+	req, err := conn.read(ctx, time.Now().Add(handshakeTimeout))
+	if err != nil {
+		// EOF and timeout errors are not of interest => only log to debug
+		if isPeerClosed(err) {
+			log.Debug().
+				Str(logKeyRemote, remoteAddr).
+				Msg("Peer closed/reset connection while reading login request")
+		} else if errors.Is(err, os.ErrDeadlineExceeded) {
+			metrics.ReadTimeouts.With(serviceGPCM).Inc()
+			log.Debug().
+				Str(logKeyRemote, remoteAddr).
+				Msg("Timed out reading login request")
+			writeError(conn, remoteAddr, gamespy.NewError(gamespy.ErrorCodeLoginTimeout), 1)
+		} else {
+			log.Error().
+				Err(err).
+				Str(logKeyRemote, remoteAddr).
+				Msg("Failed to read login request")
+		}
+		return
+	}
+
+	log.Debug().
+		Bytes(logKeyData, redactLoginRequest(req).Bytes()).
+		Str(logKeyRemote, remoteAddr).
+		Msg("Received login request")
+
+	var login internal.GamespyLoginRequest
+	if err = cmp.Or(req.Bind(&login), login.Validate()); err != nil {
+		log.Error().
+			Err(err).
+			Str(logKeyRemote, remoteAddr).
+			Msg("Received invalid login request")
+		field := failedField(err)
+		metrics.LoginsRejected.With(field).Inc()
+
+		// Error is fatal, so there is no session to serve after sending the response
+		writeError(conn, remoteAddr, gamespy.NewError(invalidLoginErrorCode(field)), 1)
+		return
+	}
+
+	hash, err := s.authenticator.Authenticate(login, challenge)
+	if err != nil {
+		log.Warn().
+			Err(err).
+			Stringer("mode", login.Mode()).
+			Str("nick", login.Nick()).
+			Str(logKeyRemote, remoteAddr).
+			Msg("Rejected login request")
+		metrics.LoginsRejected.With("Response").Inc()
+
+		writeError(conn, remoteAddr, gamespy.NewError(rejectedLoginErrorCode(login, err)), 1)
+		return
+	}
+
+	playerID := s.players.GetPlayerID(
+		login.Nick(),
+		login.ProductID,
+		login.GameName,
+		login.NamespaceID,
+		login.SDKRevision,
+	)
+	var uniqueNick *string
+	if login.Mode() != internal.LoginModeUser {
+		uniqueNick = internal.ToPointer(login.Nick())
+	}
+	res, err := gamespy.Marshal(internal.GamespyLoginResponse{
+		LoginCode:  2,
+		SessionKey: int(gamespy.ComputeCRC16(login.Identity())),
+		Proof: gamespy.GenerateProof(
+			login.Identity(),
+			hash,
+			challenge,
+			login.Challenge,
+		),
+		UserID:      playerID,
+		ProfileID:   playerID,
+		UniqueNick:  uniqueNick,
+		LoginTicket: gamespy.RandString(22) + "__",
+		ID:          1,
+	})
+	if err != nil {
+		log.Error().
+			Err(err).
+			Str(logKeyRemote, remoteAddr).
+			Msg("Failed to marshal login response")
+		return
+	}
+
+	log.Debug().
+		Bytes(logKeyData, res.Bytes()).
+		Str(logKeyRemote, remoteAddr).
+		Msg("Sending login response")
+
+	if err = conn.write(res); err != nil {
+		log.Error().
+			Err(err).
+			Str(logKeyRemote, remoteAddr).
+			Msg("Failed to send response")
+		return
+	}
+
+	metrics.LoginsAccepted.Inc()
+	metrics.HandshakeDuration.Observe(time.Since(start).Seconds())
+
+	if _, err = s.profiles.Create(internal.NewLoginProfile(playerID, login)); err != nil {
+		// Profile falls back to defaults, so the session remains usable
+		log.Error().
+			Err(err).
+			Int("profileID", playerID).
+			Str(logKeyRemote, remoteAddr).
+			Msg("Failed to create profile")
+	}
+
+	// Port has been validated to be numeric
+	port, _ := strconv.Atoi(login.Port)
+	session := s.presence.Connect(playerID, gamePeer(conn.RemoteAddr(), port), conn.write)
+	defer session.Close()
+
+	s.serveSession(ctx, conn, remoteAddr, playerID, session)
+}
+
+// redactLoginRequest Returns a copy of the login request for logging, with the auth token (a secret) redacted.
+func redactLoginRequest(req *gamespy.Packet) *gamespy.Packet {
+	redacted := gamespy.NewPacket()
+	for element := range req.All() {
+		if element.Key == "authtoken" {
+			element.Value = redactedValue
+		}
+		redacted.Add(element.Key, element.Value)
+	}
+	return redacted
+}
+
+// failedField Returns the name of the first login request field which failed validation.
+func failedField(err error) string {
+	var validationErrors validator.ValidationErrors
+	if errors.As(err, &validationErrors) && len(validationErrors) > 0 {
+		return validationErrors[0].Field()
+	}
+
+	// Packet could not be bound to the login request
+	return "packet"
+}
+
+// invalidLoginErrorCode Returns the error code for a login request which failed validation on the given field.
+func invalidLoginErrorCode(field string) gamespy.ErrorCode {
+	switch field {
+	case "UniqueNick":
+		return gamespy.ErrorCodeLoginBadUniqueNick
+	case "User":
+		return gamespy.ErrorCodeLoginBadNick
+	case "AuthToken":
+		return gamespy.ErrorCodeLoginBadPreAuth
+	default:
+		return gamespy.ErrorCodeParse
+	}
+}
+
+// rejectedLoginErrorCode Returns the error code for a login request which failed authentication.
+func rejectedLoginErrorCode(login internal.GamespyLoginRequest, err error) gamespy.ErrorCode {
+	switch {
+	case errors.Is(err, internal.ErrUnsupportedLoginMode):
+		return gamespy.ErrorCodeLoginBadPreAuth
+	case errors.Is(err, internal.ErrUnknownAccount) && login.Mode() == internal.LoginModeUser:
+		return gamespy.ErrorCodeLoginBadNick
+	case errors.Is(err, internal.ErrUnknownAccount):
+		return gamespy.ErrorCodeLoginBadUniqueNick
+	case errors.Is(err, internal.ErrWrongResponse):
+		return gamespy.ErrorCodeLoginBadPassword
+	default:
+		return gamespy.ErrorCodeLogin
+	}
+}
+
+// writeError Sends the error as response to the request with the given id.
+func writeError(conn *packetConn, remoteAddr string, gpErr *gamespy.Error, id int) {
+	res := gpErr.Packet(id)
+
+	log.Debug().
+		Bytes(logKeyData, res.Bytes()).
+		Str(logKeyRemote, remoteAddr).
+		Msg("Sending error response")
+
+	if err := conn.write(res); err != nil {
+		log.Error().
+			Err(err).
+			Str(logKeyRemote, remoteAddr).
+			Msg("Failed to send response")
+	}
+}
+
+// serveSession Keeps the connection of a logged in client open until the client logs out, closes the connection,
+// stops sending packets for longer than the idle timeout or ctx is done. Keep-alive packets are sent to the client
+// in between.
+func (s *gpcmServer) serveSession(
+	ctx context.Context,
+	conn *packetConn,
+	remoteAddr string,
+	playerID int,
+	session *presence.Session,
+) {
+	log.Info().
+		Int("profileID", playerID).
+		Str(logKeyRemote, remoteAddr).
+		Msg("Client logged in")
+
+	stop := interruptOnDone(ctx, conn)
+	defer stop()
+
+	messageLimit := ratelimit.NewBucket(s.messageInterval, s.messageBurst)
+	keepAlive := gamespy.NewPacket(gamespy.KeyValuePair{Key: "ka"})
+	lastActivity := time.Now()
+	nextKeepAlive := lastActivity.Add(s.keepAliveInterval)
+	for ctx.Err() == nil {
+		idleDeadline := lastActivity.Add(s.idleTimeout)
+		packet, err := conn.read(ctx, minTime(nextKeepAlive, idleDeadline))
+		if errors.Is(err, os.ErrDeadlineExceeded) {
+			if ctx.Err() != nil {
+				break
+			}
+
+			now := time.Now()
+			if !now.Before(idleDeadline) {
+				metrics.ReadTimeouts.With(serviceGPCM).Inc()
+				log.Info().
+					Int("profileID", playerID).
+					Str(logKeyRemote, remoteAddr).
+					Msg("Closing idle session")
+				return
+			}
+
+			if !now.Before(nextKeepAlive) {
+				log.Debug().
+					Str(logKeyRemote, remoteAddr).
+					Msg("Sending keep-alive")
+				if err = conn.write(keepAlive); err != nil {
+					log.Error().
+						Err(err).
+						Str(logKeyRemote, remoteAddr).
+						Msg("Failed to send keep-alive")
+					return
+				}
+				nextKeepAlive = now.Add(s.keepAliveInterval)
+			}
+			continue
+		} else if err != nil {
+			if isPeerClosed(err) {
+				log.Info().
+					Int("profileID", playerID).
+					Str(logKeyRemote, remoteAddr).
+					Msg("Peer closed/reset session connection")
+			} else {
+				log.Error().
+					Err(err).
+					Str(logKeyRemote, remoteAddr).
+					Msg("Failed to read session packet")
+			}
+			return
+		}
+
+		lastActivity = time.Now()
+
+		log.Debug().
+			Bytes(logKeyData, packet.Bytes()).
+			Str(logKeyRemote, remoteAddr).
+			Msg("Received session packet")
+
+		switch cmd := command(packet); cmd {
+		case "ka":
+			// Keep-alives only need to reset the idle timer
+		case "status", "addbuddy", "authadd", "delbuddy", "bm":
+			err = s.handlePresence(session, messageLimit, cmd, packet)
+			if errors.Is(err, errFloodLimit) {
+				// Flooding clients would otherwise flood the log as well
+				log.Debug().
+					Int("profileID", playerID).
+					Str(logKeyRemote, remoteAddr).
+					Msg("Dropped buddy message due to flood limit")
+			} else if err != nil {
+				log.Warn().
+					Err(err).
+					Str("command", cmd).
+					Str(logKeyRemote, remoteAddr).
+					Msg("Failed to handle presence packet")
+			}
+		case "getprofile", "updatepro", "updateui":
+			if err = s.handleProfile(conn, playerID, cmd, packet); err != nil {
+				log.Warn().
+					Err(err).
+					Str("command", cmd).
+					Str(logKeyRemote, remoteAddr).
+					Msg("Failed to handle profile packet")
+			}
+		case "logout":
+			log.Info().
+				Int("profileID", playerID).
+				Str(logKeyRemote, remoteAddr).
+				Msg("Client logged out")
+			return
+		default:
+			log.Debug().
+				Str("command", cmd).
+				Str(logKeyRemote, remoteAddr).
+				Msg("Ignoring unsupported session packet")
+		}
+	}
+
+	log.Info().
+		Int("profileID", playerID).
+		Str(logKeyRemote, remoteAddr).
+		Msg("Closing session due to shutdown")
+}
+
+func minTime(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
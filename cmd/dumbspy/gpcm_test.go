@@ -0,0 +1,104 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dogclan/dumbspy/internal"
+	"github.com/dogclan/dumbspy/internal/presence"
+	"github.com/dogclan/dumbspy/internal/storage"
+	"github.com/dogclan/dumbspy/pkg/gamespy"
+)
+
+func newTestGPCMServer(t *testing.T, store storage.Store) *gpcmServer {
+	players, err := internal.NewPlayerRegistry(store)
+	require.NoError(t, err)
+
+	return &gpcmServer{
+		keepAliveInterval: time.Minute,
+		idleTimeout:       2 * time.Minute,
+		maxPacketSize:     gamespy.DefaultMaxPacketSize,
+		authenticator:     internal.AcceptAllAuthenticator{},
+		players:           players,
+		profiles:          internal.NewProfileStore(store),
+		presence:          presence.NewHub(),
+		messageInterval:   time.Second,
+		messageBurst:      5,
+	}
+}
+
+// captureLogs Redirects log messages of all levels to the returned buffer until the test is done.
+func captureLogs(t *testing.T) *bytes.Buffer {
+	buf := new(bytes.Buffer)
+	logger, level := log.Logger, zerolog.GlobalLevel()
+	log.Logger = zerolog.New(buf)
+	zerolog.SetGlobalLevel(zerolog.DebugLevel)
+	t.Cleanup(func() {
+		log.Logger = logger
+		zerolog.SetGlobalLevel(level)
+	})
+	return buf
+}
+
+func TestGPCMServer_HandleRequest(t *testing.T) {
+	t.Run("does not reveal auth token", func(t *testing.T) {
+		// GIVEN
+		const token = "GMTy13lsJmiY7L19ojyN3XTM08ll0C4EWWijwmJyq3ttiZmoDUQJ0OSnar9nQCu5MpOGvi4Z0EcC2uNaS4yCrUA"
+		logs := captureLogs(t)
+		path := filepath.Join(t.TempDir(), "store.json")
+		store, err := storage.OpenFileStore(path)
+		require.NoError(t, err)
+		s := newTestGPCMServer(t, store)
+		client, conn := net.Pipe()
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			s.handleRequest(context.Background(), conn)
+		}()
+		reader := gamespy.NewReader(client)
+		_, err = reader.ReadPacket()
+		require.NoError(t, err)
+
+		// WHEN
+		err = gamespy.NewWriter(client).WritePacket(gamespy.NewPacket(
+			gamespy.KeyValuePair{Key: "login"},
+			gamespy.KeyValuePair{Key: "challenge", Value: "ABCDEFGHIJKLMNOPQRSTUVWXYZ012345"},
+			gamespy.KeyValuePair{Key: "authtoken", Value: token},
+			gamespy.KeyValuePair{Key: "response", Value: "5f4dcc3b5aa765d61d8327deb882cf99"},
+			gamespy.KeyValuePair{Key: "port", Value: "16567"},
+			gamespy.KeyValuePair{Key: "productid", Value: "10493"},
+			gamespy.KeyValuePair{Key: "gamename", Value: "battlefield2"},
+			gamespy.KeyValuePair{Key: "namespaceid", Value: "12"},
+			gamespy.KeyValuePair{Key: "sdkrevision", Value: "3"},
+			gamespy.KeyValuePair{Key: "id", Value: "1"},
+		))
+		require.NoError(t, err)
+		res, err := reader.ReadPacket()
+		require.NoError(t, err)
+		// Buddy list is sent once the session is connected to the presence hub
+		_, err = reader.ReadPacket()
+		require.NoError(t, err)
+		require.NoError(t, client.Close())
+		<-done
+
+		// THEN
+		assert.Equal(t, "2", res.Get("lc"))
+		assert.Equal(t, "token-bcf4c1a53a770d", res.Get("uniquenick"))
+		assert.NotContains(t, res.String(), token)
+		persisted, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.NotContains(t, string(persisted), token)
+		assert.Contains(t, logs.String(), "Received login request")
+		assert.NotContains(t, logs.String(), token)
+	})
+}
@@ -0,0 +1,276 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dogclan/dumbspy/internal"
+	"github.com/dogclan/dumbspy/internal/metrics"
+	"github.com/dogclan/dumbspy/internal/presence"
+	"github.com/dogclan/dumbspy/pkg/gamespy"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	serviceGPSP = "gpsp"
+
+	// searchTimeout is the time a client has to send the next search request before the connection is closed
+	searchTimeout = 5 * time.Second
+)
+
+// gpspServer Answers search (GPSP) requests, accepting any nick/email. Profile ids are looked up from the player
+// registry, so they match the ids handed out on login.
+type gpspServer struct {
+	maxPacketSize int
+	players       *internal.PlayerRegistry
+	presence      *presence.Hub
+}
+
+func (s *gpspServer) handleRequest(ctx context.Context, c net.Conn) {
+	conn := newPacketConn(c, serviceGPSP, s.maxPacketSize)
+	remoteAddr := conn.RemoteAddr().String()
+	defer func(conn net.Conn) {
+		err := conn.Close()
+		if err != nil {
+			log.Error().
+				Err(err).
+				Str(logKeyRemote, remoteAddr).
+				Msg("Failed to close connection")
+		}
+	}(conn)
+
+	stop := interruptOnDone(ctx, conn)
+	defer stop()
+
+	// Clients may send multiple requests on the same connection, so keep reading until they are done
+	for ctx.Err() == nil {
+		req, err := conn.read(ctx, time.Now().Add(searchTimeout))
+		if err != nil {
+			// EOF and timeout errors are expected once the client is done => only log to debug
+			if isPeerClosed(err) {
+				log.Debug().
+					Str(logKeyRemote, remoteAddr).
+					Msg("Peer closed/reset connection while reading search request")
+			} else if errors.Is(err, os.ErrDeadlineExceeded) {
+				if ctx.Err() == nil {
+					metrics.ReadTimeouts.With(serviceGPSP).Inc()
+				}
+				log.Debug().
+					Str(logKeyRemote, remoteAddr).
+					Msg("Timed out reading search request")
+			} else {
+				log.Error().
+					Err(err).
+					Str(logKeyRemote, remoteAddr).
+					Msg("Failed to read search request")
+			}
+			return
+		}
+
+		log.Debug().
+			Bytes(logKeyData, req.Bytes()).
+			Str(logKeyRemote, remoteAddr).
+			Msg("Received search request")
+
+		res, err := s.handleSearch(req)
+		if err != nil {
+			log.Error().
+				Err(err).
+				Str(logKeyRemote, remoteAddr).
+				Msg("Failed to handle search request")
+			return
+		}
+
+		if res == nil {
+			log.Debug().
+				Str("command", command(req)).
+				Str(logKeyRemote, remoteAddr).
+				Msg("Ignoring unsupported search request")
+			continue
+		}
+
+		log.Debug().
+			Bytes(logKeyData, res.Bytes()).
+			Str(logKeyRemote, remoteAddr).
+			Msg("Sending search response")
+
+		if err = conn.write(res); err != nil {
+			log.Error().
+				Err(err).
+				Str(logKeyRemote, remoteAddr).
+				Msg("Failed to send search response")
+			return
+		}
+	}
+}
+
+// handleSearch Returns the response to the given search request. Returns nil if the request is not supported.
+func (s *gpspServer) handleSearch(req *gamespy.Packet) (*gamespy.Packet, error) {
+	var search internal.GamespySearchRequest
+	if err := req.Bind(&search); err != nil {
+		return nil, err
+	}
+
+	switch command(req) {
+	case "nicks":
+		return s.handleNicks(search)
+	case "valid":
+		// Any email is valid (as in: exists)
+		return gamespy.NewPacket(gamespy.KeyValuePair{Key: "vr", Value: "1"}), nil
+	case "search":
+		return s.handleProfileSearch(search)
+	case "others":
+		return s.handleOthers(search)
+	case "profilelist":
+		return s.handleProfileList(search)
+	case "newuser":
+		return s.handleNewUser(search), nil
+	default:
+		return nil, nil
+	}
+}
+
+// handleNicks Returns the nick(s) for an email. Since emails are not tracked, the email's local part is returned as
+// the only nick.
+func (s *gpspServer) handleNicks(search internal.GamespySearchRequest) (*gamespy.Packet, error) {
+	nick := nickFromEmail(search.Email)
+	results, err := gamespy.Marshal([]internal.GamespyNicksResult{
+		{
+			Nick:       nick,
+			UniqueNick: nick,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	res := gamespy.NewPacket(gamespy.KeyValuePair{Key: "nr", Value: "0"})
+	res.Append(results)
+	res.Add("ndone", "")
+	return res, nil
+}
+
+// handleProfileSearch Returns the profiles matching the profile id or (unique) nick of the search.
+func (s *gpspServer) handleProfileSearch(search internal.GamespySearchRequest) (*gamespy.Packet, error) {
+	var players []internal.Player
+	if profileID, err := strconv.Atoi(search.ProfileID); err == nil && profileID != 0 {
+		if player, ok := s.players.Lookup(profileID); ok {
+			players = append(players, player)
+		}
+	} else {
+		players = s.players.Find(cmp.Or(search.UniqueNick, search.Nick), search.GameName, search.NamespaceID)
+	}
+
+	results := make([]internal.GamespySearchResult, 0, len(players))
+	for _, player := range players {
+		results = append(results, internal.GamespySearchResult{
+			ProfileID:   player.ID,
+			Nick:        player.Nick,
+			UniqueNick:  player.Nick,
+			NamespaceID: player.NamespaceID,
+		})
+	}
+
+	res, err := gamespy.Marshal(results)
+	if err != nil {
+		return nil, err
+	}
+
+	res.Add("bsrdone", "")
+	return res, nil
+}
+
+// handleOthers Returns the players with the search's profile on their buddy list. Buddy lists are only kept in
+// memory, so players only appear once they were added as a buddy since the last restart.
+func (s *gpspServer) handleOthers(search internal.GamespySearchRequest) (*gamespy.Packet, error) {
+	profileID, err := strconv.Atoi(search.ProfileID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid profile id %q: %w", search.ProfileID, err)
+	}
+
+	others := s.presence.Others(profileID)
+	results := make([]internal.GamespyOthersResult, 0, len(others))
+	for _, otherID := range others {
+		result := internal.GamespyOthersResult{ProfileID: otherID}
+		if player, ok := s.players.Lookup(otherID); ok {
+			result.Nick = player.Nick
+			result.UniqueNick = player.Nick
+		}
+		results = append(results, result)
+	}
+
+	list, err := gamespy.Marshal(results)
+	if err != nil {
+		return nil, err
+	}
+
+	res := gamespy.NewPacket(gamespy.KeyValuePair{Key: "others"})
+	res.Append(list)
+	res.Add("odone", "")
+	return res, nil
+}
+
+// handleProfileList Returns the profiles known for the nick derived from the email, answering like a nicks request
+// with profile ids added.
+func (s *gpspServer) handleProfileList(search internal.GamespySearchRequest) (*gamespy.Packet, error) {
+	players := s.players.Find(nickFromEmail(search.Email), search.GameName, search.NamespaceID)
+	results := make([]internal.GamespyProfileListResult, 0, len(players))
+	for _, player := range players {
+		results = append(results, internal.GamespyProfileListResult{
+			ProfileID:  player.ID,
+			Nick:       player.Nick,
+			UniqueNick: player.Nick,
+		})
+	}
+
+	list, err := gamespy.Marshal(results)
+	if err != nil {
+		return nil, err
+	}
+
+	res := gamespy.NewPacket(gamespy.KeyValuePair{Key: "nr", Value: "0"})
+	res.Append(list)
+	res.Add("ndone", "")
+	return res, nil
+}
+
+// handleNewUser Accepts any new user with a nick, returning the profile id of an existing player with the same nick if
+// possible. Requests are not authenticated, so new profile ids are not assigned here but once the player logs in.
+func (s *gpspServer) handleNewUser(search internal.GamespySearchRequest) *gamespy.Packet {
+	nick := cmp.Or(search.UniqueNick, search.Nick)
+	if nick == "" {
+		return newUserResult(gamespy.ErrorCodeNewUserUniqueNickInvalid, 0)
+	}
+
+	if players := s.players.Find(nick, search.GameName, search.NamespaceID); len(players) > 0 {
+		return newUserResult(0, players[0].ID)
+	}
+
+	profileID, ok := s.players.PeekPlayerID(nick, search.ProductID, search.GameName, search.NamespaceID, search.SDKRevision)
+	if !ok {
+		// Player would be assigned a random profile id on login, which cannot be returned in advance
+		return newUserResult(gamespy.ErrorCodeNewUserUniqueNickInUse, 0)
+	}
+
+	return newUserResult(0, profileID)
+}
+
+// newUserResult Returns a newuser response, which reports errors by code rather than as an error packet.
+func newUserResult(code gamespy.ErrorCode, profileID int) *gamespy.Packet {
+	res := gamespy.NewPacket(gamespy.KeyValuePair{Key: "nur", Value: strconv.Itoa(int(code))})
+	res.AddInt("pid", profileID)
+	return res
+}
+
+func nickFromEmail(email string) string {
+	nick, _, _ := strings.Cut(email, "@")
+	return nick
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"net/netip"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dogclan/dumbspy/internal"
+	"github.com/dogclan/dumbspy/internal/presence"
+	"github.com/dogclan/dumbspy/internal/storage"
+	"github.com/dogclan/dumbspy/pkg/gamespy"
+)
+
+func newTestGPSPServer(t *testing.T) *gpspServer {
+	players, err := internal.NewPlayerRegistry(storage.NewMemoryStore())
+	require.NoError(t, err)
+
+	return &gpspServer{
+		maxPacketSize: gamespy.DefaultMaxPacketSize,
+		players:       players,
+		presence:      presence.NewHub(),
+	}
+}
+
+// registerTestPlayer Returns the id of a Battlefield 2 player with the given nick, registering the player if required.
+func registerTestPlayer(players *internal.PlayerRegistry, nick string) int {
+	return players.GetPlayerID(nick, "10493", "battlefield2", "12", "3")
+}
+
+func TestGPSPServer_HandleSearch(t *testing.T) {
+	// Player ids are derived from player attributes, so they are the same for every registry
+	scratch := newTestGPSPServer(t)
+	someID := strconv.Itoa(registerTestPlayer(scratch.players, "some-nick"))
+	otherID := strconv.Itoa(registerTestPlayer(scratch.players, "other-nick"))
+
+	type test struct {
+		name             string
+		givenPlayers     []string
+		givenBuddies     map[string][]string
+		givenPacket      string
+		expectedResponse string
+		assertPlayers    func(t *testing.T, players *internal.PlayerRegistry)
+		wantErrContains  string
+	}
+
+	tests := []test{
+		{
+			name:             "nicks returns local part of email",
+			givenPacket:      `\nicks\\email\some-nick@example.com\pass\secret\namespaceid\12\gamename\battlefield2\final\`,
+			expectedResponse: `\nr\0\nick\some-nick\uniquenick\some-nick\ndone\\final\`,
+		},
+		{
+			name:             "valid accepts any email",
+			givenPacket:      `\valid\\email\nobody@example.com\gamename\battlefield2\final\`,
+			expectedResponse: `\vr\1\final\`,
+		},
+		{
+			name:         "search finds player by nick",
+			givenPlayers: []string{"some-nick", "other-nick"},
+			givenPacket:  `\search\\sesskey\1\profileid\0\namespaceid\12\nick\some-nick\gamename\battlefield2\final\`,
+			expectedResponse: `\bsr\` + someID + `\nick\some-nick\firstname\\lastname\\email\\uniquenick\some-nick` +
+				`\namespaceid\12\bsrdone\\final\`,
+		},
+		{
+			name:         "search finds player by profile id",
+			givenPlayers: []string{"some-nick", "other-nick"},
+			givenPacket:  `\search\\sesskey\1\profileid\` + otherID + `\namespaceid\12\gamename\battlefield2\final\`,
+			expectedResponse: `\bsr\` + otherID + `\nick\other-nick\firstname\\lastname\\email\\uniquenick\other-nick` +
+				`\namespaceid\12\bsrdone\\final\`,
+		},
+		{
+			name:             "search returns bsrdone only for unknown nick",
+			givenPlayers:     []string{"some-nick"},
+			givenPacket:      `\search\\sesskey\1\profileid\0\namespaceid\12\nick\unknown-nick\gamename\battlefield2\final\`,
+			expectedResponse: `\bsrdone\\final\`,
+		},
+		{
+			name:             "search returns bsrdone only for unknown profile id",
+			givenPlayers:     []string{"some-nick"},
+			givenPacket:      `\search\\sesskey\1\profileid\600000003\namespaceid\12\gamename\battlefield2\final\`,
+			expectedResponse: `\bsrdone\\final\`,
+		},
+		{
+			name:         "others returns players with profile on their buddy list",
+			givenPlayers: []string{"some-nick", "other-nick"},
+			givenBuddies: map[string][]string{
+				"other-nick": {"some-nick"},
+			},
+			givenPacket: `\others\\sesskey\1\profileid\` + someID + `\namespaceid\12\gamename\battlefield2\final\`,
+			expectedResponse: `\others\\o\` + otherID + `\nick\other-nick\uniquenick\other-nick\first\\last\\email\` +
+				`\odone\\final\`,
+		},
+		{
+			name:             "others returns empty list for player not on any buddy list",
+			givenPlayers:     []string{"some-nick", "other-nick"},
+			givenPacket:      `\others\\sesskey\1\profileid\` + someID + `\namespaceid\12\gamename\battlefield2\final\`,
+			expectedResponse: `\others\\odone\\final\`,
+		},
+		{
+			name:            "others fails for invalid profile id",
+			givenPacket:     `\others\\sesskey\1\profileid\some-nick\namespaceid\12\gamename\battlefield2\final\`,
+			wantErrContains: `invalid profile id "some-nick"`,
+		},
+		{
+			name:             "profilelist returns profiles of nick from email",
+			givenPlayers:     []string{"some-nick", "other-nick"},
+			givenPacket:      `\profilelist\\email\some-nick@example.com\namespaceid\12\gamename\battlefield2\final\`,
+			expectedResponse: `\nr\0\pid\` + someID + `\nick\some-nick\uniquenick\some-nick\ndone\\final\`,
+		},
+		{
+			name:             "profilelist returns empty list for unknown nick",
+			givenPacket:      `\profilelist\\email\unknown-nick@example.com\namespaceid\12\gamename\battlefield2\final\`,
+			expectedResponse: `\nr\0\ndone\\final\`,
+		},
+		{
+			name:         "newuser returns profile id of existing player",
+			givenPlayers: []string{"some-nick"},
+			givenPacket: `\newuser\\email\some-nick@example.com\nick\some-nick\uniquenick\some-nick\productid\10493` +
+				`\gamename\battlefield2\namespaceid\12\sdkrevision\3\final\`,
+			expectedResponse: `\nur\0\pid\` + someID + `\final\`,
+		},
+		{
+			name: "newuser returns profile id of new player without registering it",
+			givenPacket: `\newuser\\email\other-nick@example.com\nick\other-nick\uniquenick\other-nick\productid\10493` +
+				`\gamename\battlefield2\namespaceid\12\sdkrevision\3\final\`,
+			expectedResponse: `\nur\0\pid\` + otherID + `\final\`,
+			assertPlayers: func(t *testing.T, players *internal.PlayerRegistry) {
+				assert.Empty(t, players.Find("other-nick", "", ""))
+			},
+		},
+		{
+			name: "newuser rejects request without nick",
+			givenPacket: `\newuser\\email\some-nick@example.com\productid\10493\gamename\battlefield2\namespaceid\12` +
+				`\sdkrevision\3\final\`,
+			expectedResponse: `\nur\` + strconv.Itoa(int(gamespy.ErrorCodeNewUserUniqueNickInvalid)) + `\pid\0\final\`,
+			assertPlayers: func(t *testing.T, players *internal.PlayerRegistry) {
+				assert.Empty(t, players.Find("some-nick", "", ""))
+			},
+		},
+		{
+			name:        "ignores unsupported request",
+			givenPacket: `\unknown\\sesskey\1\final\`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// GIVEN
+			s := newTestGPSPServer(t)
+			for _, nick := range tt.givenPlayers {
+				registerTestPlayer(s.players, nick)
+			}
+			for nick, buddies := range tt.givenBuddies {
+				session := s.presence.Connect(registerTestPlayer(s.players, nick), netip.AddrPort{}, discardPacket)
+				for _, buddy := range buddies {
+					buddySession := s.presence.Connect(registerTestPlayer(s.players, buddy), netip.AddrPort{}, discardPacket)
+					require.NoError(t, session.AddBuddy(registerTestPlayer(s.players, buddy), ""))
+					require.NoError(t, buddySession.AuthAdd(registerTestPlayer(s.players, nick)))
+				}
+			}
+			req, err := gamespy.NewPacketFromString(tt.givenPacket)
+			require.NoError(t, err)
+
+			// WHEN
+			res, err := s.handleSearch(req)
+
+			// THEN
+			if tt.wantErrContains != "" {
+				require.ErrorContains(t, err, tt.wantErrContains)
+				return
+			}
+			require.NoError(t, err)
+			if tt.expectedResponse == "" {
+				assert.Nil(t, res)
+			} else {
+				require.NotNil(t, res)
+				assert.Equal(t, tt.expectedResponse, res.String())
+			}
+			if tt.assertPlayers != nil {
+				tt.assertPlayers(t, s.players)
+			}
+		})
+	}
+}
+
+func discardPacket(*gamespy.Packet) error {
+	return nil
+}
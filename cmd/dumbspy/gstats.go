@@ -0,0 +1,285 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/dogclan/dumbspy/internal"
+	"github.com/dogclan/dumbspy/internal/metrics"
+	"github.com/dogclan/dumbspy/pkg/gamespy"
+	"github.com/dogclan/dumbspy/pkg/gamespy/gstats"
+
+	"github.com/rs/zerolog/log"
+)
+
+const serviceGStats = "gstats"
+
+// gstatsServer Stores persistent player data for games. Games are not required to prove knowledge of their secret
+// key, but players must have been assigned their profile id on login and their response to the challenge must be
+// accepted by the authenticator.
+type gstatsServer struct {
+	idleTimeout   time.Duration
+	maxPacketSize int
+	authenticator internal.Authenticator
+	players       *internal.PlayerRegistry
+	playerData    *internal.PlayerDataStore
+}
+
+func newGStatsConn(conn net.Conn, maxPacketSize int) *packetConn {
+	return &packetConn{
+		Conn:    conn,
+		service: serviceGStats,
+		reader:  gstats.NewReaderSize(conn, maxPacketSize),
+		writer:  gstats.NewWriter(conn),
+	}
+}
+
+func (s *gstatsServer) handleRequest(ctx context.Context, c net.Conn) {
+	conn := newGStatsConn(c, s.maxPacketSize)
+	remoteAddr := conn.RemoteAddr().String()
+	defer func(conn net.Conn) {
+		err := conn.Close()
+		if err != nil {
+			log.Error().
+				Err(err).
+				Str(logKeyRemote, remoteAddr).
+				Msg("Failed to close connection")
+		}
+	}(conn)
+
+	serverChallenge := gamespy.RandString(10)
+	challenge, err := gamespy.Marshal(internal.GamespyStatsChallenge{
+		LoginCode: 1,
+		Challenge: serverChallenge,
+		ID:        1,
+	})
+	if err != nil {
+		log.Error().
+			Err(err).
+			Str(logKeyRemote, remoteAddr).
+			Msg("Failed to marshal challenge")
+		return
+	}
+
+	if err = conn.write(challenge); err != nil {
+		log.Error().
+			Err(err).
+			Str(logKeyRemote, remoteAddr).
+			Msg("Failed to send challenge")
+		return
+	}
+
+	stop := interruptOnDone(ctx, conn)
+	defer stop()
+
+	// Players authenticated on this connection, whose data may be written
+	authenticated := make(map[int]struct{})
+	for ctx.Err() == nil {
+		req, err2 := conn.read(ctx, time.Now().Add(s.idleTimeout))
+		if err2 != nil {
+			// EOF and timeout errors are expected once the game is done => only log to debug
+			if isPeerClosed(err2) {
+				log.Debug().
+					Str(logKeyRemote, remoteAddr).
+					Msg("Peer closed/reset stats connection")
+			} else if errors.Is(err2, os.ErrDeadlineExceeded) {
+				if ctx.Err() == nil {
+					metrics.ReadTimeouts.With(serviceGStats).Inc()
+				}
+				log.Debug().
+					Str(logKeyRemote, remoteAddr).
+					Msg("Closing idle stats connection")
+			} else {
+				log.Error().
+					Err(err2).
+					Str(logKeyRemote, remoteAddr).
+					Msg("Failed to read stats packet")
+			}
+			return
+		}
+
+		log.Debug().
+			Bytes(logKeyData, req.Bytes()).
+			Str(logKeyRemote, remoteAddr).
+			Msg("Received stats packet")
+
+		res, err2 := s.handlePacket(req, serverChallenge, authenticated)
+		if err2 != nil {
+			log.Warn().
+				Err(err2).
+				Str("command", command(req)).
+				Str(logKeyRemote, remoteAddr).
+				Msg("Failed to handle stats packet")
+			return
+		}
+
+		if res == nil {
+			continue
+		}
+
+		log.Debug().
+			Bytes(logKeyData, res.Bytes()).
+			Str(logKeyRemote, remoteAddr).
+			Msg("Sending stats response")
+
+		if err2 = conn.write(res); err2 != nil {
+			log.Error().
+				Err(err2).
+				Str(logKeyRemote, remoteAddr).
+				Msg("Failed to send stats response")
+			return
+		}
+	}
+}
+
+// handlePacket Returns the response to the given packet. Returns nil if no response is required.
+func (s *gstatsServer) handlePacket(
+	req *gamespy.Packet,
+	serverChallenge string,
+	authenticated map[int]struct{},
+) (*gamespy.Packet, error) {
+	switch cmd := command(req); cmd {
+	case "auth":
+		var auth internal.GamespyStatsAuthRequest
+		if err := req.Bind(&auth); err != nil {
+			return nil, err
+		}
+
+		// Any response is accepted, since that would require knowing each game's secret key
+		return gamespy.Marshal(internal.GamespyStatsAuthResponse{
+			LoginCode:  2,
+			SessionKey: rand.Intn(1 << 30),
+			Proof:      0,
+			ID:         auth.ID,
+		})
+	case "authp":
+		var auth internal.GamespyPlayerAuthRequest
+		if err := req.Bind(&auth); err != nil {
+			return nil, err
+		}
+		return s.handlePlayerAuth(auth, serverChallenge, authenticated)
+	case "getpd":
+		var get internal.GamespyGetPlayerDataRequest
+		if err := req.Bind(&get); err != nil {
+			return nil, err
+		}
+		return s.handleGetPlayerData(get, authenticated)
+	case "setpd":
+		var set internal.GamespySetPlayerDataRequest
+		if err := req.Bind(&set); err != nil {
+			return nil, err
+		}
+		return s.handleSetPlayerData(set, authenticated)
+	default:
+		// Includes game results (\newgame\, \updgame\), which are not stored
+		log.Debug().
+			Str("command", cmd).
+			Msg("Ignoring unsupported stats packet")
+		return nil, nil
+	}
+}
+
+// handlePlayerAuth Authenticates a player by profile id. The player must be known to the player registry and their
+// response to the server challenge must be accepted by the authenticator.
+func (s *gstatsServer) handlePlayerAuth(
+	auth internal.GamespyPlayerAuthRequest,
+	serverChallenge string,
+	authenticated map[int]struct{},
+) (*gamespy.Packet, error) {
+	res := internal.GamespyPlayerAuthResponse{
+		Result:  internal.PlayerAuthFailed,
+		LocalID: auth.LocalID,
+	}
+
+	if auth.AuthToken != "" {
+		// Auth tokens are not issued, so they cannot be mapped to profile ids
+		res.ErrorMessage = internal.ToPointer("Auth token logins are not supported")
+	} else if player, ok := s.players.Lookup(auth.ProfileID); !ok {
+		res.ErrorMessage = internal.ToPointer("Unknown profile")
+	} else if err := s.authenticator.AuthenticatePlayer(player.Nick, serverChallenge, auth.Response); err != nil {
+		log.Debug().
+			Err(err).
+			Int("profileID", auth.ProfileID).
+			Msg("Rejecting player auth")
+		res.ErrorMessage = internal.ToPointer("Invalid response")
+	} else {
+		res.Result = auth.ProfileID
+		authenticated[auth.ProfileID] = struct{}{}
+	}
+
+	return gamespy.Marshal(res)
+}
+
+// handleGetPlayerData Returns stored player data. Private data is only returned for players authenticated on the
+// connection.
+func (s *gstatsServer) handleGetPlayerData(
+	get internal.GamespyGetPlayerDataRequest,
+	authenticated map[int]struct{},
+) (*gamespy.Packet, error) {
+	res := internal.GamespyGetPlayerDataResponse{
+		LocalID:   get.LocalID,
+		ProfileID: get.ProfileID,
+	}
+
+	_, known := s.players.Lookup(get.ProfileID)
+	_, authorized := authenticated[get.ProfileID]
+	private := get.DataType == internal.PlayerDataPrivateReadOnly || get.DataType == internal.PlayerDataPrivateReadWrite
+	if !known || private && !authorized {
+		return gamespy.Marshal(res)
+	}
+
+	var keys []string
+	if trimmed := strings.Trim(get.Keys, "\x01"); trimmed != "" {
+		keys = strings.Split(trimmed, "\x01")
+	}
+
+	data, ok, err := s.playerData.Get(get.ProfileID, get.DataType, get.Index, keys)
+	if err != nil {
+		return nil, err
+	}
+
+	// Players without data yet get an empty blob rather than an error
+	res.Result = 1
+	if ok {
+		res.Modified = data.Modified
+		res.Length = len(data.Data)
+		res.Data = data.Data
+	}
+
+	return gamespy.Marshal(res)
+}
+
+// handleSetPlayerData Stores player data for a player authenticated on the connection.
+func (s *gstatsServer) handleSetPlayerData(
+	set internal.GamespySetPlayerDataRequest,
+	authenticated map[int]struct{},
+) (*gamespy.Packet, error) {
+	res := internal.GamespySetPlayerDataResponse{
+		LocalID:   set.LocalID,
+		ProfileID: set.ProfileID,
+	}
+
+	if _, ok := authenticated[set.ProfileID]; !ok {
+		return gamespy.Marshal(res)
+	}
+
+	data, err := s.playerData.Set(set.ProfileID, set.DataType, set.Index, set.Data, set.KeyValue == 1)
+	if errors.Is(err, internal.ErrInvalidPlayerData) {
+		log.Debug().
+			Err(err).
+			Int("profileID", set.ProfileID).
+			Msg("Rejecting player data")
+		return gamespy.Marshal(res)
+	} else if err != nil {
+		return nil, err
+	}
+
+	res.Result = 1
+	res.Modified = data.Modified
+	return gamespy.Marshal(res)
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dogclan/dumbspy/internal"
+	"github.com/dogclan/dumbspy/internal/storage"
+)
+
+func newTestGStatsServer(t *testing.T, authenticator internal.Authenticator) *gstatsServer {
+	store := storage.NewMemoryStore()
+	players, err := internal.NewPlayerRegistry(store)
+	require.NoError(t, err)
+
+	return &gstatsServer{
+		authenticator: authenticator,
+		players:       players,
+		playerData:    internal.NewPlayerDataStore(store),
+	}
+}
+
+func TestGStatsServer_HandlePlayerAuth(t *testing.T) {
+	const serverChallenge = "4Jp6A4kK02"
+
+	type test struct {
+		name                  string
+		givenAccounts         string
+		givenProfileID        int
+		givenResponse         string
+		expectedResult        int
+		expectedAuthenticated bool
+	}
+
+	tests := []test{
+		{
+			name:                  "accepts any response without accounts file",
+			givenResponse:         "1c5a1eb9e75006ec317bd6d8a2c09969",
+			expectedAuthenticated: true,
+		},
+		{
+			name:                  "accepts valid response",
+			givenAccounts:         "some-nick:131def0e93e67e3e62b39d74d6316511\n",
+			givenResponse:         "e53846ff5a6127a9cdbc0f9e698da058",
+			expectedAuthenticated: true,
+		},
+		{
+			name:           "rejects invalid response",
+			givenAccounts:  "some-nick:131def0e93e67e3e62b39d74d6316511\n",
+			givenResponse:  "1c5a1eb9e75006ec317bd6d8a2c09969",
+			expectedResult: internal.PlayerAuthFailed,
+		},
+		{
+			name:           "rejects unknown profile",
+			givenProfileID: 600000003,
+			givenResponse:  "e53846ff5a6127a9cdbc0f9e698da058",
+			expectedResult: internal.PlayerAuthFailed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// GIVEN
+			var authenticator internal.Authenticator = internal.AcceptAllAuthenticator{}
+			if tt.givenAccounts != "" {
+				path := filepath.Join(t.TempDir(), "accounts")
+				require.NoError(t, os.WriteFile(path, []byte(tt.givenAccounts), 0o600))
+				var err error
+				authenticator, err = internal.NewFileAuthenticator(path)
+				require.NoError(t, err)
+			}
+			s := newTestGStatsServer(t, authenticator)
+			profileID := s.players.GetPlayerID("some-nick", "10493", "battlefield2", "12", "3")
+			if tt.givenProfileID != 0 {
+				profileID = tt.givenProfileID
+			}
+			authenticated := make(map[int]struct{})
+
+			// WHEN
+			res, err := s.handlePlayerAuth(internal.GamespyPlayerAuthRequest{
+				ProfileID: profileID,
+				Response:  tt.givenResponse,
+				LocalID:   1,
+			}, serverChallenge, authenticated)
+
+			// THEN
+			require.NoError(t, err)
+			expectedResult := tt.expectedResult
+			if tt.expectedAuthenticated {
+				expectedResult = profileID
+			}
+			assert.Equal(t, strconv.Itoa(expectedResult), res.Get("pauthr"))
+			assert.Equal(t, "1", res.Get("lid"))
+			_, ok := authenticated[profileID]
+			assert.Equal(t, tt.expectedAuthenticated, ok)
+		})
+	}
+}
+
+func TestGStatsServer_HandleSetPlayerData(t *testing.T) {
+	type test struct {
+		name           string
+		givenRequest   internal.GamespySetPlayerDataRequest
+		expectedResult string
+	}
+
+	tests := []test{
+		{
+			name: "stores data",
+			givenRequest: internal.GamespySetPlayerDataRequest{
+				DataType: internal.PlayerDataPrivateReadWrite,
+				Index:    internal.PlayerDataMaxIndex,
+				Data:     "blob",
+			},
+			expectedResult: "1",
+		},
+		{
+			name: "rejects unknown data type",
+			givenRequest: internal.GamespySetPlayerDataRequest{
+				DataType: 4,
+				Data:     "blob",
+			},
+			expectedResult: "0",
+		},
+		{
+			name: "rejects index out of range",
+			givenRequest: internal.GamespySetPlayerDataRequest{
+				DataType: internal.PlayerDataPrivateReadWrite,
+				Index:    internal.PlayerDataMaxIndex + 1,
+				Data:     "blob",
+			},
+			expectedResult: "0",
+		},
+		{
+			name: "rejects data too long",
+			givenRequest: internal.GamespySetPlayerDataRequest{
+				DataType: internal.PlayerDataPrivateReadWrite,
+				Data:     strings.Repeat("x", internal.PlayerDataMaxLength+1),
+			},
+			expectedResult: "0",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// GIVEN
+			s := newTestGStatsServer(t, internal.AcceptAllAuthenticator{})
+			profileID := s.players.GetPlayerID("some-nick", "10493", "battlefield2", "12", "3")
+			set := tt.givenRequest
+			set.ProfileID = profileID
+			set.LocalID = 1
+
+			// WHEN
+			res, err := s.handleSetPlayerData(set, map[int]struct{}{profileID: {}})
+
+			// THEN
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedResult, res.Get("setpdr"))
+			assert.Equal(t, "1", res.Get("lid"))
+		})
+	}
+}
@@ -1,24 +1,300 @@
 package options
 
 import (
+	"errors"
 	"flag"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"gopkg.in/yaml.v3"
+
+	"github.com/dogclan/dumbspy/pkg/gamespy"
 )
 
+const (
+	envPrefix = "DUMBSPY_"
+
+	flagConfig   = "config"
+	flagGameKeys = "game-keys"
+
+	// redacted replaces secret values in Values
+	redacted = "***"
+)
+
+// Options Contains the effective configuration. Options are read from (in order of precedence) command line flags,
+// DUMBSPY_* environment variables, a YAML config file and defaults.
 type Options struct {
-	Version bool
-
-	ListenAddr   string
-	Debug        bool
-	ColorizeLogs bool
-}
-
-func Init() *Options {
-	opts := new(Options)
-	flag.BoolVar(&opts.Version, "v", false, "prints the version")
-	flag.BoolVar(&opts.Version, "version", false, "prints the version")
-	flag.BoolVar(&opts.Debug, "debug", false, "enable debug logging")
-	flag.BoolVar(&opts.ColorizeLogs, "colorize-logs", false, "colorize log messages")
-	flag.StringVar(&opts.ListenAddr, "address", ":29900", "server/bind address in format [host]:port")
-	flag.Parse()
-	return opts
+	Version    bool
+	ConfigFile string
+
+	ListenAddr        string        `validate:"hostname_port"`
+	SearchListenAddr  string        `validate:"hostname_port"`
+	QR2ListenAddr     string        `validate:"hostname_port"`
+	SBListenAddr      string        `validate:"hostname_port"`
+	StatsListenAddr   string        `validate:"hostname_port"`
+	NATNegListenAddr  string        `validate:"hostname_port"`
+	CDKeyListenAddr   string        `validate:"hostname_port"`
+	MetricsListenAddr string        `validate:"omitempty,hostname_port"`
+	KeepAliveInterval time.Duration `validate:"gt=0"`
+	GameServerTimeout time.Duration `validate:"gt=0"`
+	NATNegTimeout     time.Duration `validate:"gt=0"`
+	IdleTimeout       time.Duration `validate:"gtfield=KeepAliveInterval"`
+	MaxPacketSize     int           `validate:"gte=64"`
+	AccountsFile      string        `validate:"omitempty,file"`
+	CDKeysFile        string        `validate:"omitempty,file"`
+	InMemory          bool
+	DataFile          string        `validate:"required_unless=InMemory true"`
+	PlayerDataFile    string        `validate:"omitempty,nefield=DataFile"`
+	MessageInterval   time.Duration `validate:"gt=0"`
+	MessageBurst      int           `validate:"gte=1"`
+	GameKeys          map[string]string
+	GameAvailability  map[string]string `validate:"dive,oneof=available unavailable temporarily-unavailable"`
+
+	ShutdownGracePeriod time.Duration `validate:"gte=0"`
+	Debug               bool
+	ColorizeLogs        bool
+
+	values map[string]any
+}
+
+// Init Reads the options from the command line, environment and config file. Exits if the command line is invalid.
+func Init() (*Options, error) {
+	return parse(flag.CommandLine, os.Args[1:], os.LookupEnv)
+}
+
+func parse(fs *flag.FlagSet, args []string, lookupEnv func(key string) (string, bool)) (*Options, error) {
+	opts := &Options{
+		GameKeys:         make(map[string]string),
+		GameAvailability: make(map[string]string),
+	}
+	fs.BoolVar(&opts.Version, "v", false, "prints the version")
+	fs.BoolVar(&opts.Version, "version", false, "prints the version")
+	fs.StringVar(&opts.ConfigFile, flagConfig, "", "path to YAML config file (option names match flag names)")
+	fs.BoolVar(&opts.Debug, "debug", false, "enable debug logging")
+	fs.BoolVar(&opts.ColorizeLogs, "colorize-logs", false, "colorize log messages")
+	fs.StringVar(&opts.ListenAddr, "address", ":29900", "server/bind address in format [host]:port")
+	fs.StringVar(&opts.SearchListenAddr, "search-address", ":29901", "search server (GPSP) bind address in format [host]:port")
+	fs.StringVar(&opts.QR2ListenAddr, "qr2-address", ":27900", "master server (QR2) UDP bind address for game server heartbeats in format [host]:port")
+	fs.StringVar(&opts.StatsListenAddr, "stats-address", ":29920", "stats (gstats) bind address for persistent player data in format [host]:port")
+	fs.StringVar(&opts.NATNegListenAddr, "natneg-address", ":27901", "NAT negotiation (natneg) UDP bind address in format [host]:port")
+	fs.StringVar(&opts.CDKeyListenAddr, "cdkey-address", ":29910", "CD key validation (gs-cdkey) UDP bind address in format [host]:port")
+	fs.StringVar(&opts.SBListenAddr, "sb-address", ":28910", "server browser (server list) bind address in format [host]:port")
+	fs.StringVar(&opts.MetricsListenAddr, "metrics-address", "", "Prometheus metrics (HTTP) bind address in format [host]:port (disabled if empty)")
+	fs.DurationVar(&opts.KeepAliveInterval, "keep-alive-interval", 30*time.Second, "interval at which keep-alive packets are sent to logged in clients")
+	fs.DurationVar(&opts.IdleTimeout, "idle-timeout", 5*time.Minute, "duration after which sessions without any client activity are closed")
+	fs.DurationVar(&opts.GameServerTimeout, "game-server-timeout", 2*time.Minute, "duration after which game servers without any heartbeats/keep-alives are removed from the server list")
+	fs.DurationVar(&opts.NATNegTimeout, "natneg-timeout", 30*time.Second, "duration after which NAT negotiations are abandoned if the second client did not show up")
+	fs.IntVar(&opts.MaxPacketSize, "max-packet-size", gamespy.DefaultMaxPacketSize, "maximum size of packets accepted from clients in bytes")
+	fs.StringVar(&opts.AccountsFile, "accounts-file", "", "path to file with accounts allowed to log in (accepts any login if empty)")
+	fs.StringVar(&opts.CDKeysFile, "cdkeys-file", "", "path to file with MD5 hashes of CD keys accepted by the CD key validation (accepts any CD key if empty)")
+	fs.BoolVar(&opts.InMemory, "in-memory", false, "keep all data in memory only instead of persisting it to the data files (player ids and data are lost on restart)")
+	fs.StringVar(&opts.DataFile, "data-file", "dumbspy.json", "path to file in which to persist data such as player ids")
+	fs.StringVar(&opts.PlayerDataFile, "player-data-file", "dumbspy-playerdata.json", "path to separate file in which to persist player data stored by games via gstats (kept alongside other data if empty)")
+	fs.DurationVar(&opts.MessageInterval, "message-interval", time.Second, "interval at which clients may send buddy messages once their burst is used up (flood limit)")
+	fs.IntVar(&opts.MessageBurst, "message-burst", 5, "number of buddy messages clients may send in quick succession before the flood limit applies")
+	fs.Var(stringMap(opts.GameKeys), flagGameKeys, "secret keys used to encrypt server lists by game name in format gamename=key,gamename2=key2 (server lists are not served for other games)")
+	fs.Var(stringMap(opts.GameAvailability), "game-availability", "status reported to availability checks by game name in format gamename=status,gamename2=status2 (status is one of available, unavailable or temporarily-unavailable, games default to available)")
+	fs.DurationVar(&opts.ShutdownGracePeriod, "shutdown-grace-period", 10*time.Second, "maximum duration to wait for active sessions to finish on shutdown")
+
+	// Describe environment variables in usage
+	fs.VisitAll(func(f *flag.Flag) {
+		if configurable(f.Name) {
+			f.Usage += fmt.Sprintf(" [$%s]", envKey(f.Name))
+		}
+	})
+
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	// Printing the version must not depend on a valid configuration
+	if opts.Version {
+		return opts, nil
+	}
+
+	// Remember values of flags set on the command line, since file and environment would otherwise override them
+	flags := make(map[string]string)
+	fs.Visit(func(f *flag.Flag) {
+		flags[f.Name] = f.Value.String()
+	})
+
+	if _, ok := flags[flagConfig]; !ok {
+		if path, ok2 := lookupEnv(envKey(flagConfig)); ok2 {
+			opts.ConfigFile = path
+		}
+	}
+
+	if opts.ConfigFile != "" {
+		if err := applyFile(fs, opts.ConfigFile); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := applyEnv(fs, lookupEnv); err != nil {
+		return nil, err
+	}
+
+	for name, value := range flags {
+		if err := fs.Set(name, value); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := validator.New().Struct(opts); err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	opts.values = make(map[string]any)
+	fs.VisitAll(func(f *flag.Flag) {
+		if !configurable(f.Name) {
+			return
+		}
+		// Game keys are secrets, so only reveal which games have a key
+		if f.Name == flagGameKeys {
+			opts.values[f.Name] = stringMap(opts.GameKeys).redacted()
+		} else {
+			opts.values[f.Name] = f.Value.String()
+		}
+	})
+
+	return opts, nil
+}
+
+// Values Returns the effective value of every option by name. Secret values are redacted.
+func (o *Options) Values() map[string]any {
+	return maps.Clone(o.values)
+}
+
+// applyFile Sets options from the YAML document at path. Keys must match flag names.
+func applyFile(fs *flag.FlagSet, path string) error {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var document map[string]any
+	if err = yaml.Unmarshal(b, &document); err != nil {
+		return fmt.Errorf("failed to parse config file: %w", err)
+	}
+
+	// Apply in sorted order for deterministic errors
+	for _, name := range slices.Sorted(maps.Keys(document)) {
+		if !configurable(name) || fs.Lookup(name) == nil {
+			return fmt.Errorf("config file: unknown option %q", name)
+		}
+
+		value, err2 := formatValue(document[name])
+		if err2 != nil {
+			return fmt.Errorf("config file: option %q: %w", name, err2)
+		}
+
+		if err2 = fs.Set(name, value); err2 != nil {
+			return fmt.Errorf("config file: option %q: %w", name, err2)
+		}
+	}
+
+	return nil
+}
+
+// applyEnv Sets options from DUMBSPY_* environment variables.
+func applyEnv(fs *flag.FlagSet, lookupEnv func(key string) (string, bool)) error {
+	var err error
+	fs.VisitAll(func(f *flag.Flag) {
+		if err != nil || !configurable(f.Name) {
+			return
+		}
+
+		key := envKey(f.Name)
+		value, ok := lookupEnv(key)
+		if !ok {
+			return
+		}
+
+		if err2 := f.Value.Set(value); err2 != nil {
+			err = fmt.Errorf("environment variable %s: %w", key, err2)
+		}
+	})
+	return err
+}
+
+// formatValue Returns the flag representation of a YAML value. Lists are joined as "a,b", maps as "k=v,k2=v2".
+func formatValue(value any) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case string, bool, int, float64:
+		return fmt.Sprint(v), nil
+	case []any:
+		elements := make([]string, 0, len(v))
+		for _, e := range v {
+			s, err := formatValue(e)
+			if err != nil {
+				return "", err
+			}
+			elements = append(elements, s)
+		}
+		return strings.Join(elements, ","), nil
+	case map[string]any:
+		elements := make([]string, 0, len(v))
+		for _, key := range slices.Sorted(maps.Keys(v)) {
+			s, err := formatValue(v[key])
+			if err != nil {
+				return "", err
+			}
+			elements = append(elements, key+"="+s)
+		}
+		return strings.Join(elements, ","), nil
+	default:
+		return "", errors.New("unsupported value type")
+	}
+}
+
+// stringMap A flag value holding key/value pairs in format "k=v,k2=v2". Setting the value replaces all pairs.
+type stringMap map[string]string
+
+func (m stringMap) String() string {
+	elements := make([]string, 0, len(m))
+	for _, key := range slices.Sorted(maps.Keys(m)) {
+		elements = append(elements, key+"="+m[key])
+	}
+	return strings.Join(elements, ",")
+}
+
+// redacted Returns the pairs like String, but with all values replaced.
+func (m stringMap) redacted() string {
+	elements := make([]string, 0, len(m))
+	for _, key := range slices.Sorted(maps.Keys(m)) {
+		elements = append(elements, key+"="+redacted)
+	}
+	return strings.Join(elements, ",")
+}
+
+func (m stringMap) Set(s string) error {
+	clear(m)
+	if s == "" {
+		return nil
+	}
+
+	for _, element := range strings.Split(s, ",") {
+		key, value, ok := strings.Cut(element, "=")
+		if !ok || key == "" {
+			return fmt.Errorf("invalid key/value pair %q", element)
+		}
+		m[key] = value
+	}
+	return nil
+}
+
+// configurable Checks whether a flag can be set via config file/environment
+func configurable(name string) bool {
+	return name != "v" && name != "version" && name != flagConfig
+}
+
+func envKey(name string) string {
+	return envPrefix + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
 }
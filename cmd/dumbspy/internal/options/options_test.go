@@ -0,0 +1,273 @@
+package options
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	type test struct {
+		name            string
+		args            []string
+		env             map[string]string
+		file            string
+		assertOptions   func(t *testing.T, opts *Options)
+		wantErrContains string
+	}
+
+	tests := []test{
+		{
+			name: "uses defaults",
+			assertOptions: func(t *testing.T, opts *Options) {
+				assert.Equal(t, ":29900", opts.ListenAddr)
+				assert.Equal(t, 30*time.Second, opts.KeepAliveInterval)
+				assert.False(t, opts.Debug)
+				assert.False(t, opts.InMemory)
+				assert.Equal(t, "dumbspy.json", opts.DataFile)
+				assert.Equal(t, "dumbspy-playerdata.json", opts.PlayerDataFile)
+			},
+		},
+		{
+			name: "reads options from file",
+			file: "address: 127.0.0.1:29900\nkeep-alive-interval: 10s\ndebug: true\nmax-packet-size: 1024\n",
+			assertOptions: func(t *testing.T, opts *Options) {
+				assert.Equal(t, "127.0.0.1:29900", opts.ListenAddr)
+				assert.Equal(t, 10*time.Second, opts.KeepAliveInterval)
+				assert.True(t, opts.Debug)
+				assert.Equal(t, 1024, opts.MaxPacketSize)
+			},
+		},
+		{
+			name: "environment overrides file",
+			file: "address: 127.0.0.1:29900\ndebug: true\n",
+			env: map[string]string{
+				"DUMBSPY_ADDRESS":             "127.0.0.2:29900",
+				"DUMBSPY_KEEP_ALIVE_INTERVAL": "15s",
+			},
+			assertOptions: func(t *testing.T, opts *Options) {
+				assert.Equal(t, "127.0.0.2:29900", opts.ListenAddr)
+				assert.Equal(t, 15*time.Second, opts.KeepAliveInterval)
+				assert.True(t, opts.Debug)
+			},
+		},
+		{
+			name: "flags override environment and file",
+			args: []string{"-address", "127.0.0.3:29900", "-debug=false"},
+			file: "address: 127.0.0.1:29900\ndebug: true\n",
+			env: map[string]string{
+				"DUMBSPY_ADDRESS": "127.0.0.2:29900",
+			},
+			assertOptions: func(t *testing.T, opts *Options) {
+				assert.Equal(t, "127.0.0.3:29900", opts.ListenAddr)
+				assert.False(t, opts.Debug)
+			},
+		},
+		{
+			name: "reads config file path from environment",
+			env: map[string]string{
+				"DUMBSPY_CONFIG": "from-env",
+			},
+			wantErrContains: "failed to read config file",
+		},
+		{
+			name: "ignores invalid configuration if version is requested",
+			args: []string{"-v"},
+			file: "address: not-an-address\n",
+			env: map[string]string{
+				"DUMBSPY_KEEP_ALIVE_INTERVAL": "invalid",
+			},
+			assertOptions: func(t *testing.T, opts *Options) {
+				assert.True(t, opts.Version)
+			},
+		},
+		{
+			name: "returns effective values",
+			args: []string{"-debug"},
+			assertOptions: func(t *testing.T, opts *Options) {
+				values := opts.Values()
+				assert.Equal(t, "true", values["debug"])
+				assert.Equal(t, "30s", values["keep-alive-interval"])
+				assert.NotContains(t, values, "version")
+				assert.NotContains(t, values, "config")
+			},
+		},
+		{
+			name: "reads game keys from file",
+			file: "game-keys:\n  battlefield2: abc123\n  gamespy2: def456\n",
+			assertOptions: func(t *testing.T, opts *Options) {
+				assert.Equal(t, map[string]string{"battlefield2": "abc123", "gamespy2": "def456"}, opts.GameKeys)
+			},
+		},
+		{
+			name: "redacts game keys in values",
+			args: []string{"-game-keys", "battlefield2=abc123,gamespy2=def456"},
+			assertOptions: func(t *testing.T, opts *Options) {
+				values := opts.Values()
+				assert.Equal(t, "battlefield2=***,gamespy2=***", values["game-keys"])
+				assert.NotContains(t, fmt.Sprint(values), "abc123")
+				assert.NotContains(t, fmt.Sprint(values), "def456")
+			},
+		},
+		{
+			name: "game keys from environment replace keys from file",
+			file: "game-keys:\n  battlefield2: abc123\n",
+			env: map[string]string{
+				"DUMBSPY_GAME_KEYS": "gamespy2=def456",
+			},
+			assertOptions: func(t *testing.T, opts *Options) {
+				assert.Equal(t, map[string]string{"gamespy2": "def456"}, opts.GameKeys)
+			},
+		},
+		{
+			name: "fails for invalid game keys",
+			env: map[string]string{
+				"DUMBSPY_GAME_KEYS": "battlefield2",
+			},
+			wantErrContains: "invalid key/value pair \"battlefield2\"",
+		},
+		{
+			name:            "fails for invalid game availability",
+			args:            []string{"-game-availability", "battlefield2=down"},
+			wantErrContains: "validation for 'GameAvailability[battlefield2]' failed on the 'oneof' tag",
+		},
+		{
+			name:            "fails for unknown option in file",
+			file:            "unknown: value\n",
+			wantErrContains: "config file: unknown option \"unknown\"",
+		},
+		{
+			name:            "fails for non-configurable option in file",
+			file:            "version: true\n",
+			wantErrContains: "config file: unknown option \"version\"",
+		},
+		{
+			name:            "fails for invalid value in file",
+			file:            "idle-timeout: forever\n",
+			wantErrContains: "config file: option \"idle-timeout\"",
+		},
+		{
+			name:            "fails for malformed file",
+			file:            "address: [\n",
+			wantErrContains: "failed to parse config file",
+		},
+		{
+			name: "fails for invalid value in environment",
+			env: map[string]string{
+				"DUMBSPY_DEBUG": "maybe",
+			},
+			wantErrContains: "environment variable DUMBSPY_DEBUG",
+		},
+		{
+			name:            "fails for invalid address",
+			args:            []string{"-address", "not-an-address"},
+			wantErrContains: "validation for 'ListenAddr' failed on the 'hostname_port' tag",
+		},
+		{
+			name: "allows empty data file if kept in memory",
+			args: []string{"-in-memory", "-data-file", ""},
+			assertOptions: func(t *testing.T, opts *Options) {
+				assert.True(t, opts.InMemory)
+				assert.Empty(t, opts.DataFile)
+			},
+		},
+		{
+			name:            "fails for empty data file",
+			args:            []string{"-data-file", ""},
+			wantErrContains: "validation for 'DataFile' failed on the 'required_unless' tag",
+		},
+		{
+			name:            "fails for player data file matching data file",
+			args:            []string{"-data-file", "data.json", "-player-data-file", "data.json"},
+			wantErrContains: "validation for 'PlayerDataFile' failed on the 'nefield' tag",
+		},
+		{
+			name:            "fails for idle timeout shorter than keep-alive interval",
+			args:            []string{"-idle-timeout", "10s", "-keep-alive-interval", "20s"},
+			wantErrContains: "validation for 'IdleTimeout' failed on the 'gtfield' tag",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// GIVEN
+			args := tt.args
+			if tt.file != "" {
+				path := filepath.Join(t.TempDir(), "config.yaml")
+				require.NoError(t, os.WriteFile(path, []byte(tt.file), 0o600))
+				args = append([]string{"-config", path}, args...)
+			}
+			fs := flag.NewFlagSet("dumbspy", flag.ContinueOnError)
+			fs.SetOutput(io.Discard)
+			lookupEnv := func(key string) (string, bool) {
+				value, ok := tt.env[key]
+				return value, ok
+			}
+
+			// WHEN
+			opts, err := parse(fs, args, lookupEnv)
+
+			// THEN
+			if tt.wantErrContains != "" {
+				require.ErrorContains(t, err, tt.wantErrContains)
+			} else {
+				require.NoError(t, err)
+				tt.assertOptions(t, opts)
+			}
+		})
+	}
+}
+
+func TestFormatValue(t *testing.T) {
+	type test struct {
+		name          string
+		value         any
+		expectedValue string
+	}
+
+	tests := []test{
+		{
+			name:          "formats string",
+			value:         "some-value",
+			expectedValue: "some-value",
+		},
+		{
+			name:          "formats number",
+			value:         512,
+			expectedValue: "512",
+		},
+		{
+			name:          "formats list",
+			value:         []any{"a", "b"},
+			expectedValue: "a,b",
+		},
+		{
+			name:          "formats map with sorted keys",
+			value:         map[string]any{"stella": "unavailable", "battlefield2": "available"},
+			expectedValue: "battlefield2=available,stella=unavailable",
+		},
+		{
+			name:          "formats null as empty string",
+			value:         nil,
+			expectedValue: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// WHEN
+			actual, err := formatValue(tt.value)
+
+			// THEN
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedValue, actual)
+		})
+	}
+}
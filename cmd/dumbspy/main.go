@@ -1,30 +1,49 @@
 package main
 
 import (
-	"cmp"
+	"context"
 	"errors"
 	"fmt"
 	"io"
+	"maps"
 	"net"
+	"net/http"
+	"net/netip"
 	"os"
+	"os/signal"
+	"slices"
+	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
 	"github.com/dogclan/dumbspy/cmd/dumbspy/internal/options"
 	"github.com/dogclan/dumbspy/internal"
+	"github.com/dogclan/dumbspy/internal/metrics"
+	"github.com/dogclan/dumbspy/internal/negotiations"
+	"github.com/dogclan/dumbspy/internal/presence"
+	"github.com/dogclan/dumbspy/internal/servers"
+	"github.com/dogclan/dumbspy/internal/storage"
 	"github.com/dogclan/dumbspy/pkg/gamespy"
+	"github.com/dogclan/dumbspy/pkg/gamespy/qr2"
 
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 )
 
 const (
-	network      = "tcp4"
-	logKeyRemote = "remote"
-	logKeyData   = "data"
+	network       = "tcp4"
+	packetNetwork = "udp4"
 
-	// Following https://github.com/openspy/openspy-core/blob/5993df54c6b289361228920fa0db7209aed4cfe5/code/SharedTasks/src/OS/GPShared.h#L355
-	errorCodeLoginFailed = "256"
+	// maxDatagramSize is the maximum size of a UDP payload
+	maxDatagramSize = 65507
+
+	// sessionStopTimeout is the time sessions have to finish once stopped after the shutdown grace period expired
+	sessionStopTimeout = time.Second
+
+	logKeyRemote  = "remote"
+	logKeyService = "service"
+	logKeyData    = "data"
 )
 
 var (
@@ -35,7 +54,12 @@ var (
 
 func main() {
 	version := fmt.Sprintf("dumbspy %s (%s) built at %s", buildVersion, buildCommit, buildTime)
-	opts := options.Init()
+	opts, err := options.Init()
+	if err != nil {
+		log.Fatal().
+			Err(err).
+			Msg("Failed to read configuration")
+	}
 
 	// Print version and exit
 	if opts.Version {
@@ -50,176 +74,526 @@ func main() {
 		zerolog.SetGlobalLevel(zerolog.InfoLevel)
 	}
 
-	listen, err := net.Listen(network, opts.ListenAddr)
+	log.Info().
+		Str("version", version).
+		Fields(opts.Values()).
+		Msg("Starting with effective configuration")
+
+	var authenticator internal.Authenticator = internal.AcceptAllAuthenticator{}
+	if opts.AccountsFile != "" {
+		authenticator, err = internal.NewFileAuthenticator(opts.AccountsFile)
+		if err != nil {
+			log.Fatal().
+				Err(err).
+				Str("path", opts.AccountsFile).
+				Msg("Failed to load accounts")
+		}
+
+		log.Info().
+			Str("path", opts.AccountsFile).
+			Msg("Restricting logins to accounts from file")
+	}
+
+	var cdkeyValidator internal.CDKeyValidator = internal.AcceptAllCDKeyValidator{}
+	if opts.CDKeysFile != "" {
+		cdkeyValidator, err = internal.NewFileCDKeyValidator(opts.CDKeysFile)
+		if err != nil {
+			log.Fatal().
+				Err(err).
+				Str("path", opts.CDKeysFile).
+				Msg("Failed to load cd keys")
+		}
+
+		log.Info().
+			Str("path", opts.CDKeysFile).
+			Msg("Restricting cd keys to hashes from file")
+	}
+
+	var store, playerDataStore storage.Store
+	if opts.InMemory {
+		store = storage.NewMemoryStore()
+		playerDataStore = store
+
+		log.Warn().
+			Msg("Keeping data in memory only, player ids and data will be lost on restart")
+	} else {
+		store, err = storage.OpenFileStore(opts.DataFile)
+		if err != nil {
+			log.Fatal().
+				Err(err).
+				Str("path", opts.DataFile).
+				Msg("Failed to open data file")
+		}
+
+		// Player data is written far more often than other data, so it is kept in a separate file unless configured
+		// otherwise
+		playerDataStore = store
+		if opts.PlayerDataFile != "" {
+			playerDataStore, err = storage.OpenFileStore(opts.PlayerDataFile)
+			if err != nil {
+				log.Fatal().
+					Err(err).
+					Str("path", opts.PlayerDataFile).
+					Msg("Failed to open player data file")
+			}
+		}
+	}
+
+	players, err := internal.NewPlayerRegistry(store)
 	if err != nil {
 		log.Fatal().
 			Err(err).
+			Msg("Failed to load player registry")
+	}
+
+	hub := presence.NewHub()
+	gpcm := &gpcmServer{
+		keepAliveInterval: opts.KeepAliveInterval,
+		idleTimeout:       opts.IdleTimeout,
+		maxPacketSize:     opts.MaxPacketSize,
+		authenticator:     authenticator,
+		players:           players,
+		profiles:          internal.NewProfileStore(store),
+		presence:          hub,
+		messageInterval:   opts.MessageInterval,
+		messageBurst:      opts.MessageBurst,
+	}
+	gpsp := &gpspServer{
+		maxPacketSize: opts.MaxPacketSize,
+		players:       players,
+		presence:      hub,
+	}
+	gstatsService := &gstatsServer{
+		idleTimeout:   opts.IdleTimeout,
+		maxPacketSize: opts.MaxPacketSize,
+		authenticator: authenticator,
+		players:       players,
+		playerData:    internal.NewPlayerDataStore(playerDataStore),
+	}
+
+	availability := make(map[string]qr2.Availability, len(opts.GameAvailability))
+	for gameName, name := range opts.GameAvailability {
+		// Names have been validated to be known
+		availability[gameName], _ = qr2.ParseAvailability(name)
+	}
+
+	// Games with a secret key or availability status are known to be in use, any other game name could be made up
+	knownGameNames := slices.Collect(maps.Keys(opts.GameKeys))
+	knownGameNames = slices.AppendSeq(knownGameNames, maps.Keys(opts.GameAvailability))
+	registry := servers.NewRegistry(opts.GameServerTimeout, knownGameNames...)
+	qr2Service := &qr2Server{
+		registry:       registry,
+		availability:   availability,
+		expiryInterval: opts.GameServerTimeout,
+	}
+	natnegService := &natnegServer{
+		registry:       negotiations.NewRegistry(opts.NATNegTimeout),
+		expiryInterval: opts.NATNegTimeout,
+	}
+	cdkeyService := &cdkeyServer{
+		validator: cdkeyValidator,
+	}
+	sb := &sbServer{
+		registry: registry,
+		gameKeys: opts.GameKeys,
+	}
+
+	gpcmListener := listen(serviceGPCM, opts.ListenAddr)
+	gpspListener := listen(serviceGPSP, opts.SearchListenAddr)
+	sbListener := listen(serviceSB, opts.SBListenAddr)
+	gstatsListener := listen(serviceGStats, opts.StatsListenAddr)
+	qr2Conn := listenPacket(serviceQR2, opts.QR2ListenAddr)
+	natnegConn := listenPacket(serviceNATNeg, opts.NATNegListenAddr)
+	cdkeyConn := listenPacket(serviceCDKey, opts.CDKeyListenAddr)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Sessions are only stopped once the shutdown grace period expires
+	sessionCtx, stopSessions := context.WithCancel(context.Background())
+	defer stopSessions()
+
+	var listeners sync.WaitGroup
+	handlers := new(handlerTracker)
+	listeners.Add(1)
+	go func() {
+		defer listeners.Done()
+		serve(ctx, sessionCtx, serviceGPCM, gpcmListener, handlers, gpcm.handleRequest)
+	}()
+	listeners.Add(1)
+	go func() {
+		defer listeners.Done()
+		serve(ctx, sessionCtx, serviceGPSP, gpspListener, handlers, gpsp.handleRequest)
+	}()
+	listeners.Add(1)
+	go func() {
+		defer listeners.Done()
+		serve(ctx, sessionCtx, serviceSB, sbListener, handlers, sb.handleRequest)
+	}()
+	listeners.Add(1)
+	go func() {
+		defer listeners.Done()
+		serve(ctx, sessionCtx, serviceGStats, gstatsListener, handlers, gstatsService.handleRequest)
+	}()
+	listeners.Add(1)
+	go func() {
+		defer listeners.Done()
+		qr2Service.serve(ctx, qr2Conn)
+	}()
+	listeners.Add(1)
+	go func() {
+		defer listeners.Done()
+		natnegService.serve(ctx, natnegConn)
+	}()
+	listeners.Add(1)
+	go func() {
+		defer listeners.Done()
+		cdkeyService.serve(ctx, cdkeyConn)
+	}()
+
+	if opts.MetricsListenAddr != "" {
+		metricsServer := serveMetrics(opts.MetricsListenAddr)
+		defer func() {
+			if err2 := metricsServer.Close(); err2 != nil {
+				log.Error().
+					Err(err2).
+					Str(logKeyService, "metrics").
+					Msg("Failed to close metrics server")
+			}
+		}()
+	}
+
+	<-ctx.Done()
+	// Restore default signal handling, so a second signal terminates immediately
+	stop()
+
+	// Handlers may only be added by listeners, so wait for them to stop accepting connections first
+	listeners.Wait()
+
+	active := handlers.active()
+	log.Info().
+		Int("sessions", active).
+		Stringer("gracePeriod", opts.ShutdownGracePeriod).
+		Msg("Shutting down, waiting for active sessions to finish")
+
+	if handlers.wait(opts.ShutdownGracePeriod) {
+		log.Info().
+			Int("drained", active).
+			Msg("Drained all sessions, exiting")
+		return
+	}
+
+	remaining := handlers.active()
+	log.Warn().
+		Int("drained", active-remaining).
+		Int("remaining", remaining).
+		Msg("Grace period expired before all sessions finished, stopping remaining sessions")
+
+	// Interrupted sessions only need to close their connections, so they should finish quickly
+	stopSessions()
+	if !handlers.wait(sessionStopTimeout) {
+		log.Warn().
+			Int("remaining", handlers.active()).
+			Msg("Sessions did not stop in time, exiting")
+	}
+}
+
+// listen Starts a listener for the given service, exiting if the listener cannot be started.
+func listen(service, address string) net.Listener {
+	listener, err := net.Listen(network, address)
+	if err != nil {
+		log.Fatal().
+			Err(err).
+			Str(logKeyService, service).
 			Msgf("Failed to start listener")
 	}
 
 	log.Info().
-		Str("address", opts.ListenAddr).
+		Str(logKeyService, service).
+		Str("address", address).
 		Msg("Listening for connections")
 
-	// close listener
-	defer func(listen net.Listener) {
-		err2 := listen.Close()
-		if err2 != nil {
+	return listener
+}
+
+// listenPacket Starts a UDP listener for the given service, exiting if the listener cannot be started.
+func listenPacket(service, address string) *net.UDPConn {
+	addr, err := net.ResolveUDPAddr(packetNetwork, address)
+	if err != nil {
+		log.Fatal().
+			Err(err).
+			Str(logKeyService, service).
+			Msg("Failed to resolve listen address")
+	}
+
+	conn, err := net.ListenUDP(packetNetwork, addr)
+	if err != nil {
+		log.Fatal().
+			Err(err).
+			Str(logKeyService, service).
+			Msg("Failed to start listener")
+	}
+
+	log.Info().
+		Str(logKeyService, service).
+		Str("address", address).
+		Msg("Listening for packets")
+
+	return conn
+}
+
+// serve Accepts connections on listener and handles each of them in a new goroutine until ctx is done.
+// Handlers are passed sessionCtx, which signals them to finish up.
+func serve(
+	ctx context.Context,
+	sessionCtx context.Context,
+	service string,
+	listener net.Listener,
+	handlers *handlerTracker,
+	handle func(ctx context.Context, conn net.Conn),
+) {
+	// Closing the listener unblocks Accept and thus ends the accept loop
+	context.AfterFunc(ctx, func() {
+		err := listener.Close()
+		if err != nil {
 			log.Error().
-				Err(err2).
+				Err(err).
+				Str(logKeyService, service).
 				Msg("Failed to close listener")
 		}
-	}(listen)
+	})
 
 	for {
-		conn, err2 := listen.Accept()
-		if err2 != nil {
+		conn, err := listener.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				log.Debug().
+					Str(logKeyService, service).
+					Msg("Stopped accepting new connections")
+				return
+			}
+
 			log.Error().
-				Err(err2).
+				Err(err).
+				Str(logKeyService, service).
 				Msg("Failed to accept new connection")
 		} else {
-			go handleRequest(conn)
+			handlers.Go(func() {
+				metrics.ActiveConnections.With(service).Inc()
+				defer metrics.ActiveConnections.With(service).Dec()
+				handle(sessionCtx, conn)
+			})
 		}
 	}
 }
 
-func handleRequest(conn net.Conn) {
-	remoteAddr := conn.RemoteAddr().String()
-	defer func(conn net.Conn) {
-		err := conn.Close()
+// servePackets Handles packets received on conn until ctx is done, sending any reply returned by handle back to the
+// packet's sender.
+func servePackets(
+	ctx context.Context,
+	service string,
+	conn *net.UDPConn,
+	handle func(addr netip.AddrPort, b []byte) ([]byte, error),
+) {
+	// Closing the connection unblocks ReadFrom and thus ends the read loop
+	context.AfterFunc(ctx, func() {
+		if err := conn.Close(); err != nil {
+			log.Error().
+				Err(err).
+				Str(logKeyService, service).
+				Msg("Failed to close listener")
+		}
+	})
+
+	buf := make([]byte, maxDatagramSize)
+	for {
+		n, addr, err := conn.ReadFromUDPAddrPort(buf)
 		if err != nil {
+			if ctx.Err() != nil {
+				log.Debug().
+					Str(logKeyService, service).
+					Msg("Stopped receiving packets")
+				return
+			}
+
 			log.Error().
 				Err(err).
-				Str(logKeyRemote, remoteAddr).
-				Msg("Failed to close connection")
+				Str(logKeyService, service).
+				Msg("Failed to read packet")
+			continue
 		}
-	}(conn)
-
-	challenge := gamespy.RandString(10)
-	prompt := new(gamespy.Packet)
-	prompt.Add("lc", "1")
-	prompt.Add("challenge", challenge)
-	prompt.Add("id", "1")
-
-	log.Debug().
-		Bytes(logKeyData, prompt.Bytes()).
-		Str(logKeyRemote, remoteAddr).
-		Msg("Sending challenge prompt")
-	if err := write(conn, prompt); err != nil {
-		log.Error().
-			Err(err).
-			Str(logKeyRemote, remoteAddr).
-			Msg("Failed to send challenge")
-		return
+
+		addr = netip.AddrPortFrom(addr.Addr().Unmap(), addr.Port())
+		reply, err := handle(addr, buf[:n])
+		if err != nil {
+			log.Warn().
+				Err(err).
+				Str(logKeyService, service).
+				Bytes(logKeyData, buf[:n]).
+				Str(logKeyRemote, addr.String()).
+				Msg("Failed to handle packet")
+			continue
+		}
+
+		if reply != nil {
+			if _, err = conn.WriteToUDPAddrPort(reply, addr); err != nil {
+				log.Error().
+					Err(err).
+					Str(logKeyService, service).
+					Str(logKeyRemote, addr.String()).
+					Msg("Failed to send reply")
+			}
+		}
+	}
+}
+
+// serveMetrics Starts an HTTP server exposing metrics on /metrics, exiting if the listener cannot be started.
+func serveMetrics(address string) *http.Server {
+	mux := http.NewServeMux()
+	mux.Handle("GET /metrics", metrics.DefaultRegistry.Handler())
+	server := &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 5 * time.Second,
 	}
 
-	log.Debug().
-		Str(logKeyRemote, remoteAddr).
-		Msg("Reading login request")
-	req, err := read(conn)
-	if err != nil {
-		// EOF and timeout errors are not of interest => only log to debug
-		if errors.Is(err, io.EOF) || errors.Is(err, syscall.ECONNRESET) {
-			log.Debug().
-				Str(logKeyRemote, remoteAddr).
-				Msg("Peer closed/reset connection while reading login request")
-		} else if errors.Is(err, os.ErrDeadlineExceeded) {
-			log.Debug().
-				Str(logKeyRemote, remoteAddr).
-				Msg("Timed out reading login request")
-		} else {
+	listener := listen("metrics", address)
+	go func() {
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
 			log.Error().
 				Err(err).
-				Str(logKeyRemote, remoteAddr).
-				Msg("Failed to read login request")
+				Str(logKeyService, "metrics").
+				Msg("Metrics server failed")
 		}
-		return
+	}()
+
+	return server
+}
+
+// handlerTracker Tracks active connection handlers, so they can be drained on shutdown.
+type handlerTracker struct {
+	wg sync.WaitGroup
+	n  atomic.Int32
+}
+
+func (t *handlerTracker) Go(f func()) {
+	t.n.Add(1)
+	t.wg.Add(1)
+	go func() {
+		defer t.wg.Done()
+		defer t.n.Add(-1)
+		f()
+	}()
+}
+
+func (t *handlerTracker) active() int {
+	return int(t.n.Load())
+}
+
+// wait Waits for all handlers to finish. Returns false if handlers are still active after timeout.
+func (t *handlerTracker) wait(timeout time.Duration) bool {
+	done := make(chan struct{})
+	go func() {
+		t.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return true
+	case <-time.After(timeout):
+		return false
 	}
+}
 
-	log.Debug().
-		Bytes(logKeyData, req.Bytes()).
-		Str(logKeyRemote, remoteAddr).
-		Msg("Received login request")
+type packetReader interface {
+	ReadPacket() (*gamespy.Packet, error)
+}
 
-	res := new(gamespy.Packet)
-	var login internal.GamespyLoginRequest
-	if err = cmp.Or(req.Bind(&login), login.Validate()); err != nil {
-		log.Error().
-			Err(err).
-			Str(logKeyRemote, remoteAddr).
-			Msg("Received invalid login request")
-
-		res.Add("error", "")
-		res.Add("err", errorCodeLoginFailed)
-		res.Add("fatal", "")
-		res.Add("errmsg", "There was an error logging in to the GP backend.")
-		res.Add("id", "1")
-
-		log.Debug().
-			Bytes("data", res.Bytes()).
-			Str(logKeyRemote, remoteAddr).
-			Msg("Sending error response")
-	} else {
-		playerID := internal.GetPlayerID(
-			login.UniqueNick,
-			login.ProductID,
-			login.GameName,
-			login.NamespaceID,
-			login.SDKRevision,
-		)
-		res.Add("lc", "2")
-		res.AddInt("sesskey", int(gamespy.ComputeCRC16(login.UniqueNick)))
-		res.Add("proof", gamespy.GenerateProof(
-			login.UniqueNick,
-			login.Response,
-			challenge,
-			login.Challenge,
-		))
-		res.AddInt("userid", playerID)
-		res.AddInt("profileid", playerID)
-		res.Add("uniquenick", login.UniqueNick)
-		res.Add("lt", gamespy.RandString(22)+"__")
-		res.Add("id", "1")
-
-		log.Debug().
-			Bytes(logKeyData, res.Bytes()).
-			Str(logKeyRemote, remoteAddr).
-			Msg("Sending login response")
-	}
-
-	if err = write(conn, res); err != nil {
-		log.Error().
-			Err(err).
-			Str(logKeyRemote, remoteAddr).
-			Msg("Failed to send response")
+type packetWriter interface {
+	WritePacket(packet *gamespy.Packet) error
+}
+
+// packetConn Wraps a connection to read and write gamespy packets with deadlines. Writes are safe for concurrent use,
+// reads are not.
+type packetConn struct {
+	net.Conn
+	service string
+	reader  packetReader
+	writer  packetWriter
+	writeMu sync.Mutex
+}
+
+func newPacketConn(conn net.Conn, service string, maxPacketSize int) *packetConn {
+	return &packetConn{
+		Conn:    conn,
+		service: service,
+		reader:  gamespy.NewReaderSize(conn, maxPacketSize),
+		writer:  gamespy.NewWriter(conn),
 	}
 }
 
-func write(conn net.Conn, packet *gamespy.Packet) error {
-	if err := conn.SetWriteDeadline(time.Now().Add(time.Second)); err != nil {
+func (c *packetConn) write(packet *gamespy.Packet) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	if err := c.SetWriteDeadline(time.Now().Add(time.Second)); err != nil {
 		return fmt.Errorf("failed to set write deadline: %w", err)
 	}
 
-	if _, err := conn.Write(packet.Bytes()); err != nil {
+	if err := c.writer.WritePacket(packet); err != nil {
+		if errors.Is(err, os.ErrDeadlineExceeded) {
+			metrics.WriteTimeouts.With(c.service).Inc()
+		} else if errors.Is(err, syscall.ECONNRESET) {
+			metrics.PeerResets.With(c.service).Inc()
+		}
 		return fmt.Errorf("failed to write packet: %w", err)
 	}
 	return nil
 }
 
-func read(conn net.Conn) (*gamespy.Packet, error) {
-	if err := conn.SetReadDeadline(time.Now().Add(time.Second)); err != nil {
+// read Reads the next packet, waiting until deadline at most. Reads are interrupted once ctx is done, provided
+// interruptOnDone has been called for ctx.
+func (c *packetConn) read(ctx context.Context, deadline time.Time) (*gamespy.Packet, error) {
+	if err := c.SetReadDeadline(deadline); err != nil {
 		return nil, fmt.Errorf("failed to set read deadline: %w", err)
 	}
 
-	buffer := make([]byte, 512)
-	n, err := conn.Read(buffer)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read packet: %w", err)
+	// Setting the deadline may have replaced the deadline set to interrupt reads, so restore it if ctx is done
+	if ctx.Err() != nil {
+		if err := c.SetReadDeadline(time.Now()); err != nil {
+			return nil, fmt.Errorf("failed to set read deadline: %w", err)
+		}
 	}
 
-	packet, err := gamespy.NewPacketFromBytes(buffer[:n])
+	packet, err := c.reader.ReadPacket()
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse packet: %w", err)
+		// Read timeouts are not counted here, since they are also used to interrupt reads
+		if errors.Is(err, syscall.ECONNRESET) {
+			metrics.PeerResets.With(c.service).Inc()
+		}
+		return nil, fmt.Errorf("failed to read packet: %w", err)
 	}
 	return packet, nil
 }
+
+// isPeerClosed Checks whether err was caused by the peer closing or resetting the connection.
+func isPeerClosed(err error) bool {
+	return errors.Is(err, io.EOF) || errors.Is(err, syscall.ECONNRESET)
+}
+
+// interruptOnDone Unblocks any pending read on conn once ctx is done. The returned function stops the interruption.
+// Any read deadline set after calling interruptOnDone replaces the interrupting deadline, so callers setting
+// deadlines must check ctx after doing so (see packetConn.read).
+func interruptOnDone(ctx context.Context, conn net.Conn) func() bool {
+	return context.AfterFunc(ctx, func() {
+		_ = conn.SetReadDeadline(time.Now())
+	})
+}
+
+// command Returns the first key of packet, which denotes the packet's type/command.
+func command(packet *gamespy.Packet) string {
+	for element := range packet.All() {
+		return element.Key
+	}
+	return ""
+}
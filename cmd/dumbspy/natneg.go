@@ -0,0 +1,170 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/netip"
+	"time"
+
+	"github.com/dogclan/dumbspy/internal/negotiations"
+	"github.com/dogclan/dumbspy/pkg/gamespy/natneg"
+
+	"github.com/rs/zerolog/log"
+)
+
+const serviceNATNeg = "natneg"
+
+// natnegServer Pairs clients negotiating a peer-to-peer connection by their cookie and tells each of them the public
+// address of its peer. Only a single address is used, so the reachability tests are always answered from the same
+// address and port.
+type natnegServer struct {
+	registry *negotiations.Registry
+	// expiryInterval is the interval at which unpaired negotiations are timed out
+	expiryInterval time.Duration
+	conn           *net.UDPConn
+}
+
+// serve Handles packets received on conn until ctx is done.
+func (s *natnegServer) serve(ctx context.Context, conn *net.UDPConn) {
+	s.conn = conn
+	go s.expire(ctx)
+	servePackets(ctx, serviceNATNeg, conn, s.handlePacket)
+}
+
+// handlePacket Handles a packet received from addr. Returns the reply to send, if any.
+func (s *natnegServer) handlePacket(addr netip.AddrPort, b []byte) ([]byte, error) {
+	packet, err := natneg.ParsePacket(b)
+	if err != nil {
+		return nil, err
+	}
+
+	switch packet.Type {
+	case natneg.PacketInit:
+		init, err2 := natneg.ParseInit(packet.Data)
+		if err2 != nil {
+			return nil, fmt.Errorf("invalid init: %w", err2)
+		}
+
+		log.Debug().
+			Uint32("cookie", packet.Cookie).
+			Uint8("client", init.ClientIndex).
+			Uint8("portType", byte(init.PortType)).
+			Str("gamename", init.GameName).
+			Str(logKeyRemote, addr.String()).
+			Msg("Received init")
+
+		if clients, ok := s.registry.Init(packet.Cookie, packet.Version, init, addr); ok {
+			s.connect(clients[0], clients[1])
+			s.connect(clients[1], clients[0])
+		} else {
+			s.reconnect(packet.Cookie, init.ClientIndex)
+		}
+		return natneg.NewInitAck(packet.Version, packet.Cookie, init), nil
+	case natneg.PacketAddressCheck:
+		init, err2 := natneg.ParseInit(packet.Data)
+		if err2 != nil {
+			return nil, fmt.Errorf("invalid address check: %w", err2)
+		}
+		return natneg.NewAddressReply(packet.Version, packet.Cookie, init, addr), nil
+	case natneg.PacketNATifyRequest:
+		init, err2 := natneg.ParseInit(packet.Data)
+		if err2 != nil {
+			return nil, fmt.Errorf("invalid NATify request: %w", err2)
+		}
+		return natneg.NewERTTest(packet.Version, packet.Cookie, init), nil
+	case natneg.PacketReport:
+		report, err2 := natneg.ParseReport(packet.Data)
+		if err2 != nil {
+			return nil, fmt.Errorf("invalid report: %w", err2)
+		}
+
+		log.Info().
+			Uint32("cookie", packet.Cookie).
+			Uint8("client", report.ClientIndex).
+			Bool("success", report.Result == 1).
+			Uint32("natType", report.NATType).
+			Str("gamename", report.GameName).
+			Str(logKeyRemote, addr.String()).
+			Msg("Received negotiation report")
+		s.reconnect(packet.Cookie, report.ClientIndex)
+		return natneg.NewReportAck(packet.Version, packet.Cookie, report), nil
+	case natneg.PacketConnectAck:
+		// Connect acks use the init layout
+		ack, err2 := natneg.ParseInit(packet.Data)
+		if err2 != nil {
+			return nil, fmt.Errorf("invalid connect ack: %w", err2)
+		}
+		s.registry.Acknowledge(packet.Cookie, ack.ClientIndex)
+		return nil, nil
+	case natneg.PacketERTAck:
+		// Acknowledgements require no further action
+		return nil, nil
+	default:
+		log.Debug().
+			Int("type", int(packet.Type)).
+			Str(logKeyRemote, addr.String()).
+			Msg("Ignoring unsupported packet")
+		return nil, nil
+	}
+}
+
+// connect Sends a paired client the public address of its peer.
+func (s *natnegServer) connect(client, peer negotiations.Client) {
+	// Both addresses are known once clients are paired
+	to, _ := client.Addr()
+	peerAddr, _ := peer.Addr()
+
+	log.Info().
+		Uint32("cookie", client.Cookie).
+		Uint8("client", client.Index).
+		Str("peer", peerAddr.String()).
+		Str(logKeyRemote, to.String()).
+		Msg("Connecting negotiation peers")
+	s.send(to, natneg.NewConnect(client.Version, client.Cookie, peerAddr, natneg.FinishedNoError))
+}
+
+// reconnect Resends the connect packet to a paired client which did not acknowledge it yet. Clients retry their
+// inits/reports if the connect packet got lost.
+func (s *natnegServer) reconnect(cookie uint32, index byte) {
+	if client, peer, ok := s.registry.Pending(cookie, index); ok {
+		s.connect(client, peer)
+	}
+}
+
+// expire Periodically times out unpaired negotiations until ctx is done, notifying clients left without a peer.
+func (s *natnegServer) expire(ctx context.Context) {
+	ticker := time.NewTicker(s.expiryInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for _, client := range s.registry.Expire() {
+				to, ok := client.Addr()
+				if !ok {
+					continue
+				}
+
+				log.Info().
+					Uint32("cookie", client.Cookie).
+					Uint8("client", client.Index).
+					Str(logKeyRemote, to.String()).
+					Msg("Negotiation timed out without peer")
+				s.send(to, natneg.NewConnect(client.Version, client.Cookie, netip.AddrPort{}, natneg.FinishedErrorDeadbeatPartner))
+			}
+		}
+	}
+}
+
+func (s *natnegServer) send(addr netip.AddrPort, b []byte) {
+	if _, err := s.conn.WriteToUDPAddrPort(b, addr); err != nil {
+		log.Error().
+			Err(err).
+			Str(logKeyService, serviceNATNeg).
+			Str(logKeyRemote, addr.String()).
+			Msg("Failed to send packet")
+	}
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"net"
+	"net/netip"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dogclan/dumbspy/internal/negotiations"
+	"github.com/dogclan/dumbspy/pkg/gamespy/codec"
+	"github.com/dogclan/dumbspy/pkg/gamespy/natneg"
+)
+
+const testCookie = 1234
+
+// newTestNATNegPacket Returns a version 3 packet of type t carrying init data for the client index.
+func newTestNATNegPacket(t natneg.PacketType, clientIndex byte) []byte {
+	e := newTestNATNegEncoder(t)
+	e.Uint8(byte(natneg.PortTypeNN1))
+	e.Uint8(clientIndex)
+	e.Bool(false)
+	e.AddrPort(netip.MustParseAddrPort("192.168.0.5:16567"))
+	e.CString("battlefield2")
+	return e.Bytes()
+}
+
+// newTestNATNegReport Returns a version 3 report of a successful negotiation for the client index.
+func newTestNATNegReport(clientIndex byte) []byte {
+	e := newTestNATNegEncoder(natneg.PacketReport)
+	e.Uint8(byte(natneg.PortTypeNN1))
+	e.Uint8(clientIndex)
+	e.Uint8(1)
+	e.Uint32(0)
+	e.Uint32(0)
+	e.FixedString("battlefield2", 50)
+	return e.Bytes()
+}
+
+// newTestNATNegEncoder Returns an encoder holding the header of a version 3 packet of type t.
+func newTestNATNegEncoder(t natneg.PacketType) *codec.Encoder {
+	e := codec.NewEncoder([]byte{0xFD, 0xFC, 0x1E, 0x66, 0x6A, 0xB2, 3, byte(t)})
+	e.Uint32(testCookie)
+	return e
+}
+
+// listenTestClient Returns a local UDP socket for a negotiating client.
+func listenTestClient(t *testing.T) (*net.UDPConn, netip.AddrPort) {
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = conn.Close()
+	})
+	return conn, conn.LocalAddr().(*net.UDPAddr).AddrPort()
+}
+
+// receiveConnect Reads the next packet sent to conn and returns the data of the connect packet.
+func receiveConnect(t *testing.T, conn *net.UDPConn) []byte {
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(time.Second)))
+	buf := make([]byte, maxDatagramSize)
+	n, err := conn.Read(buf)
+	require.NoError(t, err)
+	packet, err := natneg.ParsePacket(buf[:n])
+	require.NoError(t, err)
+	require.Equal(t, natneg.PacketConnect, packet.Type)
+	return packet.Data
+}
+
+// assertNoPacket Asserts that no packet is sent to conn for a short while.
+func assertNoPacket(t *testing.T, conn *net.UDPConn) {
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(50*time.Millisecond)))
+	_, err := conn.Read(make([]byte, maxDatagramSize))
+	assert.ErrorIs(t, err, os.ErrDeadlineExceeded, "unexpected packet")
+}
+
+func TestNATNegServer_HandlePacket(t *testing.T) {
+	t.Run("resends connect to client retrying after being paired", func(t *testing.T) {
+		// GIVEN
+		serverConn, _ := listenTestClient(t)
+		s := &natnegServer{
+			registry: negotiations.NewRegistry(time.Minute),
+			conn:     serverConn,
+		}
+		connA, addrA := listenTestClient(t)
+		connB, addrB := listenTestClient(t)
+		_, err := s.handlePacket(addrA, newTestNATNegPacket(natneg.PacketInit, 0))
+		require.NoError(t, err)
+		_, err = s.handlePacket(addrB, newTestNATNegPacket(natneg.PacketInit, 1))
+		require.NoError(t, err)
+		connect, err := natneg.ParsePacket(natneg.NewConnect(3, testCookie, addrB, natneg.FinishedNoError))
+		require.NoError(t, err)
+		expected := connect.Data
+		require.Equal(t, expected, receiveConnect(t, connA))
+		receiveConnect(t, connB)
+
+		// WHEN
+		reply, err := s.handlePacket(addrA, newTestNATNegPacket(natneg.PacketInit, 0))
+
+		// THEN
+		require.NoError(t, err)
+		assert.NotEmpty(t, reply)
+		assert.Equal(t, expected, receiveConnect(t, connA))
+
+		// WHEN
+		_, err = s.handlePacket(addrA, newTestNATNegPacket(natneg.PacketConnectAck, 0))
+		require.NoError(t, err)
+		_, err = s.handlePacket(addrA, newTestNATNegPacket(natneg.PacketInit, 0))
+
+		// THEN
+		// Connect is not resent once acknowledged
+		require.NoError(t, err)
+		assertNoPacket(t, connA)
+
+		// WHEN
+		_, err = s.handlePacket(addrB, newTestNATNegReport(1))
+
+		// THEN
+		// Peer did not acknowledge its connect yet
+		require.NoError(t, err)
+		receiveConnect(t, connB)
+	})
+}
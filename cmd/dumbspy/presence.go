@@ -0,0 +1,90 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"time"
+
+	"github.com/dogclan/dumbspy/internal"
+	"github.com/dogclan/dumbspy/internal/metrics"
+	"github.com/dogclan/dumbspy/internal/presence"
+	"github.com/dogclan/dumbspy/internal/ratelimit"
+	"github.com/dogclan/dumbspy/pkg/gamespy"
+)
+
+var errFloodLimit = errors.New("message flood limit exceeded")
+
+// handlePresence Handles status updates, buddy list changes and buddy messages sent by a logged in client. Messages are
+// dropped if the client exceeds messageLimit.
+func (s *gpcmServer) handlePresence(
+	session *presence.Session,
+	messageLimit *ratelimit.Bucket,
+	cmd string,
+	packet *gamespy.Packet,
+) error {
+	switch cmd {
+	case "status":
+		var req internal.GamespyStatusRequest
+		if err := packet.Bind(&req); err != nil {
+			return err
+		}
+		session.SetStatus(presence.Status{
+			Code:       req.Status,
+			StatString: req.StatString,
+			LocString:  req.LocString,
+		})
+	case "addbuddy":
+		var req internal.GamespyAddBuddyRequest
+		if err := packet.Bind(&req); err != nil {
+			return err
+		}
+		if _, ok := s.players.Lookup(req.NewProfileID); !ok {
+			return fmt.Errorf("unknown profile %d", req.NewProfileID)
+		}
+		return session.AddBuddy(req.NewProfileID, req.Reason)
+	case "authadd":
+		var req internal.GamespyAuthAddRequest
+		if err := packet.Bind(&req); err != nil {
+			return err
+		}
+		return session.AuthAdd(req.FromProfileID)
+	case "delbuddy":
+		var req internal.GamespyDelBuddyRequest
+		if err := packet.Bind(&req); err != nil {
+			return err
+		}
+		session.DelBuddy(req.DelProfileID)
+	case "bm":
+		var req internal.GamespyBuddyMessageRequest
+		if err := packet.Bind(&req); err != nil {
+			return err
+		}
+		if req.Type != internal.BuddyMessageText {
+			return fmt.Errorf("unsupported buddy message type %d", req.Type)
+		}
+		if _, ok := s.players.Lookup(req.To); !ok {
+			return fmt.Errorf("unknown profile %d", req.To)
+		}
+		if !messageLimit.Allow(time.Now()) {
+			metrics.MessagesRateLimited.Inc()
+			return errFloodLimit
+		}
+		session.SendMessage(req.To, req.Message)
+		metrics.MessagesRelayed.Inc()
+	default:
+		return fmt.Errorf("unsupported presence command %q", cmd)
+	}
+
+	return nil
+}
+
+// gamePeer Returns the address buddies can reach the player's game at (remote IP and the game port reported on login).
+func gamePeer(remoteAddr net.Addr, port int) netip.AddrPort {
+	var addr netip.Addr
+	if tcpAddr, ok := remoteAddr.(*net.TCPAddr); ok {
+		addr = tcpAddr.AddrPort().Addr().Unmap()
+	}
+	return netip.AddrPortFrom(addr, uint16(port))
+}
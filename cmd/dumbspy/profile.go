@@ -0,0 +1,108 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/dogclan/dumbspy/internal"
+	"github.com/dogclan/dumbspy/pkg/gamespy"
+)
+
+var errUnknownProfile = errors.New("unknown profile")
+
+// handleProfile Answers profile requests and applies profile/user info updates sent by a logged in client. Clients
+// only update their own profile.
+func (s *gpcmServer) handleProfile(conn *packetConn, playerID int, cmd string, packet *gamespy.Packet) error {
+	switch cmd {
+	case "getprofile":
+		var req internal.GamespyProfileRequest
+		if err := packet.Bind(&req); err != nil {
+			return err
+		}
+
+		profile, err := s.profile(req.ProfileID)
+		if errors.Is(err, errUnknownProfile) {
+			// Error is not fatal, session remains usable
+			res := gamespy.NewError(gamespy.ErrorCodeGetProfileBadProfile).Packet(req.ID)
+			if err2 := conn.write(res); err2 != nil {
+				return err2
+			}
+			return fmt.Errorf("%w %d", err, req.ProfileID)
+		} else if err != nil {
+			return err
+		}
+
+		if req.ProfileID != playerID {
+			// Email addresses are private, so they are only returned to the profile's owner
+			profile.Email = ""
+		}
+
+		res, err := gamespy.Marshal(internal.GamespyProfileResponse{
+			ProfileID: profile.ID,
+			Nick:      profile.Nick,
+			UserID:    profile.UserID,
+			Email:     profile.Email,
+			// Clients cache profiles by signature, so it needs to change whenever the profile changes
+			Signature:   gamespy.ComputeMD5(fmt.Sprintf("%+v", profile)),
+			UniqueNick:  profile.UniqueNick,
+			FirstName:   profile.FirstName,
+			LastName:    profile.LastName,
+			HomePage:    profile.HomePage,
+			ZipCode:     profile.ZipCode,
+			CountryCode: profile.CountryCode,
+			Location:    profile.Location,
+			Birthday:    profile.Birthday,
+			Sex:         profile.Sex,
+			ICQUIN:      profile.ICQUIN,
+			ID:          req.ID,
+		})
+		if err != nil {
+			return err
+		}
+		return conn.write(res)
+	case "updatepro":
+		var req internal.GamespyUpdateProfileRequest
+		if err := packet.Bind(&req); err != nil {
+			return err
+		}
+		return s.updateProfile(playerID, req.Apply)
+	case "updateui":
+		var req internal.GamespyUpdateUserInfoRequest
+		if err := packet.Bind(&req); err != nil {
+			return err
+		}
+		if req.Email == nil {
+			return nil
+		}
+		return s.updateProfile(playerID, func(profile *internal.Profile) {
+			profile.Email = *req.Email
+		})
+	default:
+		return fmt.Errorf("unsupported profile command %q", cmd)
+	}
+}
+
+// profile Returns the stored profile or, for players who have not logged in since profiles were introduced, a profile
+// with defaults.
+func (s *gpcmServer) profile(profileID int) (internal.Profile, error) {
+	profile, ok, err := s.profiles.Get(profileID)
+	if err != nil || ok {
+		return profile, err
+	}
+
+	player, ok := s.players.Lookup(profileID)
+	if !ok {
+		return internal.Profile{}, errUnknownProfile
+	}
+	return internal.NewProfile(player), nil
+}
+
+func (s *gpcmServer) updateProfile(playerID int, f func(profile *internal.Profile)) error {
+	defaults, err := s.profile(playerID)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.profiles.Update(defaults, f)
+	return err
+}
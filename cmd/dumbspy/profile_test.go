@@ -0,0 +1,227 @@
+package main
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dogclan/dumbspy/internal"
+	"github.com/dogclan/dumbspy/internal/storage"
+	"github.com/dogclan/dumbspy/pkg/gamespy"
+)
+
+// newTestConn Returns a connection to pass to handlers. The returned function closes the connection and returns all
+// packets written to it.
+func newTestConn() (*packetConn, func() []*gamespy.Packet) {
+	client, server := net.Pipe()
+	received := make(chan []*gamespy.Packet, 1)
+	go func() {
+		var packets []*gamespy.Packet
+		reader := gamespy.NewReader(client)
+		for {
+			packet, err := reader.ReadPacket()
+			if err != nil {
+				received <- packets
+				return
+			}
+			packets = append(packets, packet)
+		}
+	}()
+
+	conn := newPacketConn(server, serviceGPCM, gamespy.DefaultMaxPacketSize)
+	return conn, func() []*gamespy.Packet {
+		_ = conn.Close()
+		return <-received
+	}
+}
+
+var (
+	someProfile = internal.Profile{
+		ID:          600000001,
+		UserID:      600000001,
+		Nick:        "some-nick",
+		UniqueNick:  "some-nick",
+		Email:       "some-nick@example.com",
+		FirstName:   "Some",
+		CountryCode: "DE",
+		Sex:         internal.SexFemale,
+	}
+	otherProfile = internal.Profile{
+		ID:         600000002,
+		UserID:     600000002,
+		Nick:       "other-nick",
+		UniqueNick: "other-nick",
+		Email:      "other-nick@example.com",
+		Sex:        internal.SexPat,
+	}
+)
+
+func TestGPCMServer_HandleProfile(t *testing.T) {
+	type test struct {
+		name              string
+		givenProfiles     []internal.Profile
+		givenPlayerID     int
+		givenPacket       string
+		expectedResponses []map[string]string
+		expectedProfile   internal.Profile
+		wantErrContains   string
+	}
+
+	tests := []test{
+		{
+			name:          "getprofile returns own profile including email",
+			givenProfiles: []internal.Profile{someProfile, otherProfile},
+			givenPlayerID: someProfile.ID,
+			givenPacket:   `\getprofile\\sesskey\1\profileid\600000001\id\2\final\`,
+			expectedResponses: []map[string]string{
+				{
+					"pi":          "",
+					"profileid":   "600000001",
+					"nick":        "some-nick",
+					"uniquenick":  "some-nick",
+					"email":       "some-nick@example.com",
+					"firstname":   "Some",
+					"countrycode": "DE",
+					"sex":         "1",
+					"id":          "2",
+				},
+			},
+			expectedProfile: someProfile,
+		},
+		{
+			name:          "getprofile does not return email of other player's profile",
+			givenProfiles: []internal.Profile{someProfile, otherProfile},
+			givenPlayerID: someProfile.ID,
+			givenPacket:   `\getprofile\\sesskey\1\profileid\600000002\id\3\final\`,
+			expectedResponses: []map[string]string{
+				{
+					"pi":         "",
+					"profileid":  "600000002",
+					"nick":       "other-nick",
+					"uniquenick": "other-nick",
+					"email":      "",
+					"sex":        "2",
+					"id":         "3",
+				},
+			},
+			expectedProfile: someProfile,
+		},
+		{
+			name:          "getprofile returns error for unknown profile",
+			givenProfiles: []internal.Profile{someProfile},
+			givenPlayerID: someProfile.ID,
+			givenPacket:   `\getprofile\\sesskey\1\profileid\600000003\id\4\final\`,
+			expectedResponses: []map[string]string{
+				{
+					"error": "",
+					"err":   "2561",
+					"id":    "4",
+				},
+			},
+			expectedProfile: someProfile,
+			wantErrContains: "unknown profile 600000003",
+		},
+		{
+			name:          "updatepro updates fields present in request",
+			givenProfiles: []internal.Profile{someProfile},
+			givenPlayerID: someProfile.ID,
+			givenPacket:   `\updatepro\\sesskey\1\lastname\Player\loc\Berlin\birthday\131073979\final\`,
+			expectedProfile: func() internal.Profile {
+				profile := someProfile
+				profile.LastName = "Player"
+				profile.Location = "Berlin"
+				profile.Birthday = 131073979
+				return profile
+			}(),
+		},
+		{
+			name:            "updatepro fails for invalid value",
+			givenProfiles:   []internal.Profile{someProfile},
+			givenPlayerID:   someProfile.ID,
+			givenPacket:     `\updatepro\\sesskey\1\sex\female\final\`,
+			expectedProfile: someProfile,
+			wantErrContains: "GamespyUpdateProfileRequest.Sex",
+		},
+		{
+			name:          "updateui updates email",
+			givenProfiles: []internal.Profile{someProfile},
+			givenPlayerID: someProfile.ID,
+			givenPacket:   `\updateui\\sesskey\1\email\new@example.com\cpubrandid\1\final\`,
+			expectedProfile: func() internal.Profile {
+				profile := someProfile
+				profile.Email = "new@example.com"
+				return profile
+			}(),
+		},
+		{
+			name:            "updateui ignores request without email",
+			givenProfiles:   []internal.Profile{someProfile},
+			givenPlayerID:   someProfile.ID,
+			givenPacket:     `\updateui\\sesskey\1\cpubrandid\1\final\`,
+			expectedProfile: someProfile,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// GIVEN
+			s := newTestGPCMServer(t, storage.NewMemoryStore())
+			for _, profile := range tt.givenProfiles {
+				_, err := s.profiles.Create(profile)
+				require.NoError(t, err)
+			}
+			packet, err := gamespy.NewPacketFromString(tt.givenPacket)
+			require.NoError(t, err)
+			conn, responses := newTestConn()
+
+			// WHEN
+			err = s.handleProfile(conn, tt.givenPlayerID, command(packet), packet)
+
+			// THEN
+			if tt.wantErrContains != "" {
+				require.ErrorContains(t, err, tt.wantErrContains)
+			} else {
+				require.NoError(t, err)
+			}
+			packets := responses()
+			require.Len(t, packets, len(tt.expectedResponses))
+			for i, expected := range tt.expectedResponses {
+				for key, value := range expected {
+					actual, ok := packets[i].Lookup(key)
+					assert.True(t, ok, key)
+					assert.Equal(t, value, actual, key)
+				}
+			}
+			profile, ok, err := s.profiles.Get(tt.givenPlayerID)
+			require.NoError(t, err)
+			require.True(t, ok)
+			assert.Equal(t, tt.expectedProfile, profile)
+		})
+	}
+
+	t.Run("getprofile returns defaults for player without stored profile", func(t *testing.T) {
+		// GIVEN
+		s := newTestGPCMServer(t, storage.NewMemoryStore())
+		playerID := s.players.GetPlayerID("some-nick", "10493", "battlefield2", "12", "3")
+		packet := gamespy.NewPacket(
+			gamespy.KeyValuePair{Key: "getprofile"},
+			gamespy.KeyValuePair{Key: "sesskey", Value: "1"},
+		)
+		packet.AddInt("profileid", playerID)
+		packet.AddInt("id", 2)
+		conn, responses := newTestConn()
+
+		// WHEN
+		err := s.handleProfile(conn, playerID, "getprofile", packet)
+
+		// THEN
+		require.NoError(t, err)
+		packets := responses()
+		require.Len(t, packets, 1)
+		assert.Equal(t, "some-nick", packets[0].Get("nick"))
+		assert.Equal(t, "some-nick", packets[0].Get("uniquenick"))
+		assert.Equal(t, "2", packets[0].Get("sex"))
+	})
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/netip"
+	"time"
+
+	"github.com/dogclan/dumbspy/internal/servers"
+	"github.com/dogclan/dumbspy/pkg/gamespy"
+	"github.com/dogclan/dumbspy/pkg/gamespy/qr2"
+
+	"github.com/rs/zerolog/log"
+)
+
+const serviceQR2 = "qr2"
+
+// qr2Server Receives heartbeats from game servers and keeps track of them in the server registry. Challenge responses
+// are not verified, since that requires knowing each game's secret key. Also answers the availability checks game
+// clients send before showing online menus.
+type qr2Server struct {
+	registry *servers.Registry
+	// availability is the status reported for games by game name, games not contained are available
+	availability map[string]qr2.Availability
+	// expiryInterval is the interval at which expired servers are removed from the registry
+	expiryInterval time.Duration
+}
+
+// serve Handles packets received on conn until ctx is done.
+func (s *qr2Server) serve(ctx context.Context, conn *net.UDPConn) {
+	go s.expire(ctx)
+	servePackets(ctx, serviceQR2, conn, s.handlePacket)
+}
+
+// handlePacket Handles a packet received from addr. Returns the reply to send, if any.
+func (s *qr2Server) handlePacket(addr netip.AddrPort, b []byte) ([]byte, error) {
+	packet, err := qr2.ParsePacket(b)
+	if err != nil {
+		return nil, err
+	}
+
+	switch packet.Type {
+	case qr2.PacketHeartbeat:
+		hb, err2 := qr2.ParseHeartbeat(packet.Data)
+		if err2 != nil {
+			return nil, fmt.Errorf("invalid heartbeat: %w", err2)
+		}
+
+		server, ok := s.registry.Heartbeat(addr, packet.InstanceKey, hb, newQR2Challenge(addr))
+		if !ok {
+			log.Info().
+				Str("gamename", hb.Info["gamename"]).
+				Str(logKeyRemote, addr.String()).
+				Msg("Game server exited")
+			return nil, nil
+		}
+
+		log.Debug().
+			Str("gamename", server.GameName).
+			Str(logKeyRemote, addr.String()).
+			Msg("Received heartbeat")
+
+		// Servers are only listed once they answer the challenge
+		if !server.Registered {
+			return qr2.NewChallenge(packet.InstanceKey, server.Challenge), nil
+		}
+		return nil, nil
+	case qr2.PacketChallenge:
+		if _, err2 := qr2.ParseChallengeResponse(packet.Data); err2 != nil {
+			return nil, fmt.Errorf("invalid challenge response: %w", err2)
+		}
+
+		server, ok := s.registry.Register(addr, packet.InstanceKey)
+		if !ok {
+			return nil, fmt.Errorf("challenge response from unknown server")
+		}
+
+		log.Info().
+			Str("gamename", server.GameName).
+			Str("hostname", server.Info["hostname"]).
+			Str(logKeyRemote, addr.String()).
+			Msg("Game server registered")
+		return qr2.NewClientRegistered(packet.InstanceKey), nil
+	case qr2.PacketAvailable:
+		gameName, err2 := qr2.ParseAvailabilityCheck(packet.Data)
+		if err2 != nil {
+			return nil, fmt.Errorf("invalid availability check: %w", err2)
+		}
+
+		availability := s.availability[gameName]
+		log.Debug().
+			Str("gamename", gameName).
+			Stringer("availability", availability).
+			Str(logKeyRemote, addr.String()).
+			Msg("Received availability check")
+		return qr2.NewAvailabilityReply(availability), nil
+	case qr2.PacketKeepAlive:
+		if !s.registry.KeepAlive(addr, packet.InstanceKey) {
+			log.Debug().
+				Str(logKeyRemote, addr.String()).
+				Msg("Received keep-alive from unknown server")
+		}
+		return nil, nil
+	default:
+		log.Debug().
+			Int("type", int(packet.Type)).
+			Str(logKeyRemote, addr.String()).
+			Msg("Ignoring unsupported packet")
+		return nil, nil
+	}
+}
+
+// expire Periodically removes expired servers from the registry until ctx is done.
+func (s *qr2Server) expire(ctx context.Context) {
+	ticker := time.NewTicker(s.expiryInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if n := s.registry.Expire(); n > 0 {
+				log.Info().
+					Int("servers", n).
+					Msg("Removed expired game servers")
+			}
+		}
+	}
+}
+
+// newQR2Challenge Returns a challenge in the format used by GameSpy: six random characters followed by the server's
+// public IP and port in hex.
+func newQR2Challenge(addr netip.AddrPort) string {
+	ip := addr.Addr().As4()
+	return fmt.Sprintf("%s%02X%02X%02X%02X%04X", gamespy.RandString(6), ip[0], ip[1], ip[2], ip[3], addr.Port())
+}
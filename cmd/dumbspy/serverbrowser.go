@@ -0,0 +1,234 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/dogclan/dumbspy/internal/metrics"
+	"github.com/dogclan/dumbspy/internal/servers"
+	"github.com/dogclan/dumbspy/pkg/gamespy/enctype"
+	"github.com/dogclan/dumbspy/pkg/gamespy/serverbrowser"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	serviceSB = "sb"
+
+	// listRequestTimeout is the time a client has to send its list request after connecting
+	listRequestTimeout = 5 * time.Second
+
+	serverChallengeLength = 14
+)
+
+var errUnknownGame = errors.New("no secret key configured for game")
+
+// sbServer Serves server lists of game servers reporting via QR2. Lists are encrypted with enctypeX, so they are only
+// served to games whose secret key is configured. Push updates are not supported, so the connection is closed once the
+// list has been sent.
+type sbServer struct {
+	registry *servers.Registry
+	gameKeys map[string]string
+}
+
+func (s *sbServer) handleRequest(ctx context.Context, conn net.Conn) {
+	remoteAddr := conn.RemoteAddr().String()
+	defer func(conn net.Conn) {
+		err := conn.Close()
+		if err != nil {
+			log.Error().
+				Err(err).
+				Str(logKeyRemote, remoteAddr).
+				Msg("Failed to close connection")
+		}
+	}(conn)
+
+	if err := conn.SetReadDeadline(time.Now().Add(listRequestTimeout)); err != nil {
+		log.Error().
+			Err(err).
+			Str(logKeyRemote, remoteAddr).
+			Msg("Failed to set read deadline")
+		return
+	}
+
+	// Deadline must be set before, else it could replace the deadline set to interrupt the read
+	stop := interruptOnDone(ctx, conn)
+	defer stop()
+
+	reqType, body, err := serverbrowser.ReadRequest(conn)
+	if err != nil {
+		// EOF and timeout errors are not of interest => only log to debug
+		if isPeerClosed(err) {
+			log.Debug().
+				Str(logKeyRemote, remoteAddr).
+				Msg("Peer closed/reset connection while reading list request")
+		} else if errors.Is(err, os.ErrDeadlineExceeded) {
+			metrics.ReadTimeouts.With(serviceSB).Inc()
+			log.Debug().
+				Str(logKeyRemote, remoteAddr).
+				Msg("Timed out reading list request")
+		} else {
+			log.Error().
+				Err(err).
+				Str(logKeyRemote, remoteAddr).
+				Msg("Failed to read list request")
+		}
+		return
+	}
+
+	log.Debug().
+		Int("type", int(reqType)).
+		Bytes(logKeyData, body).
+		Str(logKeyRemote, remoteAddr).
+		Msg("Received request")
+
+	if reqType != serverbrowser.RequestServerList {
+		log.Debug().
+			Int("type", int(reqType)).
+			Str(logKeyRemote, remoteAddr).
+			Msg("Ignoring unsupported request")
+		return
+	}
+
+	req, err := serverbrowser.ParseListRequest(body)
+	if err != nil {
+		log.Warn().
+			Err(err).
+			Str(logKeyRemote, remoteAddr).
+			Msg("Received invalid list request")
+		return
+	}
+
+	var clientIP netip.Addr
+	if addr, ok := conn.RemoteAddr().(*net.TCPAddr); ok {
+		clientIP = addr.AddrPort().Addr().Unmap()
+	}
+
+	res, err := s.handleList(req, clientIP)
+	if errors.Is(err, errUnknownGame) {
+		log.Debug().
+			Str("gamename", req.FromGame).
+			Str(logKeyRemote, remoteAddr).
+			Msg("Not serving list to game without secret key")
+		return
+	} else if err != nil {
+		log.Warn().
+			Err(err).
+			Str("gamename", req.QueryGame).
+			Str("filter", req.Filter).
+			Str(logKeyRemote, remoteAddr).
+			Msg("Failed to handle list request")
+		return
+	}
+
+	if err = conn.SetWriteDeadline(time.Now().Add(time.Second)); err != nil {
+		log.Error().
+			Err(err).
+			Str(logKeyRemote, remoteAddr).
+			Msg("Failed to set write deadline")
+		return
+	}
+
+	if _, err = conn.Write(res); err != nil {
+		if errors.Is(err, os.ErrDeadlineExceeded) {
+			metrics.WriteTimeouts.With(serviceSB).Inc()
+		}
+		log.Error().
+			Err(err).
+			Str(logKeyRemote, remoteAddr).
+			Msg("Failed to send list response")
+		return
+	}
+
+	// Queried game name is chosen by the client, so only games with a secret key are labeled by name
+	label := metrics.OtherLabelValue
+	if _, ok := s.gameKeys[req.QueryGame]; ok {
+		label = req.QueryGame
+	}
+	metrics.ServerListsServed.With(label).Inc()
+}
+
+// handleList Returns the encrypted response to a list request sent from clientIP.
+func (s *sbServer) handleList(req *serverbrowser.ListRequest, clientIP netip.Addr) ([]byte, error) {
+	// Lists are encrypted using the key of the requesting game, which may differ from the listed game
+	gameKey, ok := s.gameKeys[req.FromGame]
+	if !ok {
+		return nil, errUnknownGame
+	}
+
+	if req.EncodingVersion != serverbrowser.EncodingVersion {
+		return nil, fmt.Errorf("encoding version %d: %w", req.EncodingVersion, enctype.ErrUnsupported)
+	}
+
+	var list []byte
+	if req.Options&serverbrowser.OptionNoServerList != 0 {
+		list = serverbrowser.AppendAddress(nil, clientIP)
+	} else {
+		filter, err := serverbrowser.ParseFilter(req.Filter)
+		if err != nil {
+			return nil, err
+		}
+
+		var entries []serverbrowser.ListServer
+		for _, server := range s.registry.Servers(req.QueryGame) {
+			if !filter.Match(server.Info) {
+				continue
+			}
+			if req.Options&serverbrowser.OptionLimitResultCount != 0 && len(entries) >= req.MaxResults {
+				break
+			}
+			entries = append(entries, listServer(server, req.Fields))
+		}
+
+		if list, err = serverbrowser.AppendList(nil, clientIP, req.Fields, entries); err != nil {
+			return nil, err
+		}
+	}
+
+	serverChallenge := make([]byte, serverChallengeLength)
+	_, _ = rand.Read(serverChallenge)
+
+	cipher, err := enctype.NewXCipher(gameKey, req.Challenge, serverChallenge)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := enctype.AppendXHeader(make([]byte, 0, 2+serverChallengeLength+len(list)), serverChallenge)
+	if err != nil {
+		return nil, err
+	}
+
+	cipher.Encrypt(list)
+	return append(res, list...), nil
+}
+
+// listServer Returns the list entry for server, containing the values of the given fields.
+func listServer(server servers.Server, fields []string) serverbrowser.ListServer {
+	entry := serverbrowser.ListServer{
+		Addr:         server.Addr,
+		NATNegotiate: server.Info["natneg"] == "1",
+		Values:       make([]string, 0, len(fields)),
+	}
+
+	// Servers report the address within their local network as localip0 (and further indexes for more interfaces)
+	if ip, err := netip.ParseAddr(server.Info["localip0"]); err == nil && ip.Is4() {
+		port, err2 := strconv.ParseUint(server.Info["localport"], 10, 16)
+		if err2 != nil {
+			port = uint64(server.Addr.Port())
+		}
+		entry.PrivateAddr = netip.AddrPortFrom(ip, uint16(port))
+	}
+
+	for _, field := range fields {
+		entry.Values = append(entry.Values, server.Info[field])
+	}
+
+	return entry
+}
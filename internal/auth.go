@@ -0,0 +1,125 @@
+package internal
+
+import (
+	"bufio"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/dogclan/dumbspy/pkg/gamespy"
+)
+
+var (
+	ErrInvalidCredentials = errors.New("invalid credentials")
+	// ErrUnsupportedLoginMode, ErrUnknownAccount and ErrWrongResponse Detail why credentials are invalid
+	ErrUnsupportedLoginMode = fmt.Errorf("%w: unsupported login mode", ErrInvalidCredentials)
+	ErrUnknownAccount       = fmt.Errorf("%w: unknown account", ErrInvalidCredentials)
+	ErrWrongResponse        = fmt.Errorf("%w: response mismatch", ErrInvalidCredentials)
+)
+
+type Authenticator interface {
+	// Authenticate Verifies the client's response to the server challenge. Returns the password hash to generate the
+	// server proof with.
+	Authenticate(login GamespyLoginRequest, serverChallenge string) (string, error)
+	// AuthenticatePlayer Verifies the response a player sent to the stats server's challenge.
+	AuthenticatePlayer(nick, serverChallenge, response string) error
+}
+
+// AcceptAllAuthenticator Accepts any login request. Since the password is unknown, the client's response is used in
+// place of the password hash.
+type AcceptAllAuthenticator struct{}
+
+func (AcceptAllAuthenticator) Authenticate(login GamespyLoginRequest, _ string) (string, error) {
+	return login.Response, nil
+}
+
+func (AcceptAllAuthenticator) AuthenticatePlayer(_, _, _ string) error {
+	return nil
+}
+
+// FileAuthenticator Accepts login requests for accounts listed in a file. Accounts are matched by nick, so unique nick
+// and user logins are supported. Auth token logins are always rejected, since tokens cannot be mapped to accounts.
+type FileAuthenticator struct {
+	accounts map[string]string // MD5 password hashes by nick
+}
+
+// NewFileAuthenticator Reads accounts from the file at path. Each line must contain a unique nick and the MD5 hash of
+// the account's password separated by a colon (e.g. "some-nick:5f4dcc3b5aa765d61d8327deb882cf99").
+// Empty lines and lines starting with # are ignored.
+func NewFileAuthenticator(path string) (*FileAuthenticator, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open accounts file: %w", err)
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	accounts := make(map[string]string)
+	scanner := bufio.NewScanner(file)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		// Split on last colon, since hash cannot contain any colons
+		i := strings.LastIndex(line, ":")
+		if i == -1 {
+			return nil, fmt.Errorf("accounts file line %d: missing separator", n)
+		}
+
+		nick, hash := line[:i], strings.ToLower(line[i+1:])
+		if nick == "" {
+			return nil, fmt.Errorf("accounts file line %d: empty unique nick", n)
+		}
+		if b, err2 := hex.DecodeString(hash); err2 != nil || len(b) != 16 {
+			return nil, fmt.Errorf("accounts file line %d: password hash is not a valid MD5 hash", n)
+		}
+
+		accounts[nick] = hash
+	}
+
+	if err = scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read accounts file: %w", err)
+	}
+
+	return &FileAuthenticator{
+		accounts: accounts,
+	}, nil
+}
+
+func (a *FileAuthenticator) Authenticate(login GamespyLoginRequest, serverChallenge string) (string, error) {
+	if login.Mode() == LoginModeAuthToken {
+		return "", fmt.Errorf("%w %s", ErrUnsupportedLoginMode, login.Mode())
+	}
+
+	hash, ok := a.accounts[login.Nick()]
+	if !ok {
+		return "", ErrUnknownAccount
+	}
+
+	expected := gamespy.GenerateProof(login.Identity(), hash, login.Challenge, serverChallenge)
+	if subtle.ConstantTimeCompare([]byte(expected), []byte(login.Response)) != 1 {
+		return "", ErrWrongResponse
+	}
+
+	return hash, nil
+}
+
+func (a *FileAuthenticator) AuthenticatePlayer(nick, serverChallenge, response string) error {
+	hash, ok := a.accounts[nick]
+	if !ok {
+		return ErrUnknownAccount
+	}
+
+	expected := gamespy.GeneratePlayerAuthResponse(hash, serverChallenge)
+	if subtle.ConstantTimeCompare([]byte(expected), []byte(response)) != 1 {
+		return ErrWrongResponse
+	}
+
+	return nil
+}
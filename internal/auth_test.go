@@ -0,0 +1,242 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAcceptAllAuthenticator_Authenticate(t *testing.T) {
+	// GIVEN
+	login := GamespyLoginRequest{
+		UniqueNick: "some-nick",
+		Response:   "1c5a1eb9e75006ec317bd6d8a2c09969",
+	}
+
+	// WHEN
+	hash, err := AcceptAllAuthenticator{}.Authenticate(login, "4Jp6A4kK02")
+
+	// THEN
+	require.NoError(t, err)
+	assert.Equal(t, login.Response, hash)
+}
+
+func TestAcceptAllAuthenticator_AuthenticatePlayer(t *testing.T) {
+	// WHEN
+	err := AcceptAllAuthenticator{}.AuthenticatePlayer("some-nick", "4Jp6A4kK02", "1c5a1eb9e75006ec317bd6d8a2c09969")
+
+	// THEN
+	require.NoError(t, err)
+}
+
+func TestNewFileAuthenticator(t *testing.T) {
+	type test struct {
+		name             string
+		content          string
+		expectedAccounts map[string]string
+		wantErrContains  string
+	}
+
+	tests := []test{
+		{
+			name:    "reads accounts",
+			content: "some-nick:131def0e93e67e3e62b39d74d6316511\nother:nick:5F4DCC3B5AA765D61D8327DEB882CF99\n",
+			expectedAccounts: map[string]string{
+				"some-nick":  "131def0e93e67e3e62b39d74d6316511",
+				"other:nick": "5f4dcc3b5aa765d61d8327deb882cf99",
+			},
+		},
+		{
+			name:    "ignores comments and empty lines",
+			content: "# some comment\n\n  some-nick:131def0e93e67e3e62b39d74d6316511  \n",
+			expectedAccounts: map[string]string{
+				"some-nick": "131def0e93e67e3e62b39d74d6316511",
+			},
+		},
+		{
+			name:            "fails for line without separator",
+			content:         "some-nick\n",
+			wantErrContains: "accounts file line 1: missing separator",
+		},
+		{
+			name:            "fails for empty unique nick",
+			content:         "# some comment\n:131def0e93e67e3e62b39d74d6316511\n",
+			wantErrContains: "accounts file line 2: empty unique nick",
+		},
+		{
+			name:            "fails for non-md5 password hash",
+			content:         "some-nick:some-password\n",
+			wantErrContains: "accounts file line 1: password hash is not a valid MD5 hash",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// GIVEN
+			path := filepath.Join(t.TempDir(), "accounts")
+			require.NoError(t, os.WriteFile(path, []byte(tt.content), 0o600))
+
+			// WHEN
+			authenticator, err := NewFileAuthenticator(path)
+
+			// THEN
+			if tt.wantErrContains != "" {
+				require.ErrorContains(t, err, tt.wantErrContains)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, tt.expectedAccounts, authenticator.accounts)
+			}
+		})
+	}
+
+	t.Run("fails for missing file", func(t *testing.T) {
+		// WHEN
+		_, err := NewFileAuthenticator(filepath.Join(t.TempDir(), "missing"))
+
+		// THEN
+		require.ErrorContains(t, err, "failed to open accounts file")
+	})
+}
+
+func TestFileAuthenticator_Authenticate(t *testing.T) {
+	type test struct {
+		name            string
+		login           GamespyLoginRequest
+		expectedHash    string
+		wantErr         error
+		wantErrContains string
+	}
+
+	tests := []test{
+		{
+			name: "accepts valid response",
+			login: GamespyLoginRequest{
+				UniqueNick: "some-nick",
+				Challenge:  "4Jp6A4kK02",
+				Response:   "4b1ec6377ec7f3c99716df13680638e2",
+			},
+			expectedHash: "131def0e93e67e3e62b39d74d6316511",
+		},
+		{
+			name: "accepts valid user response",
+			login: GamespyLoginRequest{
+				User:      "some-nick@player@example.com",
+				Challenge: "4Jp6A4kK02",
+				Response:  "531a4f45fa925fb67c20f4bd8fe828ee",
+			},
+			expectedHash: "131def0e93e67e3e62b39d74d6316511",
+		},
+		{
+			name: "rejects auth token",
+			login: GamespyLoginRequest{
+				AuthToken: "some-token",
+				Challenge: "4Jp6A4kK02",
+				Response:  "4b1ec6377ec7f3c99716df13680638e2",
+			},
+			wantErr:         ErrUnsupportedLoginMode,
+			wantErrContains: "invalid credentials: unsupported login mode authtoken",
+		},
+		{
+			name: "rejects invalid response",
+			login: GamespyLoginRequest{
+				UniqueNick: "some-nick",
+				Challenge:  "4Jp6A4kK02",
+				Response:   "1c5a1eb9e75006ec317bd6d8a2c09969",
+			},
+			wantErr:         ErrWrongResponse,
+			wantErrContains: "invalid credentials: response mismatch",
+		},
+		{
+			name: "rejects unknown account",
+			login: GamespyLoginRequest{
+				UniqueNick: "unknown-nick",
+				Challenge:  "4Jp6A4kK02",
+				Response:   "4b1ec6377ec7f3c99716df13680638e2",
+			},
+			wantErr:         ErrUnknownAccount,
+			wantErrContains: "invalid credentials: unknown account",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// GIVEN
+			authenticator := &FileAuthenticator{
+				accounts: map[string]string{
+					"some-nick": "131def0e93e67e3e62b39d74d6316511",
+				},
+			}
+
+			// WHEN
+			hash, err := authenticator.Authenticate(tt.login, "YJk5UFExKBwn0PEpOpinWHsRCDcfejyJ")
+
+			// THEN
+			if tt.wantErrContains != "" {
+				require.ErrorContains(t, err, tt.wantErrContains)
+				assert.ErrorIs(t, err, tt.wantErr)
+				assert.ErrorIs(t, err, ErrInvalidCredentials)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, tt.expectedHash, hash)
+			}
+		})
+	}
+}
+
+func TestFileAuthenticator_AuthenticatePlayer(t *testing.T) {
+	type test struct {
+		name            string
+		nick            string
+		response        string
+		wantErr         error
+		wantErrContains string
+	}
+
+	tests := []test{
+		{
+			name:     "accepts valid response",
+			nick:     "some-nick",
+			response: "e53846ff5a6127a9cdbc0f9e698da058",
+		},
+		{
+			name:            "rejects invalid response",
+			nick:            "some-nick",
+			response:        "1c5a1eb9e75006ec317bd6d8a2c09969",
+			wantErr:         ErrWrongResponse,
+			wantErrContains: "invalid credentials: response mismatch",
+		},
+		{
+			name:            "rejects unknown account",
+			nick:            "unknown-nick",
+			response:        "e53846ff5a6127a9cdbc0f9e698da058",
+			wantErr:         ErrUnknownAccount,
+			wantErrContains: "invalid credentials: unknown account",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// GIVEN
+			authenticator := &FileAuthenticator{
+				accounts: map[string]string{
+					"some-nick": "131def0e93e67e3e62b39d74d6316511",
+				},
+			}
+
+			// WHEN
+			err := authenticator.AuthenticatePlayer(tt.nick, "4Jp6A4kK02", tt.response)
+
+			// THEN
+			if tt.wantErrContains != "" {
+				require.ErrorContains(t, err, tt.wantErrContains)
+				assert.ErrorIs(t, err, tt.wantErr)
+				assert.ErrorIs(t, err, ErrInvalidCredentials)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
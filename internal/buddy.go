@@ -0,0 +1,59 @@
+package internal
+
+// Following https://github.com/openspy/openspy-core/blob/5993df54c6b289361228920fa0db7209aed4cfe5/code/SharedTasks/src/OS/GPShared.h
+const (
+	BuddyMessageText    = 1
+	BuddyMessageRequest = 2
+	BuddyMessageAuth    = 4
+	BuddyMessageStatus  = 100
+)
+
+// Status codes reported by clients via \status\
+const (
+	StatusOffline = 0
+	StatusOnline  = 1
+)
+
+type GamespyStatusRequest struct {
+	Status     int    `gamespy:"status"`
+	SessionKey int    `gamespy:"sesskey"`
+	StatString string `gamespy:"statstring"`
+	LocString  string `gamespy:"locstring"`
+}
+
+type GamespyAddBuddyRequest struct {
+	SessionKey   int    `gamespy:"sesskey"`
+	NewProfileID int    `gamespy:"newprofileid"`
+	Reason       string `gamespy:"reason"`
+}
+
+type GamespyAuthAddRequest struct {
+	SessionKey    int    `gamespy:"sesskey"`
+	FromProfileID int    `gamespy:"fromprofileid"`
+	Signature     string `gamespy:"sig"`
+}
+
+type GamespyDelBuddyRequest struct {
+	SessionKey   int `gamespy:"sesskey"`
+	DelProfileID int `gamespy:"delprofileid"`
+}
+
+type GamespyBuddyList struct {
+	Count int    `gamespy:"bdy"`
+	List  string `gamespy:"list"` // Comma-separated profile ids
+}
+
+// GamespyBuddyMessageRequest A message sent by a client to another player.
+type GamespyBuddyMessageRequest struct {
+	Type       int    `gamespy:"bm"`
+	SessionKey int    `gamespy:"sesskey"`
+	To         int    `gamespy:"t"`
+	Message    string `gamespy:"msg"`
+}
+
+type GamespyBuddyMessage struct {
+	Type    int    `gamespy:"bm"`
+	From    int    `gamespy:"f"`
+	Date    *int64 `gamespy:"date"` // Unix timestamp, only sent for text messages
+	Message string `gamespy:"msg"`
+}
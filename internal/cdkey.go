@@ -0,0 +1,103 @@
+package internal
+
+import (
+	"bufio"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// cdKeyHashLength is the length of the (hex-encoded) MD5 hash of the CD key, which prefixes the client response
+const cdKeyHashLength = 32
+
+var ErrInvalidCDKey = errors.New("invalid cd key")
+
+// GamespyCDKeyAuthRequest Sent by game servers to validate the CD key of a player (\auth\) or to revalidate it when
+// asked to (\resp\).
+type GamespyCDKeyAuthRequest struct {
+	ProductID  string `gamespy:"pid"`
+	Challenge  string `gamespy:"ch"`
+	Response   string `gamespy:"resp"`
+	IP         string `gamespy:"ip"`
+	SessionKey string `gamespy:"skey"`
+}
+
+// CDKeyHash Returns the MD5 hash of the player's CD key contained in the response. Returns an empty string if the
+// response is too short.
+func (r GamespyCDKeyAuthRequest) CDKeyHash() string {
+	if len(r.Response) < cdKeyHashLength {
+		return ""
+	}
+	return strings.ToLower(r.Response[:cdKeyHashLength])
+}
+
+// GamespyCDKeyAuthResponse Tells game servers whether a player's CD key is valid (\uok\) or not (\unok\).
+type GamespyCDKeyAuthResponse struct {
+	OK           *string `gamespy:"uok"`
+	NotOK        *string `gamespy:"unok"`
+	CDKeyHash    string  `gamespy:"cd"`
+	SessionKey   string  `gamespy:"skey"`
+	ErrorMessage *string `gamespy:"errmsg"`
+}
+
+type CDKeyValidator interface {
+	// Validate Checks whether the CD key with the given MD5 hash may be used.
+	Validate(hash string) error
+}
+
+// AcceptAllCDKeyValidator Accepts any CD key.
+type AcceptAllCDKeyValidator struct{}
+
+func (AcceptAllCDKeyValidator) Validate(string) error {
+	return nil
+}
+
+// FileCDKeyValidator Accepts CD keys whose hashes are listed in a file.
+type FileCDKeyValidator struct {
+	hashes map[string]struct{}
+}
+
+// NewFileCDKeyValidator Reads CD key hashes from the file at path. Each line must contain the MD5 hash of a CD key
+// (e.g. "5f4dcc3b5aa765d61d8327deb882cf99"). Empty lines and lines starting with # are ignored.
+func NewFileCDKeyValidator(path string) (*FileCDKeyValidator, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open cd keys file: %w", err)
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	hashes := make(map[string]struct{})
+	scanner := bufio.NewScanner(file)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		hash := strings.ToLower(line)
+		if b, err2 := hex.DecodeString(hash); err2 != nil || len(b) != 16 {
+			return nil, fmt.Errorf("cd keys file line %d: not a valid MD5 hash", n)
+		}
+
+		hashes[hash] = struct{}{}
+	}
+
+	if err = scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read cd keys file: %w", err)
+	}
+
+	return &FileCDKeyValidator{
+		hashes: hashes,
+	}, nil
+}
+
+func (v *FileCDKeyValidator) Validate(hash string) error {
+	if _, ok := v.hashes[strings.ToLower(hash)]; !ok {
+		return fmt.Errorf("%w: unknown cd key hash", ErrInvalidCDKey)
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGamespyCDKeyAuthRequest_CDKeyHash(t *testing.T) {
+	type test struct {
+		name         string
+		response     string
+		expectedHash string
+	}
+
+	tests := []test{
+		{
+			name:         "returns hash prefix of response",
+			response:     "5F4DCC3B5AA765D61D8327DEB882CF99d41d8cd98f00b204e9800998ecf8427eaBcDeFgH",
+			expectedHash: "5f4dcc3b5aa765d61d8327deb882cf99",
+		},
+		{
+			name:     "returns empty string for short response",
+			response: "5f4dcc3b",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// WHEN
+			hash := GamespyCDKeyAuthRequest{Response: tt.response}.CDKeyHash()
+
+			// THEN
+			assert.Equal(t, tt.expectedHash, hash)
+		})
+	}
+}
+
+func TestNewFileCDKeyValidator(t *testing.T) {
+	type test struct {
+		name            string
+		content         string
+		expectedHashes  map[string]struct{}
+		wantErrContains string
+	}
+
+	tests := []test{
+		{
+			name:    "reads hashes",
+			content: "# some comment\n\n  5F4DCC3B5AA765D61D8327DEB882CF99  \n131def0e93e67e3e62b39d74d6316511\n",
+			expectedHashes: map[string]struct{}{
+				"5f4dcc3b5aa765d61d8327deb882cf99": {},
+				"131def0e93e67e3e62b39d74d6316511": {},
+			},
+		},
+		{
+			name:            "fails for non-md5 hash",
+			content:         "# some comment\nsome-cd-key\n",
+			wantErrContains: "cd keys file line 2: not a valid MD5 hash",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// GIVEN
+			path := filepath.Join(t.TempDir(), "cdkeys")
+			require.NoError(t, os.WriteFile(path, []byte(tt.content), 0o600))
+
+			// WHEN
+			validator, err := NewFileCDKeyValidator(path)
+
+			// THEN
+			if tt.wantErrContains != "" {
+				require.ErrorContains(t, err, tt.wantErrContains)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, tt.expectedHashes, validator.hashes)
+			}
+		})
+	}
+}
+
+func TestFileCDKeyValidator_Validate(t *testing.T) {
+	// GIVEN
+	validator := &FileCDKeyValidator{
+		hashes: map[string]struct{}{
+			"5f4dcc3b5aa765d61d8327deb882cf99": {},
+		},
+	}
+
+	// WHEN
+	err := validator.Validate("5F4DCC3B5AA765D61D8327DEB882CF99")
+
+	// THEN
+	assert.NoError(t, err)
+
+	// WHEN
+	err = validator.Validate("131def0e93e67e3e62b39d74d6316511")
+
+	// THEN
+	assert.ErrorIs(t, err, ErrInvalidCDKey)
+}
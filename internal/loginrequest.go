@@ -1,23 +1,132 @@
 package internal
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/mail"
+	"strings"
+
 	"github.com/go-playground/validator/v10"
 )
 
+const (
+	authTokenNickPrefix = "token-"
+	// authTokenNickHashLength is the number of hash bytes used in auth token nicks, keeping them within the 20
+	// characters clients accept as unique nick
+	authTokenNickHashLength = 7
+)
+
+// LoginMode Denotes how a client identifies itself in a login request.
+type LoginMode int
+
+const (
+	// LoginModeUniqueNick Client identifies itself by unique nick (\uniquenick\)
+	LoginModeUniqueNick LoginMode = iota
+	// LoginModeUser Client identifies itself by nick and email in format nick@email (\user\)
+	LoginModeUser
+	// LoginModeAuthToken Client identifies itself by a pre-authenticated token obtained from a partner (\authtoken\)
+	LoginModeAuthToken
+)
+
+func (m LoginMode) String() string {
+	switch m {
+	case LoginModeUniqueNick:
+		return "uniquenick"
+	case LoginModeUser:
+		return "user"
+	case LoginModeAuthToken:
+		return "authtoken"
+	default:
+		return "unknown"
+	}
+}
+
+// GamespyLoginRequest Exactly one of UniqueNick, User and AuthToken must be set, which determines the login mode.
 type GamespyLoginRequest struct {
-	Login       *string `gamespy:"login" validate:"len=0,required"` // Key only, must be empty
-	Challenge   string  `gamespy:"challenge" validate:"len=32,required"`
-	UniqueNick  string  `gamespy:"uniquenick" validate:"min=1,required"`
-	Response    string  `gamespy:"response" validate:"md5,required"`
-	Port        string  `gamespy:"port" validate:"numeric,required"`
-	ProductID   string  `gamespy:"productid" validate:"numeric,required"`
-	GameName    string  `gamespy:"gamename" validate:"min=1,required"`
-	NamespaceID string  `gamespy:"namespaceid" validate:"numeric,required"`
-	SDKRevision string  `gamespy:"sdkrevision" validate:"numeric,required"`
-	ID          string  `gamespy:"id" validate:"numeric,required"`
+	Login       string `gamespy:"login,required" validate:"len=0"` // Key only, must be empty
+	Challenge   string `gamespy:"challenge" validate:"len=32,required"`
+	UniqueNick  string `gamespy:"uniquenick" validate:"required_without_all=User AuthToken,excluded_with=User AuthToken"`
+	User        string `gamespy:"user" validate:"required_without_all=UniqueNick AuthToken,excluded_with=UniqueNick AuthToken,omitempty,user"`
+	AuthToken   string `gamespy:"authtoken" validate:"required_without_all=UniqueNick User,excluded_with=UniqueNick User"`
+	Response    string `gamespy:"response" validate:"md5,required"`
+	Port        string `gamespy:"port" validate:"numeric,required"`
+	ProductID   string `gamespy:"productid" validate:"numeric,required"`
+	GameName    string `gamespy:"gamename" validate:"min=1,required"`
+	NamespaceID string `gamespy:"namespaceid" validate:"numeric,required"`
+	SDKRevision string `gamespy:"sdkrevision" validate:"numeric,required"`
+	ID          string `gamespy:"id" validate:"numeric,required"`
 }
 
 func (r GamespyLoginRequest) Validate() error {
 	validate := validator.New()
+	if err := validate.RegisterValidation("user", validateUser); err != nil {
+		return err
+	}
 	return validate.Struct(r)
 }
+
+// Mode Returns the login mode based on which identity field is set.
+func (r GamespyLoginRequest) Mode() LoginMode {
+	if r.AuthToken != "" {
+		return LoginModeAuthToken
+	}
+	if r.User != "" {
+		return LoginModeUser
+	}
+	return LoginModeUniqueNick
+}
+
+// Identity Returns the string identifying the client in the current login mode. Client response and server proof are
+// generated using the identity.
+func (r GamespyLoginRequest) Identity() string {
+	switch r.Mode() {
+	case LoginModeAuthToken:
+		return r.AuthToken
+	case LoginModeUser:
+		return r.User
+	default:
+		return r.UniqueNick
+	}
+}
+
+// Nick Returns the nick of the player logging in. For auth token logins, the token is the only identifying attribute.
+// Since tokens are secrets, a nick derived from the token is returned instead (see authTokenNick).
+func (r GamespyLoginRequest) Nick() string {
+	switch r.Mode() {
+	case LoginModeAuthToken:
+		return authTokenNick(r.AuthToken)
+	case LoginModeUser:
+		nick, _, _ := strings.Cut(r.User, "@")
+		return nick
+	default:
+		return r.UniqueNick
+	}
+}
+
+// Email Returns the email address of the player logging in, which is only known for user logins.
+func (r GamespyLoginRequest) Email() string {
+	if r.Mode() == LoginModeUser {
+		_, email, _ := strings.Cut(r.User, "@")
+		return email
+	}
+	return ""
+}
+
+// authTokenNick Returns a nick identifying clients logging in with the given auth token without revealing the token.
+// Partners usually issue a new token for every login, so only clients reusing a token keep their nick (and thus
+// player id) across logins.
+func authTokenNick(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return authTokenNickPrefix + hex.EncodeToString(sum[:authTokenNickHashLength])
+}
+
+// validateUser Checks whether a field is in the nick@email format used by user logins.
+func validateUser(fl validator.FieldLevel) bool {
+	nick, email, found := strings.Cut(fl.Field().String(), "@")
+	if !found || nick == "" {
+		return false
+	}
+	// Parsing also accepts addresses with display names, which are not valid here
+	address, err := mail.ParseAddress(email)
+	return err == nil && address.Address == email
+}
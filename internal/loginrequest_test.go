@@ -23,10 +23,62 @@ func TestGamespyLoginRequest_Validate(t *testing.T) {
 		{
 			name: "fails for non-zero string login",
 			prepareLoginRequest: func(req *GamespyLoginRequest) {
-				req.Login = ToPointer("some-string")
+				req.Login = "some-string"
 			},
 			wantErrContains: "validation for 'Login' failed on the 'len' tag",
 		},
+		{
+			name: "passes for valid user request",
+			prepareLoginRequest: func(req *GamespyLoginRequest) {
+				req.UniqueNick = ""
+				req.User = "some-nick@player@example.com"
+			},
+		},
+		{
+			name: "passes for valid auth token request",
+			prepareLoginRequest: func(req *GamespyLoginRequest) {
+				req.UniqueNick = ""
+				req.AuthToken = "GMTy13lsJmiY7L19ojyN3XTM08ll0C4EWWijwmJyq3ttiZmoDUQJ0OSnar9nQCu5MpOGvi4Z0EcC2uNaS4yCrUA"
+			},
+		},
+		{
+			name: "fails for missing identity",
+			prepareLoginRequest: func(req *GamespyLoginRequest) {
+				req.UniqueNick = ""
+			},
+			wantErrContains: "validation for 'UniqueNick' failed on the 'required_without_all' tag",
+		},
+		{
+			name: "fails for multiple identities",
+			prepareLoginRequest: func(req *GamespyLoginRequest) {
+				req.User = "some-nick@player@example.com"
+			},
+			wantErrContains: "validation for 'User' failed on the 'excluded_with' tag",
+		},
+		{
+			name: "fails for user without email",
+			prepareLoginRequest: func(req *GamespyLoginRequest) {
+				req.UniqueNick = ""
+				req.User = "some-nick"
+			},
+			wantErrContains: "validation for 'User' failed on the 'user' tag",
+		},
+		{
+			name: "fails for user with empty nick",
+			prepareLoginRequest: func(req *GamespyLoginRequest) {
+				req.UniqueNick = ""
+				req.User = "@example.com"
+			},
+			wantErrContains: "validation for 'User' failed on the 'user' tag",
+		},
+		{
+			name: "fails for user with invalid email",
+			prepareLoginRequest: func(req *GamespyLoginRequest) {
+				req.UniqueNick = ""
+				req.User = "some-nick@Name <some-nick@example.com>"
+			},
+			wantErrContains: "validation for 'User' failed on the 'user' tag",
+		},
 		{
 			name: "fails for wrong length challenge",
 			prepareLoginRequest: func(req *GamespyLoginRequest) {
@@ -82,7 +134,7 @@ func TestGamespyLoginRequest_Validate(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			// GIVEN
 			req := &GamespyLoginRequest{
-				Login:       ToPointer(""),
+				Login:       "",
 				Challenge:   "YJk5UFExKBwn0PEpOpinWHsRCDcfejyJ",
 				UniqueNick:  "some-nick",
 				Response:    "1c5a1eb9e75006ec317bd6d8a2c09969",
@@ -107,3 +159,50 @@ func TestGamespyLoginRequest_Validate(t *testing.T) {
 		})
 	}
 }
+
+func TestGamespyLoginRequest_Identity(t *testing.T) {
+	type test struct {
+		name             string
+		givenRequest     GamespyLoginRequest
+		expectedMode     LoginMode
+		expectedIdentity string
+		expectedNick     string
+	}
+
+	tests := []test{
+		{
+			name:             "unique nick login",
+			givenRequest:     GamespyLoginRequest{UniqueNick: "some-nick"},
+			expectedMode:     LoginModeUniqueNick,
+			expectedIdentity: "some-nick",
+			expectedNick:     "some-nick",
+		},
+		{
+			name:             "user login",
+			givenRequest:     GamespyLoginRequest{User: "some-nick@player@example.com"},
+			expectedMode:     LoginModeUser,
+			expectedIdentity: "some-nick@player@example.com",
+			expectedNick:     "some-nick",
+		},
+		{
+			name:             "auth token login",
+			givenRequest:     GamespyLoginRequest{AuthToken: "some-token"},
+			expectedMode:     LoginModeAuthToken,
+			expectedIdentity: "some-token",
+			// Token is a secret, so nick is derived from its hash
+			expectedNick: "token-308eda9daf26b7",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// WHEN
+			mode, identity, nick := tt.givenRequest.Mode(), tt.givenRequest.Identity(), tt.givenRequest.Nick()
+
+			// THEN
+			assert.Equal(t, tt.expectedMode, mode)
+			assert.Equal(t, tt.expectedIdentity, identity)
+			assert.Equal(t, tt.expectedNick, nick)
+		})
+	}
+}
@@ -0,0 +1,18 @@
+package internal
+
+type GamespyLoginChallenge struct {
+	LoginCode int    `gamespy:"lc"` // Always 1 for the challenge prompt
+	Challenge string `gamespy:"challenge"`
+	ID        int    `gamespy:"id"`
+}
+
+type GamespyLoginResponse struct {
+	LoginCode   int     `gamespy:"lc"` // Always 2 for the login response
+	SessionKey  int     `gamespy:"sesskey"`
+	Proof       string  `gamespy:"proof"`
+	UserID      int     `gamespy:"userid"`
+	ProfileID   int     `gamespy:"profileid"`
+	UniqueNick  *string `gamespy:"uniquenick"` // Omitted for user logins, since profile is identified by nick and email
+	LoginTicket string  `gamespy:"lt"`
+	ID          int     `gamespy:"id"`
+}
@@ -0,0 +1,73 @@
+package metrics
+
+var (
+	// DefaultRegistry Contains all dumbspy metrics
+	DefaultRegistry = NewRegistry()
+
+	ActiveConnections = DefaultRegistry.NewGaugeVec(
+		"dumbspy_active_connections",
+		"Number of currently open client connections.",
+		"service",
+	)
+	LoginsAccepted = DefaultRegistry.NewCounter(
+		"dumbspy_logins_accepted_total",
+		"Total number of accepted login requests.",
+	)
+	LoginsRejected = DefaultRegistry.NewCounterVec(
+		"dumbspy_logins_rejected_total",
+		"Total number of rejected login requests by the request field that failed validation/authentication.",
+		"field",
+	)
+	ReadTimeouts = DefaultRegistry.NewCounterVec(
+		"dumbspy_read_timeouts_total",
+		"Total number of connections closed after timing out waiting for the client.",
+		"service",
+	)
+	WriteTimeouts = DefaultRegistry.NewCounterVec(
+		"dumbspy_write_timeouts_total",
+		"Total number of timeouts writing to clients.",
+		"service",
+	)
+	PeerResets = DefaultRegistry.NewCounterVec(
+		"dumbspy_peer_resets_total",
+		"Total number of connections reset by clients.",
+		"service",
+	)
+	PlayerIDCollisions = DefaultRegistry.NewCounter(
+		"dumbspy_player_id_collisions_total",
+		"Total number of player id collisions resolved by assigning a random player id.",
+	)
+	MessagesRelayed = DefaultRegistry.NewCounter(
+		"dumbspy_messages_relayed_total",
+		"Total number of buddy messages relayed or queued for offline players.",
+	)
+	MessagesRateLimited = DefaultRegistry.NewCounter(
+		"dumbspy_messages_rate_limited_total",
+		"Total number of buddy messages dropped due to the flood limit.",
+	)
+	GameServers = DefaultRegistry.NewGaugeVec(
+		"dumbspy_game_servers",
+		"Number of game servers currently reporting to the master server.",
+		"gamename",
+	)
+	ServerListsServed = DefaultRegistry.NewCounterVec(
+		"dumbspy_server_lists_served_total",
+		"Total number of server lists sent to clients.",
+		"gamename",
+	)
+	NATNegotiations = DefaultRegistry.NewCounterVec(
+		"dumbspy_nat_negotiations_total",
+		"Total number of NAT negotiations by result (connected or timed_out).",
+		"result",
+	)
+	CDKeyChecks = DefaultRegistry.NewCounterVec(
+		"dumbspy_cdkey_checks_total",
+		"Total number of CD key checks by result (accepted or rejected).",
+		"result",
+	)
+	HandshakeDuration = DefaultRegistry.NewHistogram(
+		"dumbspy_handshake_duration_seconds",
+		"Duration from sending the login challenge to sending the login response.",
+		[]float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1},
+	)
+)
@@ -0,0 +1,313 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"maps"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+const (
+	contentType = "text/plain; version=0.0.4; charset=utf-8"
+
+	// OtherLabelValue replaces label values not contained in a LabelValues set
+	OtherLabelValue = "other"
+)
+
+// collector Writes metrics in the Prometheus text exposition format.
+type collector interface {
+	write(w io.Writer) error
+}
+
+// Registry Collects metrics to expose them via HTTP.
+type Registry struct {
+	collectors []collector
+	mu         sync.Mutex
+}
+
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+func (r *Registry) register(c collector) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.collectors = append(r.collectors, c)
+}
+
+// Write Writes all registered metrics in registration order.
+func (r *Registry) Write(w io.Writer) error {
+	r.mu.Lock()
+	collectors := slices.Clone(r.collectors)
+	r.mu.Unlock()
+
+	bw := bufio.NewWriter(w)
+	for _, c := range collectors {
+		if err := c.write(bw); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// Handler Returns a handler serving all registered metrics in the Prometheus text exposition format.
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", contentType)
+		_ = r.Write(w)
+	})
+}
+
+type Counter struct {
+	v atomic.Uint64
+}
+
+func (c *Counter) Inc() {
+	c.v.Add(1)
+}
+
+func (c *Counter) Value() uint64 {
+	return c.v.Load()
+}
+
+type Gauge struct {
+	v atomic.Int64
+}
+
+func (g *Gauge) Inc() {
+	g.v.Add(1)
+}
+
+func (g *Gauge) Dec() {
+	g.v.Add(-1)
+}
+
+func (g *Gauge) Value() int64 {
+	return g.v.Load()
+}
+
+// Histogram Counts observations in buckets with (inclusive) upper bounds.
+type Histogram struct {
+	bounds []float64
+	counts []uint64 // Non-cumulative count per bucket, last element counts observations above all bounds
+	sum    float64
+	mu     sync.Mutex
+}
+
+func (h *Histogram) Observe(v float64) {
+	i, _ := slices.BinarySearch(h.bounds, v)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.counts[i]++
+	h.sum += v
+}
+
+// vec Holds one metric per label value.
+type vec[M any] struct {
+	name, help, kind, label string
+	metrics                 map[string]*M
+	newMetric               func() *M
+	writeMetric             func(w io.Writer, name, labels string, m *M) error
+	mu                      sync.Mutex
+}
+
+// With Returns the metric for the given label value, creating it if required.
+func (v *vec[M]) With(value string) *M {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	m, ok := v.metrics[value]
+	if !ok {
+		m = v.newMetric()
+		v.metrics[value] = m
+	}
+	return m
+}
+
+// Delete Removes the metric for the given label value. Metrics previously returned by With remain usable, but are no
+// longer written.
+func (v *vec[M]) Delete(value string) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	delete(v.metrics, value)
+}
+
+func (v *vec[M]) write(w io.Writer) error {
+	v.mu.Lock()
+	values := slices.Sorted(maps.Keys(v.metrics))
+	metrics := maps.Clone(v.metrics)
+	v.mu.Unlock()
+
+	if err := writeHeader(w, v.name, v.help, v.kind); err != nil {
+		return err
+	}
+
+	for _, value := range values {
+		labels := ""
+		if v.label != "" {
+			labels = v.label + `="` + escapeLabelValue(value) + `"`
+		}
+		if err := v.writeMetric(w, v.name, labels, metrics[value]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LabelValues A set of label values known in advance. Label values derived from client input must be restricted to
+// such a set, since clients could otherwise create any number of series.
+type LabelValues map[string]struct{}
+
+func NewLabelValues(values ...string) LabelValues {
+	l := make(LabelValues, len(values))
+	for _, value := range values {
+		l[value] = struct{}{}
+	}
+	return l
+}
+
+// Get Returns value if it is part of the set, else OtherLabelValue.
+func (l LabelValues) Get(value string) string {
+	if _, ok := l[value]; ok {
+		return value
+	}
+	return OtherLabelValue
+}
+
+type CounterVec struct {
+	vec[Counter]
+}
+
+type GaugeVec struct {
+	vec[Gauge]
+}
+
+type HistogramVec struct {
+	vec[Histogram]
+}
+
+// NewCounter Registers a counter without labels.
+func (r *Registry) NewCounter(name, help string) *Counter {
+	return r.NewCounterVec(name, help, "").With("")
+}
+
+func (r *Registry) NewCounterVec(name, help, label string) *CounterVec {
+	v := &CounterVec{vec[Counter]{
+		name:      name,
+		help:      help,
+		kind:      "counter",
+		label:     label,
+		metrics:   make(map[string]*Counter),
+		newMetric: func() *Counter { return new(Counter) },
+		writeMetric: func(w io.Writer, name, labels string, m *Counter) error {
+			return writeSample(w, name, labels, strconv.FormatUint(m.Value(), 10))
+		},
+	}}
+	r.register(v)
+	return v
+}
+
+func (r *Registry) NewGaugeVec(name, help, label string) *GaugeVec {
+	v := &GaugeVec{vec[Gauge]{
+		name:      name,
+		help:      help,
+		kind:      "gauge",
+		label:     label,
+		metrics:   make(map[string]*Gauge),
+		newMetric: func() *Gauge { return new(Gauge) },
+		writeMetric: func(w io.Writer, name, labels string, m *Gauge) error {
+			return writeSample(w, name, labels, strconv.FormatInt(m.Value(), 10))
+		},
+	}}
+	r.register(v)
+	return v
+}
+
+// NewHistogram Registers a histogram without labels. Buckets must be sorted in increasing order.
+func (r *Registry) NewHistogram(name, help string, buckets []float64) *Histogram {
+	v := &HistogramVec{vec[Histogram]{
+		name:    name,
+		help:    help,
+		kind:    "histogram",
+		metrics: make(map[string]*Histogram),
+		newMetric: func() *Histogram {
+			return &Histogram{
+				bounds: buckets,
+				counts: make([]uint64, len(buckets)+1),
+			}
+		},
+		writeMetric: writeHistogram,
+	}}
+	r.register(v)
+	return v.With("")
+}
+
+func writeHistogram(w io.Writer, name, labels string, h *Histogram) error {
+	h.mu.Lock()
+	counts := slices.Clone(h.counts)
+	sum := h.sum
+	h.mu.Unlock()
+
+	var cumulative uint64
+	for i, count := range counts {
+		cumulative += count
+		le := "+Inf"
+		if i < len(h.bounds) {
+			le = formatFloat(h.bounds[i])
+		}
+
+		bucketLabels := `le="` + le + `"`
+		if labels != "" {
+			bucketLabels = labels + "," + bucketLabels
+		}
+		if err := writeSample(w, name+"_bucket", bucketLabels, strconv.FormatUint(cumulative, 10)); err != nil {
+			return err
+		}
+	}
+
+	if err := writeSample(w, name+"_sum", labels, formatFloat(sum)); err != nil {
+		return err
+	}
+	return writeSample(w, name+"_count", labels, strconv.FormatUint(cumulative, 10))
+}
+
+func writeHeader(w io.Writer, name, help, kind string) error {
+	_, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, escapeHelp(help), name, kind)
+	return err
+}
+
+func writeSample(w io.Writer, name, labels, value string) error {
+	var err error
+	if labels != "" {
+		_, err = fmt.Fprintf(w, "%s{%s} %s\n", name, labels, value)
+	} else {
+		_, err = fmt.Fprintf(w, "%s %s\n", name, value)
+	}
+	return err
+}
+
+func formatFloat(f float64) string {
+	if math.IsInf(f, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+func escapeHelp(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(s)
+}
+
+func escapeLabelValue(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`).Replace(s)
+}
@@ -0,0 +1,152 @@
+package metrics
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistry_Write(t *testing.T) {
+	t.Run("writes counter", func(t *testing.T) {
+		// GIVEN
+		registry := NewRegistry()
+		counter := registry.NewCounter("some_total", "Some help.")
+		counter.Inc()
+		counter.Inc()
+
+		// WHEN
+		buffer := new(bytes.Buffer)
+		err := registry.Write(buffer)
+
+		// THEN
+		require.NoError(t, err)
+		assert.Equal(t, "# HELP some_total Some help.\n# TYPE some_total counter\nsome_total 2\n", buffer.String())
+	})
+
+	t.Run("writes counter vec sorted by label value", func(t *testing.T) {
+		// GIVEN
+		registry := NewRegistry()
+		counter := registry.NewCounterVec("some_total", "Some help.", "field")
+		counter.With("UniqueNick").Inc()
+		counter.With("Challenge").Inc()
+		counter.With("UniqueNick").Inc()
+
+		// WHEN
+		buffer := new(bytes.Buffer)
+		err := registry.Write(buffer)
+
+		// THEN
+		require.NoError(t, err)
+		assert.Equal(t, "# HELP some_total Some help.\n# TYPE some_total counter\n"+
+			"some_total{field=\"Challenge\"} 1\n"+
+			"some_total{field=\"UniqueNick\"} 2\n", buffer.String())
+	})
+
+	t.Run("writes gauge vec", func(t *testing.T) {
+		// GIVEN
+		registry := NewRegistry()
+		gauge := registry.NewGaugeVec("some_gauge", "Some help.", "service")
+		gauge.With("gpcm").Inc()
+		gauge.With("gpcm").Inc()
+		gauge.With("gpsp").Inc()
+		gauge.With("gpsp").Dec()
+
+		// WHEN
+		buffer := new(bytes.Buffer)
+		err := registry.Write(buffer)
+
+		// THEN
+		require.NoError(t, err)
+		assert.Equal(t, "# HELP some_gauge Some help.\n# TYPE some_gauge gauge\n"+
+			"some_gauge{service=\"gpcm\"} 2\n"+
+			"some_gauge{service=\"gpsp\"} 0\n", buffer.String())
+	})
+
+	t.Run("does not write deleted gauge", func(t *testing.T) {
+		// GIVEN
+		registry := NewRegistry()
+		gauge := registry.NewGaugeVec("some_gauge", "Some help.", "gamename")
+		gauge.With("battlefield2").Inc()
+		gauge.With("spoofed").Inc()
+		gauge.With("spoofed").Dec()
+
+		// WHEN
+		gauge.Delete("spoofed")
+		buffer := new(bytes.Buffer)
+		err := registry.Write(buffer)
+
+		// THEN
+		require.NoError(t, err)
+		assert.Equal(t, "# HELP some_gauge Some help.\n# TYPE some_gauge gauge\n"+
+			"some_gauge{gamename=\"battlefield2\"} 1\n", buffer.String())
+	})
+
+	t.Run("writes histogram with cumulative buckets", func(t *testing.T) {
+		// GIVEN
+		registry := NewRegistry()
+		histogram := registry.NewHistogram("some_seconds", "Some help.", []float64{0.1, 0.5})
+		histogram.Observe(0.05)
+		histogram.Observe(0.1)
+		histogram.Observe(0.3)
+		histogram.Observe(2)
+
+		// WHEN
+		buffer := new(bytes.Buffer)
+		err := registry.Write(buffer)
+
+		// THEN
+		require.NoError(t, err)
+		assert.Equal(t, "# HELP some_seconds Some help.\n# TYPE some_seconds histogram\n"+
+			"some_seconds_bucket{le=\"0.1\"} 2\n"+
+			"some_seconds_bucket{le=\"0.5\"} 3\n"+
+			"some_seconds_bucket{le=\"+Inf\"} 4\n"+
+			"some_seconds_sum 2.45\n"+
+			"some_seconds_count 4\n", buffer.String())
+	})
+
+	t.Run("escapes help and label values", func(t *testing.T) {
+		// GIVEN
+		registry := NewRegistry()
+		counter := registry.NewCounterVec("some_total", "Some\\help\nwith newline.", "label")
+		counter.With("some \"quoted\"\\value").Inc()
+
+		// WHEN
+		buffer := new(bytes.Buffer)
+		err := registry.Write(buffer)
+
+		// THEN
+		require.NoError(t, err)
+		assert.Equal(t, "# HELP some_total Some\\\\help\\nwith newline.\n# TYPE some_total counter\n"+
+			"some_total{label=\"some \\\"quoted\\\"\\\\value\"} 1\n", buffer.String())
+	})
+}
+
+func TestLabelValues_Get(t *testing.T) {
+	// GIVEN
+	values := NewLabelValues("battlefield2", "gamespy2")
+
+	// WHEN
+	labels := []string{values.Get("battlefield2"), values.Get("spoofed"), values.Get("")}
+
+	// THEN
+	assert.Equal(t, []string{"battlefield2", OtherLabelValue, OtherLabelValue}, labels)
+}
+
+func TestRegistry_Handler(t *testing.T) {
+	// GIVEN
+	registry := NewRegistry()
+	registry.NewCounter("some_total", "Some help.").Inc()
+	recorder := httptest.NewRecorder()
+
+	// WHEN
+	registry.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	// THEN
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, contentType, recorder.Header().Get("Content-Type"))
+	assert.Equal(t, "# HELP some_total Some help.\n# TYPE some_total counter\nsome_total 1\n", recorder.Body.String())
+}
@@ -0,0 +1,163 @@
+// Package negotiations Pairs game clients taking part in NAT negotiations.
+package negotiations
+
+import (
+	"maps"
+	"net/netip"
+	"sync"
+	"time"
+
+	"github.com/dogclan/dumbspy/internal/metrics"
+	"github.com/dogclan/dumbspy/pkg/gamespy/natneg"
+)
+
+// Client A client taking part in a negotiation, identified by the negotiation's cookie and its client index.
+type Client struct {
+	Cookie      uint32
+	Index       byte
+	Version     byte // Protocol version used by the client, which replies must match
+	UseGamePort bool
+	Addrs       map[natneg.PortType]netip.AddrPort // Public addresses by port type the client sent inits from
+}
+
+// Addr Returns the public address the client's peer should connect to. Returns false if the client did not send an
+// init from the respective port yet.
+func (c Client) Addr() (netip.AddrPort, bool) {
+	portType := natneg.PortTypeNN1
+	if c.UseGamePort {
+		portType = natneg.PortTypeGP
+	}
+	addr, ok := c.Addrs[portType]
+	return addr, ok
+}
+
+type negotiation struct {
+	clients   [2]*Client
+	connected bool
+	// acknowledged denotes by client index whether a client acknowledged the connect packet
+	acknowledged [2]bool
+	started      time.Time
+}
+
+// Registry Contains ongoing negotiations. Negotiations expire once the timeout has passed since their first init.
+type Registry struct {
+	negotiations map[uint32]*negotiation
+	timeout      time.Duration
+	now          func() time.Time
+	mu           sync.Mutex
+}
+
+func NewRegistry(timeout time.Duration) *Registry {
+	return &Registry{
+		negotiations: make(map[uint32]*negotiation),
+		timeout:      timeout,
+		now:          time.Now,
+	}
+}
+
+// Init Records an init a client sent from addr. Returns copies of both clients once the addresses to connect them are
+// known. Clients are only returned once per negotiation, see Pending for clients retrying after being paired.
+func (r *Registry) Init(cookie uint32, version byte, init natneg.Init, addr netip.AddrPort) ([2]Client, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	n, ok := r.negotiations[cookie]
+	if !ok || r.expired(n) {
+		n = &negotiation{
+			started: r.now(),
+		}
+		r.negotiations[cookie] = n
+	}
+
+	c := n.clients[init.ClientIndex]
+	if c == nil {
+		c = &Client{
+			Cookie: cookie,
+			Index:  init.ClientIndex,
+			Addrs:  make(map[natneg.PortType]netip.AddrPort),
+		}
+		n.clients[init.ClientIndex] = c
+	}
+	c.Version = version
+	c.UseGamePort = init.UseGamePort
+	c.Addrs[init.PortType] = addr
+
+	if n.connected || !ready(n.clients[0]) || !ready(n.clients[1]) {
+		return [2]Client{}, false
+	}
+
+	n.connected = true
+	metrics.NATNegotiations.With("connected").Inc()
+	return [2]Client{clone(n.clients[0]), clone(n.clients[1])}, true
+}
+
+// Pending Returns copies of a paired client and its peer unless the client acknowledged the connect packet already.
+// Clients keep retrying inits and reports until they receive the connect packet, which thus needs to be resent if it
+// got lost.
+func (r *Registry) Pending(cookie uint32, index byte) (Client, Client, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	n, ok := r.negotiations[cookie]
+	if !ok || r.expired(n) || !n.connected || index > 1 || n.acknowledged[index] {
+		return Client{}, Client{}, false
+	}
+
+	return clone(n.clients[index]), clone(n.clients[1-index]), true
+}
+
+// Acknowledge Records that a client received the connect packet.
+func (r *Registry) Acknowledge(cookie uint32, index byte) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	n, ok := r.negotiations[cookie]
+	if !ok || !n.connected || index > 1 {
+		return
+	}
+	n.acknowledged[index] = true
+}
+
+// Expire Removes all expired negotiations. Returns copies of the clients left without a peer in these negotiations.
+func (r *Registry) Expire() []Client {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var clients []Client
+	for cookie, n := range r.negotiations {
+		if !r.expired(n) {
+			continue
+		}
+
+		delete(r.negotiations, cookie)
+		if n.connected {
+			continue
+		}
+
+		metrics.NATNegotiations.With("timed_out").Inc()
+		for _, c := range n.clients {
+			if c != nil {
+				clients = append(clients, clone(c))
+			}
+		}
+	}
+	return clients
+}
+
+func (r *Registry) expired(n *negotiation) bool {
+	return r.now().Sub(n.started) > r.timeout
+}
+
+func ready(c *Client) bool {
+	if c == nil {
+		return false
+	}
+	_, ok := c.Addr()
+	return ok
+}
+
+func clone(c *Client) Client {
+	cc := *c
+	cc.Addrs = maps.Clone(c.Addrs)
+	return cc
+}
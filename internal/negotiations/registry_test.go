@@ -0,0 +1,164 @@
+package negotiations
+
+import (
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dogclan/dumbspy/pkg/gamespy/natneg"
+)
+
+const cookie = 1234
+
+var (
+	addrA = netip.MustParseAddrPort("10.0.0.1:3658")
+	addrB = netip.MustParseAddrPort("10.0.0.2:3658")
+)
+
+func newTestRegistry() (*Registry, *time.Time) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	r := NewRegistry(30 * time.Second)
+	r.now = func() time.Time {
+		return now
+	}
+	return r, &now
+}
+
+func TestRegistry_Init(t *testing.T) {
+	t.Run("pairs clients sharing a cookie", func(t *testing.T) {
+		// GIVEN
+		r, _ := newTestRegistry()
+
+		// WHEN
+		_, ok := r.Init(cookie, 3, natneg.Init{PortType: natneg.PortTypeNN1, ClientIndex: 0}, addrA)
+
+		// THEN
+		assert.False(t, ok)
+
+		// WHEN
+		clients, ok := r.Init(cookie, 3, natneg.Init{PortType: natneg.PortTypeNN1, ClientIndex: 1}, addrB)
+
+		// THEN
+		require.True(t, ok)
+		peerA, ok := clients[0].Addr()
+		require.True(t, ok)
+		assert.Equal(t, addrA, peerA)
+		peerB, ok := clients[1].Addr()
+		require.True(t, ok)
+		assert.Equal(t, addrB, peerB)
+		assert.Equal(t, uint32(cookie), clients[1].Cookie)
+
+		// WHEN
+		_, ok = r.Init(cookie, 3, natneg.Init{PortType: natneg.PortTypeNN2, ClientIndex: 1}, addrB)
+
+		// THEN
+		// Clients are only paired once
+		assert.False(t, ok)
+	})
+
+	t.Run("waits for game port init if game port is used", func(t *testing.T) {
+		// GIVEN
+		r, _ := newTestRegistry()
+		gameAddr := netip.MustParseAddrPort("10.0.0.2:16567")
+		r.Init(cookie, 3, natneg.Init{PortType: natneg.PortTypeNN1, ClientIndex: 0}, addrA)
+
+		// WHEN
+		_, ok := r.Init(cookie, 3, natneg.Init{PortType: natneg.PortTypeNN1, ClientIndex: 1, UseGamePort: true}, addrB)
+
+		// THEN
+		assert.False(t, ok)
+
+		// WHEN
+		clients, ok := r.Init(cookie, 3, natneg.Init{PortType: natneg.PortTypeGP, ClientIndex: 1, UseGamePort: true}, gameAddr)
+
+		// THEN
+		require.True(t, ok)
+		peer, ok := clients[1].Addr()
+		require.True(t, ok)
+		assert.Equal(t, gameAddr, peer)
+	})
+}
+
+func TestRegistry_Pending(t *testing.T) {
+	t.Run("returns paired client until connect is acknowledged", func(t *testing.T) {
+		// GIVEN
+		r, _ := newTestRegistry()
+
+		// WHEN
+		r.Init(cookie, 3, natneg.Init{PortType: natneg.PortTypeNN1, ClientIndex: 0}, addrA)
+		_, _, ok := r.Pending(cookie, 0)
+
+		// THEN
+		// Clients are not pending before being paired
+		assert.False(t, ok)
+
+		// WHEN
+		r.Init(cookie, 3, natneg.Init{PortType: natneg.PortTypeNN1, ClientIndex: 1}, addrB)
+		client, peer, ok := r.Pending(cookie, 0)
+
+		// THEN
+		require.True(t, ok)
+		assert.Equal(t, byte(0), client.Index)
+		to, ok := client.Addr()
+		require.True(t, ok)
+		assert.Equal(t, addrA, to)
+		peerAddr, ok := peer.Addr()
+		require.True(t, ok)
+		assert.Equal(t, addrB, peerAddr)
+
+		// WHEN
+		r.Acknowledge(cookie, 0)
+		_, _, ok = r.Pending(cookie, 0)
+
+		// THEN
+		assert.False(t, ok)
+		// Peer remains pending until it acknowledges the connect itself
+		_, _, ok = r.Pending(cookie, 1)
+		assert.True(t, ok)
+	})
+
+	t.Run("does not return clients of unknown or expired negotiation", func(t *testing.T) {
+		// GIVEN
+		r, now := newTestRegistry()
+		r.Init(cookie, 3, natneg.Init{PortType: natneg.PortTypeNN1, ClientIndex: 0}, addrA)
+		r.Init(cookie, 3, natneg.Init{PortType: natneg.PortTypeNN1, ClientIndex: 1}, addrB)
+
+		// WHEN
+		_, _, unknown := r.Pending(cookie+1, 0)
+		_, _, invalid := r.Pending(cookie, 2)
+		*now = now.Add(time.Minute)
+		_, _, expired := r.Pending(cookie, 0)
+
+		// THEN
+		assert.False(t, unknown)
+		assert.False(t, invalid)
+		assert.False(t, expired)
+	})
+}
+
+func TestRegistry_Expire(t *testing.T) {
+	// GIVEN
+	r, now := newTestRegistry()
+	r.Init(cookie, 3, natneg.Init{PortType: natneg.PortTypeNN1, ClientIndex: 0}, addrA)
+	r.Init(cookie+1, 3, natneg.Init{PortType: natneg.PortTypeNN1, ClientIndex: 0}, addrA)
+	r.Init(cookie+1, 3, natneg.Init{PortType: natneg.PortTypeNN1, ClientIndex: 1}, addrB)
+
+	// WHEN
+	clients := r.Expire()
+
+	// THEN
+	assert.Empty(t, clients)
+
+	// WHEN
+	*now = now.Add(time.Minute)
+	clients = r.Expire()
+
+	// THEN
+	// Only the client without a peer is returned
+	require.Len(t, clients, 1)
+	assert.Equal(t, uint32(cookie), clients[0].Cookie)
+	assert.Empty(t, r.negotiations)
+}
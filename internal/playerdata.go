@@ -0,0 +1,155 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dogclan/dumbspy/internal/storage"
+)
+
+const (
+	playerDataBucket = "playerdata"
+
+	// PlayerDataMaxIndex is the highest index players may store data at
+	PlayerDataMaxIndex = 15
+	// PlayerDataMaxLength is the maximum length of data stored per data type and index in bytes
+	PlayerDataMaxLength = 2048
+)
+
+// ErrInvalidPlayerData is returned for data outside the bounds players may store data within
+var ErrInvalidPlayerData = errors.New("invalid player data")
+
+// PlayerData A blob of persistent data stored for a player. Key/value data is stored in gamespy packet format
+// (e.g. "\score\10\kills\2").
+type PlayerData struct {
+	Data     string `json:"data"`
+	Modified int64  `json:"modified"` // Unix timestamp
+}
+
+// PlayerDataStore Persists player data in a storage.Store. Players may store one blob per data type and index.
+type PlayerDataStore struct {
+	store storage.Store
+	now   func() time.Time
+	mu    sync.Mutex
+}
+
+func NewPlayerDataStore(store storage.Store) *PlayerDataStore {
+	return &PlayerDataStore{
+		store: store,
+		now:   time.Now,
+	}
+}
+
+// Get Returns the data stored for a player. If keys are given, the data is treated as key/value data and only the
+// given keys are returned (with empty values for keys not contained in the data).
+func (s *PlayerDataStore) Get(profileID, dataType, index int, keys []string) (PlayerData, bool, error) {
+	var data PlayerData
+	ok, err := s.store.Get(playerDataBucket, playerDataKey(profileID, dataType, index), &data)
+	if err != nil {
+		return PlayerData{}, false, fmt.Errorf("failed to get player data for profile %d: %w", profileID, err)
+	}
+
+	if ok && len(keys) > 0 {
+		pairs := parseKeyValues(data.Data)
+		selected := make([][2]string, 0, len(keys))
+		for _, key := range keys {
+			var value string
+			if i := indexKey(pairs, key); i != -1 {
+				value = pairs[i][1]
+			}
+			selected = append(selected, [2]string{key, value})
+		}
+		data.Data = formatKeyValues(selected)
+	}
+
+	return data, ok, nil
+}
+
+// Set Stores data for a player. Key/value data is merged into the stored data, updating existing keys and appending
+// new ones. Other data replaces the stored data. Returns ErrInvalidPlayerData if the data type or index is unknown or
+// the (merged) data exceeds PlayerDataMaxLength.
+func (s *PlayerDataStore) Set(profileID, dataType, index int, data string, keyValues bool) (PlayerData, error) {
+	if dataType < PlayerDataPublicReadOnly || dataType > PlayerDataPrivateReadWrite {
+		return PlayerData{}, fmt.Errorf("%w: unknown data type %d", ErrInvalidPlayerData, dataType)
+	}
+	if index < 0 || index > PlayerDataMaxIndex {
+		return PlayerData{}, fmt.Errorf("%w: index %d out of range", ErrInvalidPlayerData, index)
+	}
+	if len(data) > PlayerDataMaxLength {
+		return PlayerData{}, fmt.Errorf("%w: data too long (%d bytes)", ErrInvalidPlayerData, len(data))
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := playerDataKey(profileID, dataType, index)
+	if keyValues {
+		var existing PlayerData
+		if _, err := s.store.Get(playerDataBucket, key, &existing); err != nil {
+			return PlayerData{}, fmt.Errorf("failed to get player data for profile %d: %w", profileID, err)
+		}
+
+		pairs := parseKeyValues(existing.Data)
+		for _, pair := range parseKeyValues(data) {
+			if i := indexKey(pairs, pair[0]); i != -1 {
+				pairs[i] = pair
+			} else {
+				pairs = append(pairs, pair)
+			}
+		}
+		data = formatKeyValues(pairs)
+		if len(data) > PlayerDataMaxLength {
+			return PlayerData{}, fmt.Errorf("%w: merged data too long (%d bytes)", ErrInvalidPlayerData, len(data))
+		}
+	}
+
+	stored := PlayerData{
+		Data:     data,
+		Modified: s.now().Unix(),
+	}
+	if err := s.store.Put(playerDataBucket, key, stored); err != nil {
+		return PlayerData{}, fmt.Errorf("failed to store player data for profile %d: %w", profileID, err)
+	}
+	return stored, nil
+}
+
+func playerDataKey(profileID, dataType, index int) string {
+	return fmt.Sprintf("%d:%d:%d", profileID, dataType, index)
+}
+
+// parseKeyValues Returns the key/value pairs of data in gamespy packet format. A trailing key without a value is
+// returned with an empty value.
+func parseKeyValues(data string) [][2]string {
+	if data == "" {
+		return nil
+	}
+
+	elements := strings.Split(strings.TrimPrefix(data, `\`), `\`)
+	pairs := make([][2]string, 0, (len(elements)+1)/2)
+	for i := 0; i < len(elements); i += 2 {
+		var value string
+		if i+1 < len(elements) {
+			value = elements[i+1]
+		}
+		pairs = append(pairs, [2]string{elements[i], value})
+	}
+	return pairs
+}
+
+func formatKeyValues(pairs [][2]string) string {
+	var b strings.Builder
+	for _, pair := range pairs {
+		b.WriteString(`\` + pair[0] + `\` + pair[1])
+	}
+	return b.String()
+}
+
+func indexKey(pairs [][2]string, key string) int {
+	return slices.IndexFunc(pairs, func(pair [2]string) bool {
+		return pair[0] == key
+	})
+}
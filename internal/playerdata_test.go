@@ -0,0 +1,157 @@
+package internal
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dogclan/dumbspy/internal/storage"
+)
+
+func newTestPlayerDataStore() (*PlayerDataStore, *time.Time) {
+	now := time.Unix(1700000000, 0)
+	s := NewPlayerDataStore(storage.NewMemoryStore())
+	s.now = func() time.Time {
+		return now
+	}
+	return s, &now
+}
+
+func TestPlayerDataStore(t *testing.T) {
+	t.Run("stores data", func(t *testing.T) {
+		// GIVEN
+		s, now := newTestPlayerDataStore()
+
+		// WHEN
+		_, ok, err := s.Get(600001095, PlayerDataPrivateReadWrite, 0, nil)
+
+		// THEN
+		require.NoError(t, err)
+		assert.False(t, ok)
+
+		// WHEN
+		stored, err := s.Set(600001095, PlayerDataPrivateReadWrite, 0, "some\x00blob", false)
+
+		// THEN
+		require.NoError(t, err)
+		assert.Equal(t, PlayerData{Data: "some\x00blob", Modified: 1700000000}, stored)
+
+		// WHEN
+		*now = now.Add(time.Minute)
+		_, err = s.Set(600001095, PlayerDataPrivateReadWrite, 0, "other", false)
+		require.NoError(t, err)
+		data, ok, err := s.Get(600001095, PlayerDataPrivateReadWrite, 0, nil)
+
+		// THEN
+		require.NoError(t, err)
+		require.True(t, ok)
+		assert.Equal(t, PlayerData{Data: "other", Modified: 1700000060}, data)
+
+		// WHEN
+		_, ok, err = s.Get(600001095, PlayerDataPrivateReadWrite, 1, nil)
+
+		// THEN
+		// Indexes are stored separately
+		require.NoError(t, err)
+		assert.False(t, ok)
+	})
+
+	t.Run("merges key/value data", func(t *testing.T) {
+		// GIVEN
+		s, _ := newTestPlayerDataStore()
+		_, err := s.Set(600001095, PlayerDataPublicReadWrite, 0, `\score\10\kills\2`, true)
+		require.NoError(t, err)
+
+		// WHEN
+		stored, err := s.Set(600001095, PlayerDataPublicReadWrite, 0, `\kills\3\deaths\1`, true)
+
+		// THEN
+		require.NoError(t, err)
+		assert.Equal(t, `\score\10\kills\3\deaths\1`, stored.Data)
+
+		// WHEN
+		data, ok, err := s.Get(600001095, PlayerDataPublicReadWrite, 0, []string{"deaths", "rank", "score"})
+
+		// THEN
+		require.NoError(t, err)
+		require.True(t, ok)
+		assert.Equal(t, `\deaths\1\rank\\score\10`, data.Data)
+	})
+
+	t.Run("rejects data outside of bounds", func(t *testing.T) {
+		type test struct {
+			name            string
+			givenDataType   int
+			givenIndex      int
+			givenData       string
+			givenKeyValues  bool
+			wantErrContains string
+		}
+
+		tests := []test{
+			{
+				name:            "unknown data type",
+				givenDataType:   4,
+				givenData:       "blob",
+				wantErrContains: "unknown data type 4",
+			},
+			{
+				name:            "negative data type",
+				givenDataType:   -1,
+				givenData:       "blob",
+				wantErrContains: "unknown data type -1",
+			},
+			{
+				name:            "index out of range",
+				givenDataType:   PlayerDataPublicReadWrite,
+				givenIndex:      PlayerDataMaxIndex + 1,
+				givenData:       "blob",
+				wantErrContains: "index 16 out of range",
+			},
+			{
+				name:            "negative index",
+				givenDataType:   PlayerDataPublicReadWrite,
+				givenIndex:      -1,
+				givenData:       "blob",
+				wantErrContains: "index -1 out of range",
+			},
+			{
+				name:            "data too long",
+				givenDataType:   PlayerDataPublicReadWrite,
+				givenData:       strings.Repeat("x", PlayerDataMaxLength+1),
+				wantErrContains: "data too long (2049 bytes)",
+			},
+			{
+				name:            "merged data too long",
+				givenDataType:   PlayerDataPublicReadWrite,
+				givenData:       `\other\` + strings.Repeat("x", PlayerDataMaxLength-100),
+				givenKeyValues:  true,
+				wantErrContains: "merged data too long",
+			},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				// GIVEN
+				s, _ := newTestPlayerDataStore()
+				existing := `\some\` + strings.Repeat("x", PlayerDataMaxLength-100)
+				_, err := s.Set(600001095, PlayerDataPublicReadWrite, 0, existing, true)
+				require.NoError(t, err)
+
+				// WHEN
+				_, err = s.Set(600001095, tt.givenDataType, tt.givenIndex, tt.givenData, tt.givenKeyValues)
+
+				// THEN
+				require.ErrorIs(t, err, ErrInvalidPlayerData)
+				assert.ErrorContains(t, err, tt.wantErrContains)
+				data, ok, err := s.Get(600001095, PlayerDataPublicReadWrite, 0, nil)
+				require.NoError(t, err)
+				require.True(t, ok)
+				assert.Equal(t, existing, data.Data)
+			})
+		}
+	})
+}
@@ -0,0 +1,207 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/dogclan/dumbspy/internal/metrics"
+	"github.com/dogclan/dumbspy/internal/storage"
+	"github.com/dogclan/dumbspy/pkg/gamespy"
+)
+
+const (
+	basePlayerID = 600000000
+
+	playersBucket = "players"
+)
+
+type Player struct {
+	ID          int
+	Nick        string
+	ProductID   string
+	GameName    string
+	NamespaceID string
+	SDKRevision string
+}
+
+// PlayerRegistry Assigns player ids to players and persists them in a storage.Store,
+// so players keep their ids across restarts.
+type PlayerRegistry struct {
+	store       storage.Store
+	identifiers map[int]string // Identifiers by player id
+	ids         map[string]int // Player ids by identifier
+	mu          sync.Mutex
+}
+
+// NewPlayerRegistry Returns a registry containing all player ids previously assigned using store.
+func NewPlayerRegistry(store storage.Store) (*PlayerRegistry, error) {
+	r := &PlayerRegistry{
+		store:       store,
+		identifiers: make(map[int]string),
+		ids:         make(map[string]int),
+	}
+
+	err := store.ForEach(playersBucket, func(key string, value json.RawMessage) error {
+		playerID, err := strconv.Atoi(key)
+		if err != nil {
+			return fmt.Errorf("invalid player id %q: %w", key, err)
+		}
+
+		var identifier string
+		if err = json.Unmarshal(value, &identifier); err != nil {
+			return fmt.Errorf("invalid identifier for player id %d: %w", playerID, err)
+		}
+
+		r.identifiers[playerID] = identifier
+		r.ids[identifier] = playerID
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load player ids: %w", err)
+	}
+
+	return r, nil
+}
+
+func (r *PlayerRegistry) GetPlayerID(nick, productID, gameName, namespaceID, sdkRevision string) int {
+	identifier := joinIdentifier(nick, productID, gameName, namespaceID, sdkRevision)
+
+	playerID, assigned := r.assign(identifier)
+	if !assigned {
+		return playerID
+	}
+
+	// Persist without holding the lock, so concurrent logins do not wait for each other's disk I/O
+	if err := r.store.Put(playersBucket, strconv.Itoa(playerID), identifier); err != nil {
+		// Player id remains valid for the running duration of the dumbspy process
+		log.Error().
+			Err(err).
+			Int("playerID", playerID).
+			Str("identifier", identifier).
+			Msg("Failed to persist player id")
+	}
+
+	return playerID
+}
+
+// PeekPlayerID Returns the player id GetPlayerID would return for the given attributes without assigning (and thus
+// persisting) it. Returns false if the player id cannot be determined in advance, which is the case if the player
+// would be assigned a random player id due to an identifier collision.
+func (r *PlayerRegistry) PeekPlayerID(nick, productID, gameName, namespaceID, sdkRevision string) (int, bool) {
+	identifier := joinIdentifier(nick, productID, gameName, namespaceID, sdkRevision)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if playerID, ok := r.ids[identifier]; ok {
+		return playerID, true
+	}
+
+	playerID := basePlayerID + int(gamespy.ComputeCRC16(identifier))
+	if _, ok := r.identifiers[playerID]; ok {
+		return 0, false
+	}
+
+	return playerID, true
+}
+
+// assign Returns the player id assigned to identifier, assigning a new one if required. Returns true if the player id
+// was newly assigned and thus still needs to be persisted.
+func (r *PlayerRegistry) assign(identifier string) (int, bool) {
+	// Lock and unlock players maps to prevent concurrent access
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if playerID, ok := r.ids[identifier]; ok {
+		return playerID, false
+	}
+
+	playerID := basePlayerID + int(gamespy.ComputeCRC16(identifier))
+	existingIdentifier, ok := r.identifiers[playerID]
+	if ok {
+		log.Warn().
+			Str("existingIdentifier", existingIdentifier).
+			Str("identifier", identifier).
+			Msg("Player identifier mismatch, assigning random player id")
+		metrics.PlayerIDCollisions.Inc()
+
+		// The random player id is persisted like any other, so a player whose identifier is colliding
+		// will receive the same (random) player id each time they log in.
+		for ok {
+			playerID = basePlayerID - rand.Intn(10000)
+			_, ok = r.identifiers[playerID]
+		}
+	}
+
+	r.identifiers[playerID] = identifier
+	r.ids[identifier] = playerID
+
+	return playerID, true
+}
+
+// Lookup Returns the player the given player id was assigned to.
+func (r *PlayerRegistry) Lookup(playerID int) (Player, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	identifier, ok := r.identifiers[playerID]
+	if !ok {
+		return Player{}, false
+	}
+
+	return parseIdentifier(playerID, identifier), true
+}
+
+// Find Returns all players with the given nick, ordered by player id. Players are only filtered by game name and
+// namespace id if the respective argument is not empty.
+func (r *PlayerRegistry) Find(nick, gameName, namespaceID string) []Player {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	players := make([]Player, 0)
+	for playerID, identifier := range r.identifiers {
+		player := parseIdentifier(playerID, identifier)
+		if player.Nick != nick ||
+			gameName != "" && player.GameName != gameName ||
+			namespaceID != "" && player.NamespaceID != namespaceID {
+			continue
+		}
+		players = append(players, player)
+	}
+
+	slices.SortFunc(players, func(a, b Player) int {
+		return a.ID - b.ID
+	})
+
+	return players
+}
+
+// joinIdentifier Joins all unique/constant attributes in a login request to get a unique identifier.
+func joinIdentifier(nick, productID, gameName, namespaceID, sdkRevision string) string {
+	return strings.Join([]string{nick, productID, gameName, namespaceID, sdkRevision}, ":")
+}
+
+func parseIdentifier(playerID int, identifier string) Player {
+	// Nick may contain the separator, so split remaining attributes off from the end
+	elements := strings.Split(identifier, ":")
+	if len(elements) < 5 {
+		return Player{ID: playerID, Nick: identifier}
+	}
+
+	n := len(elements)
+	return Player{
+		ID:          playerID,
+		Nick:        strings.Join(elements[:n-4], ":"),
+		ProductID:   elements[n-4],
+		GameName:    elements[n-3],
+		NamespaceID: elements[n-2],
+		SDKRevision: elements[n-1],
+	}
+}
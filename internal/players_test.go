@@ -0,0 +1,358 @@
+package internal
+
+import (
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dogclan/dumbspy/internal/storage"
+)
+
+func TestNewPlayerRegistry(t *testing.T) {
+	t.Run("loads persisted player ids", func(t *testing.T) {
+		// GIVEN
+		store := storage.NewMemoryStore()
+		require.NoError(t, store.Put(playersBucket, "600001095", "some-identifier"))
+
+		// WHEN
+		registry, err := NewPlayerRegistry(store)
+
+		// THEN
+		require.NoError(t, err)
+		assert.Equal(t, map[int]string{600001095: "some-identifier"}, registry.identifiers)
+		assert.Equal(t, map[string]int{"some-identifier": 600001095}, registry.ids)
+	})
+
+	t.Run("fails for non-numeric player id", func(t *testing.T) {
+		// GIVEN
+		store := storage.NewMemoryStore()
+		require.NoError(t, store.Put(playersBucket, "not-numeric", "some-identifier"))
+
+		// WHEN
+		_, err := NewPlayerRegistry(store)
+
+		// THEN
+		require.ErrorContains(t, err, "failed to load player ids: invalid player id \"not-numeric\"")
+	})
+
+	t.Run("fails for non-string identifier", func(t *testing.T) {
+		// GIVEN
+		store := storage.NewMemoryStore()
+		require.NoError(t, store.Put(playersBucket, "600001095", 1))
+
+		// WHEN
+		_, err := NewPlayerRegistry(store)
+
+		// THEN
+		require.ErrorContains(t, err, "failed to load player ids: invalid identifier for player id 600001095")
+	})
+}
+
+func TestPlayerRegistry_GetPlayerID(t *testing.T) {
+	type test struct {
+		name             string
+		nick             string
+		productID        string
+		gameName         string
+		namespaceID      string
+		sdkRevision      string
+		players          map[string]string
+		expectedPlayerID int
+		wantRandom       bool
+	}
+
+	tests := []test{
+		{
+			name:             "assigns player id to new player",
+			nick:             "some-nick",
+			productID:        "some-productID",
+			gameName:         "some-gameName",
+			namespaceID:      "some-namespaceID",
+			sdkRevision:      "some-sdkRevision",
+			players:          map[string]string{},
+			expectedPlayerID: 600001095,
+		},
+		{
+			name:        "re-assigns player id to returning player",
+			nick:        "some-nick",
+			productID:   "some-productID",
+			gameName:    "some-gameName",
+			namespaceID: "some-namespaceID",
+			sdkRevision: "some-sdkRevision",
+			players: map[string]string{
+				"600001095": strings.Join([]string{
+					"some-nick",
+					"some-productID",
+					"some-gameName",
+					"some-namespaceID",
+					"some-sdkRevision",
+				}, ":"),
+			},
+			expectedPlayerID: 600001095,
+		},
+		{
+			name:        "assigns random player id on identifier collision",
+			nick:        "some-nick",
+			productID:   "some-productID",
+			gameName:    "some-gameName",
+			namespaceID: "some-namespaceID",
+			sdkRevision: "some-sdkRevision",
+			players: map[string]string{
+				"600001095": "some-other-identifier",
+			},
+			expectedPlayerID: 600001095,
+			wantRandom:       true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// GIVEN
+			store := storage.NewMemoryStore()
+			for playerID, identifier := range tt.players {
+				require.NoError(t, store.Put(playersBucket, playerID, identifier))
+			}
+			registry, err := NewPlayerRegistry(store)
+			require.NoError(t, err)
+
+			// WHEN
+			firstIterationID := registry.GetPlayerID(tt.nick, tt.productID, tt.gameName, tt.namespaceID, tt.sdkRevision)
+
+			// THEN
+			if tt.wantRandom {
+				assert.NotEqual(t, tt.expectedPlayerID, firstIterationID)
+			} else {
+				assert.Equal(t, tt.expectedPlayerID, firstIterationID)
+			}
+
+			// WHEN we run GetPlayerID again using the same inputs
+			secondIterationID := registry.GetPlayerID(tt.nick, tt.productID, tt.gameName, tt.namespaceID, tt.sdkRevision)
+
+			// THEN we should receive the same player id again (random or not)
+			assert.Equal(t, firstIterationID, secondIterationID)
+		})
+	}
+
+	t.Run("re-assigns random player id after restart", func(t *testing.T) {
+		// GIVEN
+		path := filepath.Join(t.TempDir(), "store.json")
+		store, err := storage.OpenFileStore(path)
+		require.NoError(t, err)
+		require.NoError(t, store.Put(playersBucket, "600001095", "some-other-identifier"))
+		registry, err := NewPlayerRegistry(store)
+		require.NoError(t, err)
+		firstRunID := registry.GetPlayerID("some-nick", "some-productID", "some-gameName", "some-namespaceID", "some-sdkRevision")
+
+		// WHEN
+		store, err = storage.OpenFileStore(path)
+		require.NoError(t, err)
+		registry, err = NewPlayerRegistry(store)
+		require.NoError(t, err)
+		secondRunID := registry.GetPlayerID("some-nick", "some-productID", "some-gameName", "some-namespaceID", "some-sdkRevision")
+
+		// THEN
+		assert.NotEqual(t, 600001095, firstRunID)
+		assert.Equal(t, firstRunID, secondRunID)
+	})
+
+	t.Run("does not block concurrent logins while persisting", func(t *testing.T) {
+		// GIVEN
+		store := &blockingStore{
+			Store:   storage.NewMemoryStore(),
+			key:     "600001095",
+			release: make(chan struct{}),
+		}
+		registry, err := NewPlayerRegistry(store)
+		require.NoError(t, err)
+		done := make(chan int)
+		go func() {
+			done <- registry.GetPlayerID("some-nick", "some-productID", "some-gameName", "some-namespaceID", "some-sdkRevision")
+		}()
+		require.Eventually(t, func() bool {
+			_, ok := registry.Lookup(600001095)
+			return ok
+		}, time.Second, time.Millisecond)
+
+		// WHEN
+		otherID := registry.GetPlayerID("other-nick", "some-productID", "some-gameName", "some-namespaceID", "some-sdkRevision")
+
+		// THEN
+		assert.NotEqual(t, 600001095, otherID)
+		ok, err := store.Get(playersBucket, strconv.Itoa(otherID), new(string))
+		require.NoError(t, err)
+		assert.True(t, ok)
+
+		// WHEN
+		close(store.release)
+
+		// THEN
+		assert.Equal(t, 600001095, <-done)
+	})
+}
+
+// blockingStore Blocks writes of a single key until release is closed.
+type blockingStore struct {
+	storage.Store
+	key     string
+	release chan struct{}
+}
+
+func (s *blockingStore) Put(bucket, key string, v any) error {
+	if key == s.key {
+		<-s.release
+	}
+	return s.Store.Put(bucket, key, v)
+}
+
+func TestPlayerRegistry_PeekPlayerID(t *testing.T) {
+	type test struct {
+		name             string
+		players          map[string]string
+		expectedPlayerID int
+		expectedOK       bool
+	}
+
+	tests := []test{
+		{
+			name:             "returns player id new player would be assigned",
+			players:          map[string]string{},
+			expectedPlayerID: 600001095,
+			expectedOK:       true,
+		},
+		{
+			name: "returns player id assigned to returning player",
+			players: map[string]string{
+				"599990000": "some-nick:some-productID:some-gameName:some-namespaceID:some-sdkRevision",
+			},
+			expectedPlayerID: 599990000,
+			expectedOK:       true,
+		},
+		{
+			name: "returns false on identifier collision",
+			players: map[string]string{
+				"600001095": "some-other-identifier",
+			},
+			expectedOK: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// GIVEN
+			store := storage.NewMemoryStore()
+			for playerID, identifier := range tt.players {
+				require.NoError(t, store.Put(playersBucket, playerID, identifier))
+			}
+			registry, err := NewPlayerRegistry(store)
+			require.NoError(t, err)
+
+			// WHEN
+			playerID, ok := registry.PeekPlayerID("some-nick", "some-productID", "some-gameName", "some-namespaceID", "some-sdkRevision")
+
+			// THEN
+			assert.Equal(t, tt.expectedOK, ok)
+			assert.Equal(t, tt.expectedPlayerID, playerID)
+			// Player id must not be assigned
+			assert.Len(t, registry.identifiers, len(tt.players))
+		})
+	}
+}
+
+func TestPlayerRegistry_Lookup(t *testing.T) {
+	t.Run("returns player for assigned player id", func(t *testing.T) {
+		// GIVEN
+		registry, err := NewPlayerRegistry(storage.NewMemoryStore())
+		require.NoError(t, err)
+		playerID := registry.GetPlayerID("some:nick", "10493", "battlefield2", "12", "3")
+
+		// WHEN
+		player, ok := registry.Lookup(playerID)
+
+		// THEN
+		assert.True(t, ok)
+		assert.Equal(t, Player{
+			ID:          playerID,
+			Nick:        "some:nick",
+			ProductID:   "10493",
+			GameName:    "battlefield2",
+			NamespaceID: "12",
+			SDKRevision: "3",
+		}, player)
+	})
+
+	t.Run("returns false for unknown player id", func(t *testing.T) {
+		// GIVEN
+		registry, err := NewPlayerRegistry(storage.NewMemoryStore())
+		require.NoError(t, err)
+
+		// WHEN
+		_, ok := registry.Lookup(600001095)
+
+		// THEN
+		assert.False(t, ok)
+	})
+}
+
+func TestPlayerRegistry_Find(t *testing.T) {
+	type test struct {
+		name        string
+		nick        string
+		gameName    string
+		namespaceID string
+		expectedIDs []int
+	}
+
+	tests := []test{
+		{
+			name:        "finds players by nick",
+			nick:        "some-nick",
+			expectedIDs: []int{600000001, 600000002, 600000003},
+		},
+		{
+			name:        "finds players by nick and game name",
+			nick:        "some-nick",
+			gameName:    "battlefield2",
+			expectedIDs: []int{600000001, 600000002},
+		},
+		{
+			name:        "finds players by nick, game name and namespace id",
+			nick:        "some-nick",
+			gameName:    "battlefield2",
+			namespaceID: "12",
+			expectedIDs: []int{600000001},
+		},
+		{
+			name:        "returns empty slice for unknown nick",
+			nick:        "unknown-nick",
+			expectedIDs: []int{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// GIVEN
+			store := storage.NewMemoryStore()
+			require.NoError(t, store.Put(playersBucket, "600000003", "some-nick:10493:stella:12:3"))
+			require.NoError(t, store.Put(playersBucket, "600000002", "some-nick:10493:battlefield2:0:3"))
+			require.NoError(t, store.Put(playersBucket, "600000001", "some-nick:10493:battlefield2:12:3"))
+			require.NoError(t, store.Put(playersBucket, "600000004", "other-nick:10493:battlefield2:12:3"))
+			registry, err := NewPlayerRegistry(store)
+			require.NoError(t, err)
+
+			// WHEN
+			players := registry.Find(tt.nick, tt.gameName, tt.namespaceID)
+
+			// THEN
+			ids := make([]int, 0, len(players))
+			for _, player := range players {
+				ids = append(ids, player.ID)
+			}
+			assert.Equal(t, tt.expectedIDs, ids)
+		})
+	}
+}
@@ -0,0 +1,359 @@
+// Package presence Tracks the status of logged in players and their buddy lists, pushing status changes to buddies.
+// State is kept in memory only, so buddy lists are lost on restart.
+package presence
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"maps"
+	"net/netip"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/dogclan/dumbspy/internal"
+	"github.com/dogclan/dumbspy/pkg/gamespy"
+)
+
+const (
+	authText = "I have authorized your request to add me to your list"
+
+	// maxQueuedMessages is the number of messages kept for an offline player, older messages are dropped
+	maxQueuedMessages = 100
+)
+
+var (
+	ErrSelf      = errors.New("cannot add self as buddy")
+	ErrNoRequest = errors.New("no pending buddy request")
+)
+
+type Status struct {
+	Code       int
+	StatString string
+	LocString  string
+}
+
+// Hub Connects the sessions of logged in players. A player's buddy list contains the players whose status it receives.
+// Players are added to a buddy list once they authorize the respective buddy request.
+type Hub struct {
+	sessions map[int]*Session         // Online sessions by profile id
+	buddies  map[int]map[int]struct{} // Buddy profile ids by profile id
+	requests map[int]map[int]string   // Pending buddy request reasons by profile id of requested and requesting player
+	queued   map[int][]message        // Messages to offline players by recipient profile id
+	now      func() time.Time
+	mu       sync.Mutex
+}
+
+type message struct {
+	fromID int
+	date   time.Time
+	text   string
+}
+
+func NewHub() *Hub {
+	return &Hub{
+		sessions: make(map[int]*Session),
+		buddies:  make(map[int]map[int]struct{}),
+		requests: make(map[int]map[int]string),
+		queued:   make(map[int][]message),
+		now:      time.Now,
+	}
+}
+
+// Session A player's connection to the hub. Packets are passed to send, which must be safe for concurrent use.
+type Session struct {
+	hub       *Hub
+	profileID int
+	addr      netip.AddrPort // Public IP and game port reported to buddies
+	status    Status
+	send      func(packet *gamespy.Packet) error
+}
+
+// delivery A packet to be sent to a session once the hub is unlocked, so slow clients cannot block the hub.
+type delivery struct {
+	session *Session
+	packet  *gamespy.Packet
+}
+
+// Connect Registers an online session for the player, replacing any previous session of the same player. The player
+// is sent its buddy list, the status of online buddies, any pending buddy requests and messages queued while the player
+// was offline. Players with the player on their buddy list are notified that the player is online.
+func (h *Hub) Connect(profileID int, addr netip.AddrPort, send func(packet *gamespy.Packet) error) *Session {
+	s := &Session{
+		hub:       h,
+		profileID: profileID,
+		addr:      addr,
+		status:    Status{Code: internal.StatusOnline},
+		send:      send,
+	}
+
+	h.mu.Lock()
+	h.sessions[profileID] = s
+
+	buddies := slices.Sorted(maps.Keys(h.buddies[profileID]))
+	deliveries := []delivery{{session: s, packet: buddyList(buddies)}}
+	for _, buddyID := range buddies {
+		if buddy, ok := h.sessions[buddyID]; ok {
+			deliveries = append(deliveries, delivery{session: s, packet: buddy.statusMessage()})
+		}
+	}
+	for _, fromID := range slices.Sorted(maps.Keys(h.requests[profileID])) {
+		deliveries = append(deliveries, delivery{
+			session: s,
+			packet:  requestMessage(fromID, profileID, h.requests[profileID][fromID]),
+		})
+	}
+	for _, m := range h.queued[profileID] {
+		deliveries = append(deliveries, delivery{session: s, packet: textMessage(m)})
+	}
+	delete(h.queued, profileID)
+	deliveries = append(deliveries, h.statusDeliveries(s)...)
+	h.mu.Unlock()
+
+	deliver(deliveries)
+	return s
+}
+
+// Close Removes the session from the hub and notifies players with the player on their buddy list that the player
+// went offline. Does nothing if the session has been replaced by a newer session of the same player.
+func (s *Session) Close() {
+	h := s.hub
+	h.mu.Lock()
+	if h.sessions[s.profileID] != s {
+		h.mu.Unlock()
+		return
+	}
+
+	delete(h.sessions, s.profileID)
+	s.status = Status{Code: internal.StatusOffline}
+	deliveries := h.statusDeliveries(s)
+	h.mu.Unlock()
+
+	deliver(deliveries)
+}
+
+// SetStatus Updates the player's status and pushes it to players with the player on their buddy list.
+func (s *Session) SetStatus(status Status) {
+	h := s.hub
+	h.mu.Lock()
+	s.status = status
+	var deliveries []delivery
+	if h.sessions[s.profileID] == s {
+		deliveries = h.statusDeliveries(s)
+	}
+	h.mu.Unlock()
+
+	deliver(deliveries)
+}
+
+// AddBuddy Requests to add a player to the session player's buddy list. The request is delivered immediately if the
+// player is online or else once the player connects.
+func (s *Session) AddBuddy(profileID int, reason string) error {
+	if profileID == s.profileID {
+		return ErrSelf
+	}
+
+	h := s.hub
+	h.mu.Lock()
+	if _, ok := h.buddies[s.profileID][profileID]; ok {
+		// Already a buddy, nothing to request
+		h.mu.Unlock()
+		return nil
+	}
+
+	requests, ok := h.requests[profileID]
+	if !ok {
+		requests = make(map[int]string)
+		h.requests[profileID] = requests
+	}
+	requests[s.profileID] = reason
+
+	var deliveries []delivery
+	if target, ok2 := h.sessions[profileID]; ok2 {
+		deliveries = append(deliveries, delivery{
+			session: target,
+			packet:  requestMessage(s.profileID, profileID, reason),
+		})
+	}
+	h.mu.Unlock()
+
+	deliver(deliveries)
+	return nil
+}
+
+// AuthAdd Authorizes a pending buddy request, adding the session player to the requesting player's buddy list. If
+// online, the requesting player is notified and sent the session player's status.
+func (s *Session) AuthAdd(profileID int) error {
+	h := s.hub
+	h.mu.Lock()
+	if _, ok := h.requests[s.profileID][profileID]; !ok {
+		h.mu.Unlock()
+		return fmt.Errorf("%w from profile %d", ErrNoRequest, profileID)
+	}
+	delete(h.requests[s.profileID], profileID)
+
+	buddies, ok := h.buddies[profileID]
+	if !ok {
+		buddies = make(map[int]struct{})
+		h.buddies[profileID] = buddies
+	}
+	buddies[s.profileID] = struct{}{}
+
+	var deliveries []delivery
+	if requester, ok2 := h.sessions[profileID]; ok2 {
+		deliveries = append(deliveries,
+			delivery{session: requester, packet: authMessage(s.profileID)},
+			delivery{session: requester, packet: s.statusMessage()},
+		)
+	}
+	h.mu.Unlock()
+
+	deliver(deliveries)
+	return nil
+}
+
+// SendMessage Relays a text message to a player. If the player is offline, the message is queued and delivered once the
+// player connects.
+func (s *Session) SendMessage(profileID int, text string) {
+	h := s.hub
+	m := message{
+		fromID: s.profileID,
+		date:   h.now(),
+		text:   text,
+	}
+
+	h.mu.Lock()
+	var deliveries []delivery
+	if target, ok := h.sessions[profileID]; ok {
+		deliveries = append(deliveries, delivery{session: target, packet: textMessage(m)})
+	} else {
+		queue := append(h.queued[profileID], m)
+		if len(queue) > maxQueuedMessages {
+			queue = queue[len(queue)-maxQueuedMessages:]
+		}
+		h.queued[profileID] = queue
+	}
+	h.mu.Unlock()
+
+	deliver(deliveries)
+}
+
+// DelBuddy Removes a player from the session player's buddy list.
+func (s *Session) DelBuddy(profileID int) {
+	h := s.hub
+	h.mu.Lock()
+	delete(h.buddies[s.profileID], profileID)
+	h.mu.Unlock()
+}
+
+// Others Returns the profile ids of all players with the given player on their buddy list, ordered by profile id.
+func (h *Hub) Others(profileID int) []int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	others := make([]int, 0)
+	for otherID, buddies := range h.buddies {
+		if _, ok := buddies[profileID]; ok {
+			others = append(others, otherID)
+		}
+	}
+	slices.Sort(others)
+	return others
+}
+
+// statusDeliveries Returns deliveries of the session's status to all online players with the session player on their
+// buddy list. Hub must be locked.
+func (h *Hub) statusDeliveries(s *Session) []delivery {
+	var deliveries []delivery
+	packet := s.statusMessage()
+	for _, profileID := range slices.Sorted(maps.Keys(h.sessions)) {
+		if _, ok := h.buddies[profileID][s.profileID]; ok {
+			deliveries = append(deliveries, delivery{session: h.sessions[profileID], packet: packet})
+		}
+	}
+	return deliveries
+}
+
+func (s *Session) statusMessage() *gamespy.Packet {
+	// Clients read the IP as a 32-bit integer in network byte order, which little-endian clients print reversed
+	var ip uint32
+	if s.addr.Addr().Is4() {
+		b := s.addr.Addr().As4()
+		ip = binary.LittleEndian.Uint32(b[:])
+	}
+
+	msg := fmt.Sprintf(
+		"|s|%d|ss|%s|ls|%s|ip|%d|p|%d|qm|0",
+		s.status.Code,
+		s.status.StatString,
+		s.status.LocString,
+		ip,
+		s.addr.Port(),
+	)
+	return buddyMessage(internal.BuddyMessageStatus, s.profileID, msg)
+}
+
+func buddyList(buddies []int) *gamespy.Packet {
+	ids := make([]string, 0, len(buddies))
+	for _, buddyID := range buddies {
+		ids = append(ids, strconv.Itoa(buddyID))
+	}
+
+	// Marshalling a struct without pointers cannot fail
+	packet, _ := gamespy.Marshal(internal.GamespyBuddyList{
+		Count: len(buddies),
+		List:  strings.Join(ids, ","),
+	})
+	return packet
+}
+
+func requestMessage(fromID, toID int, reason string) *gamespy.Packet {
+	return buddyMessage(internal.BuddyMessageRequest, fromID, reason+signature(fromID, toID))
+}
+
+func authMessage(fromID int) *gamespy.Packet {
+	return buddyMessage(internal.BuddyMessageAuth, fromID, authText+signature(fromID, 0))
+}
+
+func textMessage(m message) *gamespy.Packet {
+	date := m.date.Unix()
+	packet, _ := gamespy.Marshal(internal.GamespyBuddyMessage{
+		Type:    internal.BuddyMessageText,
+		From:    m.fromID,
+		Date:    &date,
+		Message: m.text,
+	})
+	return packet
+}
+
+func buddyMessage(messageType, fromID int, msg string) *gamespy.Packet {
+	packet, _ := gamespy.Marshal(internal.GamespyBuddyMessage{
+		Type:    messageType,
+		From:    fromID,
+		Message: msg,
+	})
+	return packet
+}
+
+// signature Returns the signature suffix clients expect on buddy requests/authorizations. Clients only pass the
+// signature back to the server, so it does not need to be verifiable.
+func signature(fromID, toID int) string {
+	return "|signed|" + gamespy.ComputeMD5(fmt.Sprintf("%d:%d", fromID, toID))
+}
+
+func deliver(deliveries []delivery) {
+	for _, d := range deliveries {
+		if err := d.session.send(d.packet); err != nil {
+			// Failing sessions are closed by their connection handler, nothing to do here
+			log.Debug().
+				Err(err).
+				Int("profileID", d.session.profileID).
+				Msg("Failed to deliver presence packet")
+		}
+	}
+}
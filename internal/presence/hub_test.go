@@ -0,0 +1,226 @@
+package presence
+
+import (
+	"fmt"
+	"net/netip"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dogclan/dumbspy/pkg/gamespy"
+)
+
+// recorder Records packets sent to a session.
+type recorder struct {
+	packets []string
+	mu      sync.Mutex
+}
+
+func (r *recorder) send(packet *gamespy.Packet) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.packets = append(r.packets, packet.String())
+	return nil
+}
+
+// take Returns and clears the recorded packets.
+func (r *recorder) take() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	packets := r.packets
+	r.packets = nil
+	return packets
+}
+
+func connect(h *Hub, profileID int) (*Session, *recorder) {
+	r := new(recorder)
+	s := h.Connect(profileID, netip.MustParseAddrPort("10.0.0.1:3658"), r.send)
+	return s, r
+}
+
+func TestHub_Connect(t *testing.T) {
+	// GIVEN
+	h := NewHub()
+
+	// WHEN
+	_, r := connect(h, 1)
+
+	// THEN
+	assert.Equal(t, []string{`\bdy\0\list\\final\`}, r.take())
+}
+
+func TestHub_BuddyLifecycle(t *testing.T) {
+	// GIVEN
+	h := NewHub()
+	a, ra := connect(h, 1)
+	b, rb := connect(h, 2)
+	ra.take()
+	rb.take()
+
+	// WHEN
+	err := a.AddBuddy(2, "please")
+
+	// THEN
+	require.NoError(t, err)
+	assert.Equal(t, []string{`\bm\2\f\1\msg\please|signed|` + gamespy.ComputeMD5("1:2") + `\final\`}, rb.take())
+
+	// WHEN
+	err = b.AuthAdd(1)
+
+	// THEN
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		`\bm\4\f\2\msg\I have authorized your request to add me to your list|signed|` + gamespy.ComputeMD5("2:0") + `\final\`,
+		`\bm\100\f\2\msg\|s|1|ss||ls||ip|16777226|p|3658|qm|0\final\`,
+	}, ra.take())
+
+	// WHEN
+	b.SetStatus(Status{Code: 2, StatString: "Playing", LocString: "gamespy://10.0.0.2:16567"})
+
+	// THEN
+	assert.Equal(t, []string{`\bm\100\f\2\msg\|s|2|ss|Playing|ls|gamespy://10.0.0.2:16567|ip|16777226|p|3658|qm|0\final\`}, ra.take())
+	// Buddy relationship is one-way, so b does not receive a's status
+	a.SetStatus(Status{Code: 1})
+	assert.Empty(t, rb.take())
+
+	// WHEN
+	b.Close()
+
+	// THEN
+	assert.Equal(t, []string{`\bm\100\f\2\msg\|s|0|ss||ls||ip|16777226|p|3658|qm|0\final\`}, ra.take())
+
+	// WHEN
+	_, rb = connect(h, 2)
+	a.Close()
+	_, ra = connect(h, 1)
+
+	// THEN
+	assert.Equal(t, []string{`\bdy\0\list\\final\`}, rb.take())
+	assert.Equal(t, []string{
+		`\bdy\1\list\2\final\`,
+		`\bm\100\f\2\msg\|s|1|ss||ls||ip|16777226|p|3658|qm|0\final\`,
+	}, ra.take())
+
+	// WHEN
+	h.sessions[1].DelBuddy(2)
+	h.sessions[2].SetStatus(Status{Code: 1})
+
+	// THEN
+	assert.Empty(t, ra.take())
+}
+
+func TestHub_Others(t *testing.T) {
+	// GIVEN
+	h := NewHub()
+	a, _ := connect(h, 1)
+	b, _ := connect(h, 2)
+	c, _ := connect(h, 3)
+	require.NoError(t, c.AddBuddy(2, "please"))
+	require.NoError(t, a.AddBuddy(2, "please"))
+	require.NoError(t, b.AuthAdd(3))
+	require.NoError(t, b.AuthAdd(1))
+	a.Close()
+
+	// WHEN
+	others := h.Others(2)
+
+	// THEN
+	// Buddy lists are kept while players are offline
+	assert.Equal(t, []int{1, 3}, others)
+	assert.Empty(t, h.Others(1))
+}
+
+func TestHub_AddBuddyOffline(t *testing.T) {
+	// GIVEN
+	h := NewHub()
+	a, _ := connect(h, 1)
+
+	// WHEN
+	err := a.AddBuddy(2, "please")
+	_, rb := connect(h, 2)
+
+	// THEN
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		`\bdy\0\list\\final\`,
+		`\bm\2\f\1\msg\please|signed|` + gamespy.ComputeMD5("1:2") + `\final\`,
+	}, rb.take())
+}
+
+func TestHub_Errors(t *testing.T) {
+	// GIVEN
+	h := NewHub()
+	a, _ := connect(h, 1)
+
+	// WHEN
+	errSelf := a.AddBuddy(1, "")
+	errNoRequest := a.AuthAdd(2)
+
+	// THEN
+	assert.ErrorIs(t, errSelf, ErrSelf)
+	assert.ErrorIs(t, errNoRequest, ErrNoRequest)
+}
+
+func TestSession_CloseReplaced(t *testing.T) {
+	// GIVEN
+	h := NewHub()
+	old, _ := connect(h, 1)
+	current, _ := connect(h, 1)
+
+	// WHEN
+	old.Close()
+
+	// THEN
+	assert.Same(t, current, h.sessions[1])
+}
+
+func TestSession_SendMessage(t *testing.T) {
+	// GIVEN
+	h := NewHub()
+	h.now = func() time.Time {
+		return time.Unix(1700000000, 0)
+	}
+	a, _ := connect(h, 1)
+	_, rb := connect(h, 2)
+	rb.take()
+
+	// WHEN
+	a.SendMessage(2, "hello")
+
+	// THEN
+	assert.Equal(t, []string{`\bm\1\f\1\date\1700000000\msg\hello\final\`}, rb.take())
+}
+
+func TestSession_SendMessageOffline(t *testing.T) {
+	// GIVEN
+	h := NewHub()
+	h.now = func() time.Time {
+		return time.Unix(1700000000, 0)
+	}
+	a, _ := connect(h, 1)
+
+	// WHEN
+	for i := range maxQueuedMessages + 1 {
+		a.SendMessage(2, strconv.Itoa(i))
+	}
+	_, rb := connect(h, 2)
+
+	// THEN
+	packets := rb.take()
+	require.Len(t, packets, maxQueuedMessages+1)
+	assert.Equal(t, `\bdy\0\list\\final\`, packets[0])
+	// Oldest message is dropped
+	assert.Equal(t, `\bm\1\f\1\date\1700000000\msg\1\final\`, packets[1])
+	assert.Equal(t, fmt.Sprintf(`\bm\1\f\1\date\1700000000\msg\%d\final\`, maxQueuedMessages), packets[maxQueuedMessages])
+
+	// WHEN
+	_, rb = connect(h, 2)
+
+	// THEN
+	// Queue is emptied on delivery
+	assert.Equal(t, []string{`\bdy\0\list\\final\`}, rb.take())
+}
@@ -0,0 +1,67 @@
+package internal
+
+type GamespyProfileRequest struct {
+	SessionKey int `gamespy:"sesskey"`
+	ProfileID  int `gamespy:"profileid"`
+	ID         int `gamespy:"id"`
+}
+
+// GamespyProfileResponse Contains the profile fields read by GameSpy SDK clients.
+type GamespyProfileResponse struct {
+	ProfileInfo string `gamespy:"pi"` // Key only, always empty
+	ProfileID   int    `gamespy:"profileid"`
+	Nick        string `gamespy:"nick"`
+	UserID      int    `gamespy:"userid"`
+	Email       string `gamespy:"email"`
+	Signature   string `gamespy:"sig"`
+	UniqueNick  string `gamespy:"uniquenick"`
+	FirstName   string `gamespy:"firstname"`
+	LastName    string `gamespy:"lastname"`
+	HomePage    string `gamespy:"homepage"`
+	ZipCode     string `gamespy:"zipcode"`
+	CountryCode string `gamespy:"countrycode"`
+	Location    string `gamespy:"loc"`
+	Birthday    int    `gamespy:"birthday"`
+	Sex         int    `gamespy:"sex"`
+	ICQUIN      int    `gamespy:"icquin"`
+	ID          int    `gamespy:"id"`
+}
+
+// GamespyUpdateProfileRequest Only fields present in the request are updated.
+type GamespyUpdateProfileRequest struct {
+	SessionKey  int     `gamespy:"sesskey"`
+	FirstName   *string `gamespy:"firstname"`
+	LastName    *string `gamespy:"lastname"`
+	HomePage    *string `gamespy:"homepage"`
+	ZipCode     *string `gamespy:"zipcode"`
+	CountryCode *string `gamespy:"countrycode"`
+	Location    *string `gamespy:"loc"`
+	Birthday    *int    `gamespy:"birthday"`
+	Sex         *int    `gamespy:"sex"`
+	ICQUIN      *int    `gamespy:"icquin"`
+}
+
+// Apply Sets all fields present in the request on profile.
+func (r GamespyUpdateProfileRequest) Apply(profile *Profile) {
+	setIfPresent(&profile.FirstName, r.FirstName)
+	setIfPresent(&profile.LastName, r.LastName)
+	setIfPresent(&profile.HomePage, r.HomePage)
+	setIfPresent(&profile.ZipCode, r.ZipCode)
+	setIfPresent(&profile.CountryCode, r.CountryCode)
+	setIfPresent(&profile.Location, r.Location)
+	setIfPresent(&profile.Birthday, r.Birthday)
+	setIfPresent(&profile.Sex, r.Sex)
+	setIfPresent(&profile.ICQUIN, r.ICQUIN)
+}
+
+// GamespyUpdateUserInfoRequest Only the email address is kept, other user info (such as hardware details) is ignored.
+type GamespyUpdateUserInfoRequest struct {
+	SessionKey int     `gamespy:"sesskey"`
+	Email      *string `gamespy:"email"`
+}
+
+func setIfPresent[T any](target *T, value *T) {
+	if value != nil {
+		*target = *value
+	}
+}
@@ -0,0 +1,127 @@
+package internal
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+
+	"github.com/dogclan/dumbspy/internal/storage"
+)
+
+const (
+	profilesBucket = "profiles"
+
+	// Following https://github.com/openspy/openspy-core/blob/5993df54c6b289361228920fa0db7209aed4cfe5/code/SharedTasks/src/OS/GPShared.h
+	SexMale   = 0
+	SexFemale = 1
+	SexPat    = 2 // Not specified
+)
+
+// Profile Contains the information a player can see about another player's profile.
+type Profile struct {
+	ID          int    `json:"id"`
+	UserID      int    `json:"userId"`
+	Nick        string `json:"nick"`
+	UniqueNick  string `json:"uniqueNick"`
+	Email       string `json:"email"`
+	FirstName   string `json:"firstName"`
+	LastName    string `json:"lastName"`
+	HomePage    string `json:"homePage"`
+	ZipCode     string `json:"zipCode"`
+	CountryCode string `json:"countryCode"`
+	Location    string `json:"location"`
+	Birthday    int    `json:"birthday"` // Packed as day | month << 8 | year << 16
+	Sex         int    `json:"sex"`
+	ICQUIN      int    `json:"icqUin"`
+}
+
+// NewProfile Returns a profile with defaults for a player.
+func NewProfile(player Player) Profile {
+	return Profile{
+		ID:         player.ID,
+		UserID:     player.ID,
+		Nick:       player.Nick,
+		UniqueNick: player.Nick,
+		Sex:        SexPat,
+	}
+}
+
+// NewLoginProfile Returns a profile with defaults derived from a player's login request.
+func NewLoginProfile(playerID int, login GamespyLoginRequest) Profile {
+	profile := NewProfile(Player{ID: playerID, Nick: login.Nick()})
+	if login.Mode() == LoginModeUser {
+		// Player identified by nick and email rather than a unique nick
+		profile.UniqueNick = ""
+		profile.Email = login.Email()
+	}
+	return profile
+}
+
+// ProfileStore Persists profiles in a storage.Store.
+type ProfileStore struct {
+	store storage.Store
+	mu    sync.Mutex
+}
+
+func NewProfileStore(store storage.Store) *ProfileStore {
+	return &ProfileStore{
+		store: store,
+	}
+}
+
+// Get Returns the stored profile with the given id.
+func (s *ProfileStore) Get(profileID int) (Profile, bool, error) {
+	var profile Profile
+	ok, err := s.store.Get(profilesBucket, strconv.Itoa(profileID), &profile)
+	if err != nil {
+		return Profile{}, false, fmt.Errorf("failed to get profile %d: %w", profileID, err)
+	}
+	return profile, ok, nil
+}
+
+// Create Stores the given profile unless a profile with the same id already exists. Returns the stored profile.
+func (s *ProfileStore) Create(profile Profile) (Profile, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing, ok, err := s.Get(profile.ID)
+	if err != nil || ok {
+		return existing, err
+	}
+
+	if err = s.put(profile); err != nil {
+		return Profile{}, err
+	}
+	return profile, nil
+}
+
+// Update Applies f to the stored profile with the given id (or defaults if the profile does not exist yet) and stores
+// the result.
+func (s *ProfileStore) Update(defaults Profile, f func(profile *Profile)) (Profile, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	profile, ok, err := s.Get(defaults.ID)
+	if err != nil {
+		return Profile{}, err
+	}
+	if !ok {
+		profile = defaults
+	}
+
+	f(&profile)
+	// Profile id is the key and thus cannot be changed
+	profile.ID = defaults.ID
+
+	if err = s.put(profile); err != nil {
+		return Profile{}, err
+	}
+	return profile, nil
+}
+
+func (s *ProfileStore) put(profile Profile) error {
+	if err := s.store.Put(profilesBucket, strconv.Itoa(profile.ID), profile); err != nil {
+		return fmt.Errorf("failed to store profile %d: %w", profile.ID, err)
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package internal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dogclan/dumbspy/internal/storage"
+)
+
+func TestNewLoginProfile(t *testing.T) {
+	type test struct {
+		name            string
+		givenLogin      GamespyLoginRequest
+		expectedProfile Profile
+	}
+
+	tests := []test{
+		{
+			name:       "unique nick login",
+			givenLogin: GamespyLoginRequest{UniqueNick: "some-nick"},
+			expectedProfile: Profile{
+				ID:         600001095,
+				UserID:     600001095,
+				Nick:       "some-nick",
+				UniqueNick: "some-nick",
+				Sex:        SexPat,
+			},
+		},
+		{
+			name:       "user login",
+			givenLogin: GamespyLoginRequest{User: "some-nick@player@example.com"},
+			expectedProfile: Profile{
+				ID:     600001095,
+				UserID: 600001095,
+				Nick:   "some-nick",
+				Email:  "player@example.com",
+				Sex:    SexPat,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// WHEN
+			profile := NewLoginProfile(600001095, tt.givenLogin)
+
+			// THEN
+			assert.Equal(t, tt.expectedProfile, profile)
+		})
+	}
+}
+
+func TestProfileStore(t *testing.T) {
+	// GIVEN
+	store := NewProfileStore(storage.NewMemoryStore())
+	defaults := NewProfile(Player{ID: 600001095, Nick: "some-nick"})
+
+	// WHEN
+	_, found, err := store.Get(defaults.ID)
+
+	// THEN
+	require.NoError(t, err)
+	assert.False(t, found)
+
+	// WHEN
+	created, err := store.Create(defaults)
+
+	// THEN
+	require.NoError(t, err)
+	assert.Equal(t, defaults, created)
+
+	// WHEN
+	updated, err := store.Update(defaults, GamespyUpdateProfileRequest{
+		FirstName:   ToPointer("Some"),
+		CountryCode: ToPointer("DE"),
+		Sex:         ToPointer(SexFemale),
+	}.Apply)
+
+	// THEN
+	require.NoError(t, err)
+	expected := defaults
+	expected.FirstName = "Some"
+	expected.CountryCode = "DE"
+	expected.Sex = SexFemale
+	assert.Equal(t, expected, updated)
+
+	// WHEN
+	// Existing profile must not be replaced by defaults
+	created, err = store.Create(defaults)
+
+	// THEN
+	require.NoError(t, err)
+	assert.Equal(t, expected, created)
+
+	// WHEN
+	stored, found, err := store.Get(defaults.ID)
+
+	// THEN
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, expected, stored)
+}
+
+func TestProfileStore_UpdateMissing(t *testing.T) {
+	// GIVEN
+	store := NewProfileStore(storage.NewMemoryStore())
+	defaults := NewProfile(Player{ID: 600001095, Nick: "some-nick"})
+
+	// WHEN
+	updated, err := store.Update(defaults, func(profile *Profile) {
+		profile.ID = 1
+		profile.Location = "Berlin"
+	})
+
+	// THEN
+	require.NoError(t, err)
+	expected := defaults
+	expected.Location = "Berlin"
+	assert.Equal(t, expected, updated)
+}
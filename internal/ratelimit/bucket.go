@@ -0,0 +1,39 @@
+// Package ratelimit Provides a token bucket to limit how often clients may perform an action.
+package ratelimit
+
+import (
+	"time"
+)
+
+// Bucket A token bucket holding up to burst tokens, which refills by one token per interval. Bucket is not safe for
+// concurrent use, since it is intended to be owned by a single connection handler.
+type Bucket struct {
+	interval time.Duration
+	burst    float64
+	tokens   float64
+	last     time.Time
+}
+
+// NewBucket Returns a full bucket.
+func NewBucket(interval time.Duration, burst int) *Bucket {
+	return &Bucket{
+		interval: interval,
+		burst:    float64(burst),
+		tokens:   float64(burst),
+	}
+}
+
+// Allow Takes a token from the bucket if one is available at now.
+func (b *Bucket) Allow(now time.Time) bool {
+	if !b.last.IsZero() {
+		b.tokens = min(b.burst, b.tokens+float64(now.Sub(b.last))/float64(b.interval))
+	}
+	b.last = now
+
+	if b.tokens < 1 {
+		return false
+	}
+
+	b.tokens--
+	return true
+}
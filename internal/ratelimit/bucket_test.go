@@ -0,0 +1,30 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBucket_Allow(t *testing.T) {
+	// GIVEN
+	b := NewBucket(time.Second, 2)
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	// WHEN
+	allowed := []bool{
+		b.Allow(start),
+		b.Allow(start),
+		b.Allow(start), // burst used up
+		b.Allow(start.Add(500 * time.Millisecond)),  // half a token refilled
+		b.Allow(start.Add(1000 * time.Millisecond)), // one token refilled
+		b.Allow(start.Add(1100 * time.Millisecond)), // bucket empty again
+		b.Allow(start.Add(time.Hour)),               // refill is capped at burst
+		b.Allow(start.Add(time.Hour)),
+		b.Allow(start.Add(time.Hour)),
+	}
+
+	// THEN
+	assert.Equal(t, []bool{true, true, false, false, true, false, true, true, false}, allowed)
+}
@@ -0,0 +1,45 @@
+package internal
+
+// GamespySearchRequest Contains the attributes of all requests sent to the search (GPSP) server.
+// Which attributes are present depends on the request.
+type GamespySearchRequest struct {
+	SessionKey  string `gamespy:"sesskey"`
+	ProfileID   string `gamespy:"profileid"`
+	Email       string `gamespy:"email"`
+	Nick        string `gamespy:"nick"`
+	UniqueNick  string `gamespy:"uniquenick"`
+	ProductID   string `gamespy:"productid"`
+	GameName    string `gamespy:"gamename"`
+	NamespaceID string `gamespy:"namespaceid"`
+	SDKRevision string `gamespy:"sdkrevision"`
+}
+
+type GamespyNicksResult struct {
+	Nick       string `gamespy:"nick"`
+	UniqueNick string `gamespy:"uniquenick"`
+}
+
+type GamespySearchResult struct {
+	ProfileID   int    `gamespy:"bsr"`
+	Nick        string `gamespy:"nick"`
+	FirstName   string `gamespy:"firstname"`
+	LastName    string `gamespy:"lastname"`
+	Email       string `gamespy:"email"`
+	UniqueNick  string `gamespy:"uniquenick"`
+	NamespaceID string `gamespy:"namespaceid"`
+}
+
+type GamespyOthersResult struct {
+	ProfileID  int    `gamespy:"o"`
+	Nick       string `gamespy:"nick"`
+	UniqueNick string `gamespy:"uniquenick"`
+	FirstName  string `gamespy:"first"`
+	LastName   string `gamespy:"last"`
+	Email      string `gamespy:"email"`
+}
+
+type GamespyProfileListResult struct {
+	ProfileID  int    `gamespy:"pid"`
+	Nick       string `gamespy:"nick"`
+	UniqueNick string `gamespy:"uniquenick"`
+}
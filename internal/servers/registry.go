@@ -0,0 +1,179 @@
+// Package servers Keeps track of game servers reporting to the master server.
+package servers
+
+import (
+	"maps"
+	"net/netip"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/dogclan/dumbspy/internal/metrics"
+	"github.com/dogclan/dumbspy/pkg/gamespy/qr2"
+)
+
+// Server A game server, identified by its public address (the address its heartbeats are sent from).
+type Server struct {
+	Addr        netip.AddrPort
+	InstanceKey [4]byte
+	GameName    string
+	Challenge   string // Challenge sent to the server, which it must answer to be registered
+	Registered  bool   // Whether the server answered the challenge and is thus listed
+	Info        map[string]string
+	Players     []map[string]string
+	Teams       []map[string]string
+	LastSeen    time.Time
+}
+
+// Registry Contains live game servers. Servers expire if they do not send any heartbeats or keep-alives within the
+// timeout.
+type Registry struct {
+	servers   map[netip.AddrPort]*Server
+	timeout   time.Duration
+	gameNames metrics.LabelValues
+	now       func() time.Time
+	mu        sync.Mutex
+}
+
+// NewRegistry Returns an empty registry. Game names are reported by servers and thus untrusted, so servers are only
+// counted by game name in metrics for the given known game names. Servers of any other game are counted as "other".
+func NewRegistry(timeout time.Duration, knownGameNames ...string) *Registry {
+	return &Registry{
+		servers:   make(map[netip.AddrPort]*Server),
+		timeout:   timeout,
+		gameNames: metrics.NewLabelValues(knownGameNames...),
+		now:       time.Now,
+	}
+}
+
+// Heartbeat Updates the server at addr with the heartbeat, adding the server if it is not known yet or restarted
+// (changed its instance key). Unregistered servers are assigned the given challenge. Returns a copy of the server.
+// Servers reporting that they are exiting are removed.
+func (r *Registry) Heartbeat(addr netip.AddrPort, instanceKey [4]byte, hb *qr2.Heartbeat, challenge string) (Server, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	s, ok := r.servers[addr]
+	if hb.Info["statechanged"] == qr2.StateExiting {
+		if ok {
+			r.remove(s)
+		}
+		return Server{}, false
+	}
+
+	if !ok || s.InstanceKey != instanceKey || r.expired(s) {
+		if ok {
+			r.remove(s)
+		}
+		s = &Server{
+			Addr:        addr,
+			InstanceKey: instanceKey,
+			GameName:    hb.Info["gamename"],
+		}
+		r.servers[addr] = s
+		metrics.GameServers.With(r.gameNames.Get(s.GameName)).Inc()
+	}
+
+	if !s.Registered {
+		s.Challenge = challenge
+	}
+	s.Info = hb.Info
+	s.Players = hb.Players
+	s.Teams = hb.Teams
+	s.LastSeen = r.now()
+
+	return *s, true
+}
+
+// Register Marks the server at addr as registered, listing it for clients. Returns a copy of the server.
+func (r *Registry) Register(addr netip.AddrPort, instanceKey [4]byte) (Server, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	s, ok := r.lookup(addr, instanceKey)
+	if !ok {
+		return Server{}, false
+	}
+
+	s.Registered = true
+	s.LastSeen = r.now()
+	return *s, true
+}
+
+// KeepAlive Prevents the server at addr from expiring. Returns false if the server is not known.
+func (r *Registry) KeepAlive(addr netip.AddrPort, instanceKey [4]byte) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	s, ok := r.lookup(addr, instanceKey)
+	if ok {
+		s.LastSeen = r.now()
+	}
+	return ok
+}
+
+// Get Returns a copy of the live server at addr.
+func (r *Registry) Get(addr netip.AddrPort) (Server, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	s, ok := r.servers[addr]
+	if !ok || r.expired(s) {
+		return Server{}, false
+	}
+	return *s, true
+}
+
+// Servers Returns copies of all live, registered servers for the game, ordered by address.
+func (r *Registry) Servers(gameName string) []Server {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var servers []Server
+	for _, addr := range slices.SortedFunc(maps.Keys(r.servers), netip.AddrPort.Compare) {
+		s := r.servers[addr]
+		if s.GameName == gameName && s.Registered && !r.expired(s) {
+			servers = append(servers, *s)
+		}
+	}
+	return servers
+}
+
+// Expire Removes all expired servers. Returns the number of removed servers.
+func (r *Registry) Expire() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	n := 0
+	for _, s := range r.servers {
+		if r.expired(s) {
+			r.remove(s)
+			n++
+		}
+	}
+	return n
+}
+
+func (r *Registry) lookup(addr netip.AddrPort, instanceKey [4]byte) (*Server, bool) {
+	s, ok := r.servers[addr]
+	if !ok || s.InstanceKey != instanceKey || r.expired(s) {
+		return nil, false
+	}
+	return s, true
+}
+
+func (r *Registry) expired(s *Server) bool {
+	return r.now().Sub(s.LastSeen) > r.timeout
+}
+
+func (r *Registry) remove(s *Server) {
+	delete(r.servers, s.Addr)
+
+	// Servers are only added/removed while the registry is locked, so the gauge cannot change concurrently
+	label := r.gameNames.Get(s.GameName)
+	gauge := metrics.GameServers.With(label)
+	gauge.Dec()
+	if gauge.Value() == 0 {
+		metrics.GameServers.Delete(label)
+	}
+}
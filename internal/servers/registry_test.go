@@ -0,0 +1,165 @@
+package servers
+
+import (
+	"bytes"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dogclan/dumbspy/internal/metrics"
+	"github.com/dogclan/dumbspy/pkg/gamespy/qr2"
+)
+
+var (
+	addr        = netip.MustParseAddrPort("10.0.0.2:29900")
+	instanceKey = [4]byte{0x01, 0x02, 0x03, 0x04}
+)
+
+func heartbeat(state string) *qr2.Heartbeat {
+	return &qr2.Heartbeat{
+		Info: map[string]string{
+			"gamename":     "battlefield2",
+			"hostname":     "some server",
+			"statechanged": state,
+		},
+	}
+}
+
+func newTestRegistry() (*Registry, *time.Time) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	r := NewRegistry(time.Minute)
+	r.now = func() time.Time {
+		return now
+	}
+	return r, &now
+}
+
+func TestRegistry_Lifecycle(t *testing.T) {
+	// GIVEN
+	r, now := newTestRegistry()
+
+	// WHEN
+	s, ok := r.Heartbeat(addr, instanceKey, heartbeat(qr2.StateStarting), "some-challenge")
+
+	// THEN
+	require.True(t, ok)
+	assert.Equal(t, "battlefield2", s.GameName)
+	assert.Equal(t, "some-challenge", s.Challenge)
+	assert.False(t, s.Registered)
+	// Unregistered servers are not listed
+	assert.Empty(t, r.Servers("battlefield2"))
+
+	// WHEN
+	s, ok = r.Register(addr, instanceKey)
+
+	// THEN
+	require.True(t, ok)
+	assert.True(t, s.Registered)
+	assert.Len(t, r.Servers("battlefield2"), 1)
+	assert.Empty(t, r.Servers("other"))
+
+	// WHEN
+	*now = now.Add(50 * time.Second)
+	ok = r.KeepAlive(addr, instanceKey)
+	*now = now.Add(50 * time.Second)
+
+	// THEN
+	require.True(t, ok)
+	assert.Len(t, r.Servers("battlefield2"), 1)
+
+	// WHEN
+	s, ok = r.Heartbeat(addr, instanceKey, heartbeat(qr2.StateNormal), "other-challenge")
+
+	// THEN
+	require.True(t, ok)
+	// Registered servers keep their challenge
+	assert.Equal(t, "some-challenge", s.Challenge)
+	assert.Equal(t, qr2.StateNormal, s.Info["statechanged"])
+
+	// WHEN
+	_, ok = r.Heartbeat(addr, instanceKey, heartbeat(qr2.StateExiting), "")
+
+	// THEN
+	assert.False(t, ok)
+	_, ok = r.Get(addr)
+	assert.False(t, ok)
+}
+
+func TestRegistry_GameServersMetric(t *testing.T) {
+	// GIVEN
+	r := NewRegistry(time.Minute, "battlefield2")
+	spoofed := heartbeat(qr2.StateNormal)
+	spoofed.Info["gamename"] = "some-spoofed-name"
+	writeMetrics := func() string {
+		buffer := new(bytes.Buffer)
+		require.NoError(t, metrics.DefaultRegistry.Write(buffer))
+		return buffer.String()
+	}
+
+	// WHEN
+	_, ok := r.Heartbeat(addr, instanceKey, heartbeat(qr2.StateNormal), "some-challenge")
+	require.True(t, ok)
+	_, ok = r.Heartbeat(netip.MustParseAddrPort("10.0.0.3:29900"), instanceKey, spoofed, "other-challenge")
+	require.True(t, ok)
+
+	// THEN
+	written := writeMetrics()
+	assert.Contains(t, written, `dumbspy_game_servers{gamename="battlefield2"} 1`)
+	assert.Contains(t, written, `dumbspy_game_servers{gamename="other"}`)
+	assert.NotContains(t, written, "some-spoofed-name")
+
+	// WHEN
+	_, ok = r.Heartbeat(addr, instanceKey, heartbeat(qr2.StateExiting), "")
+
+	// THEN
+	assert.False(t, ok)
+	assert.NotContains(t, writeMetrics(), `gamename="battlefield2"`)
+}
+
+func TestRegistry_InstanceKeyChange(t *testing.T) {
+	// GIVEN
+	r, _ := newTestRegistry()
+	r.Heartbeat(addr, instanceKey, heartbeat(qr2.StateStarting), "some-challenge")
+	r.Register(addr, instanceKey)
+
+	// WHEN
+	s, ok := r.Heartbeat(addr, [4]byte{0x05, 0x06, 0x07, 0x08}, heartbeat(qr2.StateStarting), "other-challenge")
+
+	// THEN
+	// Server restarted, so it has to answer a new challenge
+	require.True(t, ok)
+	assert.False(t, s.Registered)
+	assert.Equal(t, "other-challenge", s.Challenge)
+	assert.False(t, r.KeepAlive(addr, instanceKey))
+}
+
+func TestRegistry_Expire(t *testing.T) {
+	// GIVEN
+	r, now := newTestRegistry()
+	r.Heartbeat(addr, instanceKey, heartbeat(qr2.StateStarting), "some-challenge")
+	r.Register(addr, instanceKey)
+	other := netip.MustParseAddrPort("10.0.0.3:29900")
+	*now = now.Add(30 * time.Second)
+	r.Heartbeat(other, instanceKey, heartbeat(qr2.StateStarting), "some-challenge")
+	r.Register(other, instanceKey)
+
+	// WHEN
+	*now = now.Add(45 * time.Second)
+
+	// THEN
+	// Expired servers are neither returned nor kept alive, even before they are removed
+	servers := r.Servers("battlefield2")
+	require.Len(t, servers, 1)
+	assert.Equal(t, other, servers[0].Addr)
+	assert.False(t, r.KeepAlive(addr, instanceKey))
+
+	// WHEN
+	n := r.Expire()
+
+	// THEN
+	assert.Equal(t, 1, n)
+	assert.Len(t, r.servers, 1)
+}
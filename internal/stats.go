@@ -0,0 +1,86 @@
+package internal
+
+// Following https://github.com/openspy/openspy-core/blob/5993df54c6b289361228920fa0db7209aed4cfe5/code/gstats/server/GSPeer.h
+const (
+	// Player data types (public/private, read-only/read-write)
+	PlayerDataPublicReadOnly   = 0
+	PlayerDataPrivateReadOnly  = 1
+	PlayerDataPublicReadWrite  = 2
+	PlayerDataPrivateReadWrite = 3
+
+	// PlayerAuthFailed is the player auth result reported for rejected players (otherwise, the profile id is reported)
+	PlayerAuthFailed = -3
+)
+
+type GamespyStatsChallenge struct {
+	LoginCode int    `gamespy:"lc"`
+	Challenge string `gamespy:"challenge"`
+	ID        int    `gamespy:"id"`
+}
+
+// GamespyStatsAuthRequest Sent by games to authenticate themselves, responding to the challenge using their secret
+// key.
+type GamespyStatsAuthRequest struct {
+	GameName string `gamespy:"gamename"`
+	Response string `gamespy:"response"`
+	Port     int    `gamespy:"port"`
+	ID       int    `gamespy:"id"`
+}
+
+type GamespyStatsAuthResponse struct {
+	LoginCode  int `gamespy:"lc"`
+	SessionKey int `gamespy:"sesskey"`
+	Proof      int `gamespy:"proof"`
+	ID         int `gamespy:"id"`
+}
+
+// GamespyPlayerAuthRequest Sent by games to authenticate a player, either by profile id or by auth token. The local
+// id is chosen by the game and echoed in the response.
+type GamespyPlayerAuthRequest struct {
+	ProfileID int    `gamespy:"pid"`
+	AuthToken string `gamespy:"authtoken"`
+	Response  string `gamespy:"resp"`
+	LocalID   int    `gamespy:"lid"`
+}
+
+type GamespyPlayerAuthResponse struct {
+	Result       int     `gamespy:"pauthr"` // Profile id or PlayerAuthFailed
+	LocalID      int     `gamespy:"lid"`
+	ErrorMessage *string `gamespy:"errmsg"`
+}
+
+// GamespyGetPlayerDataRequest Requests player data. Keys are separated by \x01 (since backslashes are used as
+// separators in packets) and select values from key/value data.
+type GamespyGetPlayerDataRequest struct {
+	ProfileID int    `gamespy:"pid"`
+	DataType  int    `gamespy:"ptype"`
+	Index     int    `gamespy:"dindex"`
+	Keys      string `gamespy:"keys"`
+	LocalID   int    `gamespy:"lid"`
+}
+
+type GamespyGetPlayerDataResponse struct {
+	Result    int    `gamespy:"getpdr"` // 1 on success, 0 on failure
+	LocalID   int    `gamespy:"lid"`
+	ProfileID int    `gamespy:"pid"`
+	Modified  int64  `gamespy:"mod"`
+	Length    int    `gamespy:"length"`
+	Data      string `gamespy:"data"` // Must be last, since it may contain backslashes
+}
+
+type GamespySetPlayerDataRequest struct {
+	ProfileID int    `gamespy:"pid"`
+	DataType  int    `gamespy:"ptype"`
+	Index     int    `gamespy:"dindex"`
+	KeyValue  int    `gamespy:"kv"` // 1 if data is key/value data
+	LocalID   int    `gamespy:"lid"`
+	Length    int    `gamespy:"length"`
+	Data      string `gamespy:"data"`
+}
+
+type GamespySetPlayerDataResponse struct {
+	Result    int   `gamespy:"setpdr"` // 1 on success, 0 on failure
+	LocalID   int   `gamespy:"lid"`
+	ProfileID int   `gamespy:"pid"`
+	Modified  int64 `gamespy:"mod"`
+}
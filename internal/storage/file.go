@@ -0,0 +1,175 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// FileStore Keeps values in memory and writes all buckets to a single JSON file on every change. The file is replaced
+// atomically, so it is never left partially written. Intended for small amounts of data which change infrequently.
+// The file is written without holding the lock guarding the values, so reads never wait for disk I/O. Changes made
+// while the file is being written are written together by the next write.
+type FileStore struct {
+	MemoryStore
+	path string
+	// version is incremented on every change, guarded by MemoryStore.mu
+	version uint64
+	// flushed is the version last written to the file, guarded by flushMu
+	flushed uint64
+	flushMu sync.Mutex
+}
+
+// OpenFileStore Returns a store backed by the file at path, loading any existing values from it.
+// The file is created on the first change if it does not exist.
+func OpenFileStore(path string) (*FileStore, error) {
+	s := &FileStore{
+		MemoryStore: MemoryStore{
+			buckets: make(map[string]map[string]json.RawMessage),
+		},
+		path: path,
+	}
+
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read store file: %w", err)
+	}
+
+	if err = json.Unmarshal(b, &s.buckets); err != nil {
+		return nil, fmt.Errorf("failed to decode store file: %w", err)
+	}
+
+	// Decoding a "null" document results in a nil map
+	if s.buckets == nil {
+		s.buckets = make(map[string]map[string]json.RawMessage)
+	}
+
+	return s, nil
+}
+
+func (s *FileStore) Put(bucket, key string, v any) error {
+	value, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to encode value: %w", err)
+	}
+
+	s.mu.Lock()
+	previous := s.put(bucket, key, value)
+	version := s.bump()
+	s.mu.Unlock()
+
+	s.flushMu.Lock()
+	defer s.flushMu.Unlock()
+
+	if err = s.flush(version); err != nil {
+		s.restore(bucket, key, value, previous)
+		return err
+	}
+
+	return nil
+}
+
+func (s *FileStore) Delete(bucket, key string) error {
+	s.mu.Lock()
+	previous, ok := s.buckets[bucket][key]
+	if !ok {
+		s.mu.Unlock()
+		return nil
+	}
+	delete(s.buckets[bucket], key)
+	version := s.bump()
+	s.mu.Unlock()
+
+	s.flushMu.Lock()
+	defer s.flushMu.Unlock()
+
+	if err := s.flush(version); err != nil {
+		s.restore(bucket, key, nil, previous)
+		return err
+	}
+
+	return nil
+}
+
+// bump Increments and returns the version. Caller must hold the lock.
+func (s *FileStore) bump() uint64 {
+	s.version++
+	return s.version
+}
+
+// restore Restores the previous value of key after the file could not be written to keep memory in sync with the
+// file. Does nothing if the key has been changed again in the meantime. Caller must hold flushMu.
+func (s *FileStore) restore(bucket, key string, value, previous json.RawMessage) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current, ok := s.buckets[bucket][key]
+	if ok != (value != nil) || !bytes.Equal(current, value) {
+		return
+	}
+
+	if previous != nil {
+		s.put(bucket, key, previous)
+	} else {
+		delete(s.buckets[bucket], key)
+	}
+}
+
+// flush Writes all buckets to the file, unless a previous write already included the given version.
+// Caller must hold flushMu.
+func (s *FileStore) flush(version uint64) error {
+	if s.flushed >= version {
+		return nil
+	}
+
+	s.mu.RLock()
+	b, err := json.Marshal(s.buckets)
+	current := s.version
+	s.mu.RUnlock()
+	if err != nil {
+		return fmt.Errorf("failed to encode store: %w", err)
+	}
+
+	if err = s.persist(b); err != nil {
+		return err
+	}
+
+	s.flushed = current
+	return nil
+}
+
+// persist Replaces the file with b. Caller must hold flushMu.
+func (s *FileStore) persist(b []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary store file: %w", err)
+	}
+	// Removing fails once the file has been renamed, which is fine
+	defer func() {
+		_ = os.Remove(tmp.Name())
+	}()
+
+	if _, err = tmp.Write(b); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed to write temporary store file: %w", err)
+	}
+	if err = tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed to sync temporary store file: %w", err)
+	}
+	if err = tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close temporary store file: %w", err)
+	}
+
+	if err = os.Rename(tmp.Name(), s.path); err != nil {
+		return fmt.Errorf("failed to replace store file: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,125 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileStore(t *testing.T) {
+	testStore(t, func(t *testing.T) Store {
+		store, err := OpenFileStore(filepath.Join(t.TempDir(), "store.json"))
+		require.NoError(t, err)
+		return store
+	})
+
+	t.Run("loads values persisted by previous store", func(t *testing.T) {
+		// GIVEN
+		path := filepath.Join(t.TempDir(), "store.json")
+		previous, err := OpenFileStore(path)
+		require.NoError(t, err)
+		require.NoError(t, previous.Put("bucket", "key", value{Name: "some-name", Count: 1}))
+		require.NoError(t, previous.Put("bucket", "deleted", "some-value"))
+		require.NoError(t, previous.Delete("bucket", "deleted"))
+
+		// WHEN
+		store, err := OpenFileStore(path)
+
+		// THEN
+		require.NoError(t, err)
+		var actual value
+		ok, err := store.Get("bucket", "key", &actual)
+		require.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, value{Name: "some-name", Count: 1}, actual)
+		ok, err = store.Get("bucket", "deleted", new(string))
+		require.NoError(t, err)
+		assert.False(t, ok)
+	})
+
+	t.Run("does not create file until first change", func(t *testing.T) {
+		// GIVEN
+		path := filepath.Join(t.TempDir(), "store.json")
+
+		// WHEN
+		_, err := OpenFileStore(path)
+
+		// THEN
+		require.NoError(t, err)
+		assert.NoFileExists(t, path)
+	})
+
+	t.Run("loads empty document", func(t *testing.T) {
+		// GIVEN
+		path := filepath.Join(t.TempDir(), "store.json")
+		require.NoError(t, os.WriteFile(path, []byte("null"), 0o600))
+
+		// WHEN
+		store, err := OpenFileStore(path)
+
+		// THEN
+		require.NoError(t, err)
+		require.NoError(t, store.Put("bucket", "key", "some-value"))
+	})
+
+	t.Run("fails for malformed file", func(t *testing.T) {
+		// GIVEN
+		path := filepath.Join(t.TempDir(), "store.json")
+		require.NoError(t, os.WriteFile(path, []byte("not-json"), 0o600))
+
+		// WHEN
+		_, err := OpenFileStore(path)
+
+		// THEN
+		require.ErrorContains(t, err, "failed to decode store file")
+	})
+
+	t.Run("keeps previous value if file cannot be written", func(t *testing.T) {
+		// GIVEN
+		path := filepath.Join(t.TempDir(), "missing-dir", "store.json")
+		store, err := OpenFileStore(path)
+		require.NoError(t, err)
+
+		// WHEN
+		err = store.Put("bucket", "key", "some-value")
+
+		// THEN
+		require.ErrorContains(t, err, "failed to create temporary store file")
+		ok, err := store.Get("bucket", "key", new(string))
+		require.NoError(t, err)
+		assert.False(t, ok)
+	})
+	t.Run("persists concurrent changes", func(t *testing.T) {
+		// GIVEN
+		path := filepath.Join(t.TempDir(), "store.json")
+		store, err := OpenFileStore(path)
+		require.NoError(t, err)
+
+		// WHEN
+		var wg sync.WaitGroup
+		for i := range 20 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				assert.NoError(t, store.Put("bucket", strconv.Itoa(i), i))
+			}()
+		}
+		wg.Wait()
+
+		// THEN
+		reopened, err := OpenFileStore(path)
+		require.NoError(t, err)
+		for i := range 20 {
+			var actual int
+			ok, err := reopened.Get("bucket", strconv.Itoa(i), &actual)
+			require.NoError(t, err)
+			assert.True(t, ok)
+			assert.Equal(t, i, actual)
+		}
+	})
+}
@@ -0,0 +1,84 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"sync"
+)
+
+// MemoryStore Keeps values in memory only. Values are stored encoded, so later modifications of a value passed to
+// Put do not affect the stored value.
+type MemoryStore struct {
+	buckets map[string]map[string]json.RawMessage
+	mu      sync.RWMutex
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		buckets: make(map[string]map[string]json.RawMessage),
+	}
+}
+
+func (s *MemoryStore) Get(bucket, key string, v any) (bool, error) {
+	s.mu.RLock()
+	value, ok := s.buckets[bucket][key]
+	s.mu.RUnlock()
+
+	if !ok {
+		return false, nil
+	}
+
+	if err := json.Unmarshal(value, v); err != nil {
+		return false, fmt.Errorf("failed to decode value: %w", err)
+	}
+	return true, nil
+}
+
+func (s *MemoryStore) Put(bucket, key string, v any) error {
+	value, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to encode value: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.put(bucket, key, value)
+	return nil
+}
+
+func (s *MemoryStore) Delete(bucket, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.buckets[bucket], key)
+	return nil
+}
+
+func (s *MemoryStore) ForEach(bucket string, f func(key string, value json.RawMessage) error) error {
+	// Iterate over a copy to allow f to modify the store
+	s.mu.RLock()
+	values := maps.Clone(s.buckets[bucket])
+	s.mu.RUnlock()
+
+	for key, value := range values {
+		if err := f(key, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// put Stores the encoded value and returns the previous value (nil if the key did not exist). Caller must hold the lock.
+func (s *MemoryStore) put(bucket, key string, value json.RawMessage) json.RawMessage {
+	values, ok := s.buckets[bucket]
+	if !ok {
+		values = make(map[string]json.RawMessage)
+		s.buckets[bucket] = values
+	}
+
+	previous := values[key]
+	values[key] = value
+	return previous
+}
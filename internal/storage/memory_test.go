@@ -0,0 +1,161 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type value struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+func TestMemoryStore(t *testing.T) {
+	testStore(t, func(t *testing.T) Store {
+		return NewMemoryStore()
+	})
+}
+
+// testStore Runs tests every Store implementation needs to pass
+func testStore(t *testing.T, newStore func(t *testing.T) Store) {
+	t.Helper()
+
+	t.Run("gets value stored by put", func(t *testing.T) {
+		// GIVEN
+		store := newStore(t)
+		require.NoError(t, store.Put("bucket", "key", value{Name: "some-name", Count: 1}))
+
+		// WHEN
+		var actual value
+		ok, err := store.Get("bucket", "key", &actual)
+
+		// THEN
+		require.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, value{Name: "some-name", Count: 1}, actual)
+	})
+
+	t.Run("replaces existing value", func(t *testing.T) {
+		// GIVEN
+		store := newStore(t)
+		require.NoError(t, store.Put("bucket", "key", "first-value"))
+		require.NoError(t, store.Put("bucket", "key", "second-value"))
+
+		// WHEN
+		var actual string
+		ok, err := store.Get("bucket", "key", &actual)
+
+		// THEN
+		require.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, "second-value", actual)
+	})
+
+	t.Run("returns false for missing key", func(t *testing.T) {
+		// GIVEN
+		store := newStore(t)
+		require.NoError(t, store.Put("bucket", "key", "some-value"))
+
+		// WHEN
+		var actual string
+		ok, err := store.Get("bucket", "missing", &actual)
+
+		// THEN
+		require.NoError(t, err)
+		assert.False(t, ok)
+	})
+
+	t.Run("keeps buckets separate", func(t *testing.T) {
+		// GIVEN
+		store := newStore(t)
+		require.NoError(t, store.Put("bucket", "key", "some-value"))
+
+		// WHEN
+		var actual string
+		ok, err := store.Get("other-bucket", "key", &actual)
+
+		// THEN
+		require.NoError(t, err)
+		assert.False(t, ok)
+	})
+
+	t.Run("deletes value", func(t *testing.T) {
+		// GIVEN
+		store := newStore(t)
+		require.NoError(t, store.Put("bucket", "key", "some-value"))
+
+		// WHEN
+		err := store.Delete("bucket", "key")
+
+		// THEN
+		require.NoError(t, err)
+		ok, err := store.Get("bucket", "key", new(string))
+		require.NoError(t, err)
+		assert.False(t, ok)
+	})
+
+	t.Run("ignores delete of missing key", func(t *testing.T) {
+		// GIVEN
+		store := newStore(t)
+
+		// WHEN
+		err := store.Delete("bucket", "missing")
+
+		// THEN
+		require.NoError(t, err)
+	})
+
+	t.Run("iterates over bucket", func(t *testing.T) {
+		// GIVEN
+		store := newStore(t)
+		require.NoError(t, store.Put("bucket", "a", 1))
+		require.NoError(t, store.Put("bucket", "b", 2))
+		require.NoError(t, store.Put("other-bucket", "c", 3))
+
+		// WHEN
+		actual := map[string]string{}
+		err := store.ForEach("bucket", func(key string, value json.RawMessage) error {
+			actual[key] = string(value)
+			return nil
+		})
+
+		// THEN
+		require.NoError(t, err)
+		assert.Equal(t, map[string]string{"a": "1", "b": "2"}, actual)
+	})
+
+	t.Run("stops iteration on error", func(t *testing.T) {
+		// GIVEN
+		store := newStore(t)
+		require.NoError(t, store.Put("bucket", "a", 1))
+		require.NoError(t, store.Put("bucket", "b", 2))
+		someErr := errors.New("some-error")
+
+		// WHEN
+		calls := 0
+		err := store.ForEach("bucket", func(key string, value json.RawMessage) error {
+			calls++
+			return someErr
+		})
+
+		// THEN
+		require.ErrorIs(t, err, someErr)
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("fails to decode value into incompatible type", func(t *testing.T) {
+		// GIVEN
+		store := newStore(t)
+		require.NoError(t, store.Put("bucket", "key", "some-value"))
+
+		// WHEN
+		_, err := store.Get("bucket", "key", new(int))
+
+		// THEN
+		require.ErrorContains(t, err, "failed to decode value")
+	})
+}
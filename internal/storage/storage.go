@@ -0,0 +1,17 @@
+package storage
+
+import (
+	"encoding/json"
+)
+
+// Store Persists JSON-encoded values by key in named buckets.
+type Store interface {
+	// Get Decodes the value stored under key in bucket into v. Returns false if no such value exists.
+	Get(bucket, key string, v any) (bool, error)
+	// Put Stores v under key in bucket, replacing any existing value.
+	Put(bucket, key string, v any) error
+	// Delete Removes the value stored under key in bucket, if any.
+	Delete(bucket, key string) error
+	// ForEach Calls f for every key and value in bucket. Iteration stops at the first error returned by f.
+	ForEach(bucket string, f func(key string, value json.RawMessage) error) error
+}
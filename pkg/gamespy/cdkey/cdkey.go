@@ -0,0 +1,36 @@
+// Package cdkey Implements the framing of the GameSpy CD key (gs-cdkey) protocol, which game servers use to validate
+// their players' CD keys via UDP. Packets are XOR-encoded with a fixed key and, unlike other gamespy packets, are not
+// terminated by \final\.
+package cdkey
+
+import (
+	"bytes"
+	"slices"
+
+	"github.com/dogclan/dumbspy/pkg/gamespy"
+)
+
+const (
+	xorKey = "gamespy"
+
+	final = `\final\`
+)
+
+// Decode Returns the packet contained in the encoded datagram b.
+func Decode(b []byte) (*gamespy.Packet, error) {
+	decoded := slices.Clone(b)
+	gamespy.XOR(decoded, xorKey)
+
+	// Some implementations terminate packets regardless, so only add the terminator if it is missing
+	if !bytes.HasSuffix(decoded, []byte(final)) {
+		decoded = append(decoded, final...)
+	}
+	return gamespy.NewPacketFromBytes(decoded)
+}
+
+// Encode Returns the encoded datagram for packet.
+func Encode(packet *gamespy.Packet) []byte {
+	b := bytes.TrimSuffix(packet.Bytes(), []byte(final))
+	gamespy.XOR(b, xorKey)
+	return b
+}
@@ -0,0 +1,78 @@
+package cdkey
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dogclan/dumbspy/pkg/gamespy"
+)
+
+func TestDecode(t *testing.T) {
+	type test struct {
+		name            string
+		givenPlain      string
+		expectedPacket  *gamespy.Packet
+		wantErrContains string
+	}
+
+	tests := []test{
+		{
+			name:       "decodes packet",
+			givenPlain: `\auth\\pid\1\skey\42`,
+			expectedPacket: gamespy.NewPacket(
+				gamespy.KeyValuePair{Key: "auth"},
+				gamespy.KeyValuePair{Key: "pid", Value: "1"},
+				gamespy.KeyValuePair{Key: "skey", Value: "42"},
+			),
+		},
+		{
+			name:       "decodes packet terminated by final",
+			givenPlain: `\ka\\final\`,
+			expectedPacket: gamespy.NewPacket(
+				gamespy.KeyValuePair{Key: "ka"},
+			),
+		},
+		{
+			name:            "fails for malformed packet",
+			givenPlain:      `ka`,
+			wantErrContains: "malformed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// GIVEN
+			b := []byte(tt.givenPlain)
+			gamespy.XOR(b, "gamespy")
+
+			// WHEN
+			packet, err := Decode(b)
+
+			// THEN
+			if tt.wantErrContains != "" {
+				require.ErrorContains(t, err, tt.wantErrContains)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, slices.Collect(tt.expectedPacket.All()), slices.Collect(packet.All()))
+			}
+		})
+	}
+}
+
+func TestEncode(t *testing.T) {
+	// GIVEN
+	packet := gamespy.NewPacket(
+		gamespy.KeyValuePair{Key: "uok"},
+		gamespy.KeyValuePair{Key: "cd", Value: "abc"},
+	)
+
+	// WHEN
+	b := Encode(packet)
+
+	// THEN
+	gamespy.XOR(b, "gamespy")
+	assert.Equal(t, `\uok\\cd\abc`, string(b))
+}
@@ -0,0 +1,317 @@
+// Package codec Encodes and decodes the binary structures shared by the GameSpy UDP/TCP protocols (QR2, server
+// browsing, natneg): null-terminated strings, big-endian integers, IPv4 addresses and ports, key lists and
+// per-player/per-team value tables.
+package codec
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/netip"
+	"strings"
+
+	"github.com/dogclan/dumbspy/pkg/gamespy"
+)
+
+var (
+	ErrShortData      = errors.New("data too short")
+	ErrMissingNulByte = errors.New("missing string terminator")
+)
+
+// Decoder Reads values from a byte slice. Errors are sticky: once a read fails, all further reads return zero values
+// and Err returns the first error. Callers thus only need to check Err after a sequence of reads.
+type Decoder struct {
+	b   []byte
+	err error
+}
+
+func NewDecoder(b []byte) *Decoder {
+	return &Decoder{
+		b: b,
+	}
+}
+
+// Err Returns the first error encountered while reading.
+func (d *Decoder) Err() error {
+	return d.err
+}
+
+// Len Returns the number of unread bytes.
+func (d *Decoder) Len() int {
+	return len(d.b)
+}
+
+// Rest Returns all unread bytes, which reference the decoded slice.
+func (d *Decoder) Rest() []byte {
+	b := d.b
+	d.b = d.b[len(d.b):]
+	return b
+}
+
+// Raw Returns the next n bytes, which reference the decoded slice.
+func (d *Decoder) Raw(n int) []byte {
+	if d.err != nil {
+		return nil
+	}
+	if len(d.b) < n {
+		d.fail(ErrShortData)
+		return nil
+	}
+
+	b := d.b[:n]
+	d.b = d.b[n:]
+	return b
+}
+
+func (d *Decoder) Uint8() uint8 {
+	b := d.Raw(1)
+	if b == nil {
+		return 0
+	}
+	return b[0]
+}
+
+// Bool Reads a single byte, which is true unless zero.
+func (d *Decoder) Bool() bool {
+	return d.Uint8() != 0
+}
+
+func (d *Decoder) Uint16() uint16 {
+	b := d.Raw(2)
+	if b == nil {
+		return 0
+	}
+	return binary.BigEndian.Uint16(b)
+}
+
+func (d *Decoder) Uint32() uint32 {
+	b := d.Raw(4)
+	if b == nil {
+		return 0
+	}
+	return binary.BigEndian.Uint32(b)
+}
+
+// IP Reads an IPv4 address.
+func (d *Decoder) IP() netip.Addr {
+	b := d.Raw(4)
+	if b == nil {
+		return netip.Addr{}
+	}
+	return netip.AddrFrom4([4]byte(b))
+}
+
+// AddrPort Reads an IPv4 address followed by a port.
+func (d *Decoder) AddrPort() netip.AddrPort {
+	ip := d.IP()
+	port := d.Uint16()
+	if d.err != nil {
+		return netip.AddrPort{}
+	}
+	return netip.AddrPortFrom(ip, port)
+}
+
+// CString Reads a null-terminated string.
+func (d *Decoder) CString() string {
+	if d.err != nil {
+		return ""
+	}
+
+	i := bytes.IndexByte(d.b, 0)
+	if i == -1 {
+		d.fail(ErrMissingNulByte)
+		return ""
+	}
+
+	s := string(d.b[:i])
+	d.b = d.b[i+1:]
+	return s
+}
+
+// FixedString Reads a string padded with null bytes to a field of n bytes. Fields may be cut short at the end of the
+// data.
+func (d *Decoder) FixedString(n int) string {
+	b := d.Raw(min(n, len(d.b)))
+	b, _, _ = bytes.Cut(b, []byte{0})
+	return string(b)
+}
+
+// Keys Reads null-terminated keys up to an empty key.
+func (d *Decoder) Keys() []string {
+	var keys []string
+	for {
+		key := d.CString()
+		if d.err != nil {
+			return nil
+		}
+		if key == "" {
+			return keys
+		}
+		keys = append(keys, key)
+	}
+}
+
+// KeyValues Reads null-terminated key and value strings up to an empty key. Pairs are returned in the order they were
+// read, including any duplicate keys.
+func (d *Decoder) KeyValues() []gamespy.KeyValuePair {
+	var values []gamespy.KeyValuePair
+	for {
+		key := d.CString()
+		if d.err != nil {
+			return nil
+		}
+		if key == "" {
+			return values
+		}
+
+		value := d.CString()
+		if d.err != nil {
+			d.err = fmt.Errorf("key %q: %w", key, d.err)
+			return nil
+		}
+		values = append(values, gamespy.KeyValuePair{Key: key, Value: value})
+	}
+}
+
+// Table Reads a two byte count followed by keys (see Keys) and the null-terminated values of each of the count rows,
+// one value per key.
+func (d *Decoder) Table() []map[string]string {
+	count := int(d.Uint16())
+	keys := d.Keys()
+	if d.err != nil {
+		return nil
+	}
+
+	// Do not trust the count for the allocation, each row must consume at least one byte per key
+	rows := make([]map[string]string, 0, min(count, len(d.b)))
+	for range count {
+		row := make(map[string]string, len(keys))
+		for _, key := range keys {
+			value := d.CString()
+			if d.err != nil {
+				d.err = fmt.Errorf("key %q: %w", key, d.err)
+				return nil
+			}
+			row[key] = value
+		}
+		rows = append(rows, row)
+	}
+
+	return rows
+}
+
+func (d *Decoder) fail(err error) {
+	d.err = err
+	d.b = nil
+}
+
+// Encoder Appends values to a byte slice.
+type Encoder struct {
+	b []byte
+}
+
+// NewEncoder Returns an encoder appending to dst.
+func NewEncoder(dst []byte) *Encoder {
+	return &Encoder{
+		b: dst,
+	}
+}
+
+// Bytes Returns the encoded data (including the data of the slice passed to NewEncoder).
+func (e *Encoder) Bytes() []byte {
+	return e.b
+}
+
+// Len Returns the length of the encoded data.
+func (e *Encoder) Len() int {
+	return len(e.b)
+}
+
+func (e *Encoder) Raw(b []byte) {
+	e.b = append(e.b, b...)
+}
+
+func (e *Encoder) Uint8(v uint8) {
+	e.b = append(e.b, v)
+}
+
+// Bool Writes a single byte, 1 for true and 0 for false.
+func (e *Encoder) Bool(v bool) {
+	if v {
+		e.b = append(e.b, 1)
+	} else {
+		e.b = append(e.b, 0)
+	}
+}
+
+func (e *Encoder) Uint16(v uint16) {
+	e.b = binary.BigEndian.AppendUint16(e.b, v)
+}
+
+func (e *Encoder) Uint32(v uint32) {
+	e.b = binary.BigEndian.AppendUint32(e.b, v)
+}
+
+// IP Writes an IPv4 address. Addresses other than IPv4 addresses are written as 0.0.0.0.
+func (e *Encoder) IP(ip netip.Addr) {
+	if !ip.Is4() {
+		e.b = append(e.b, 0, 0, 0, 0)
+		return
+	}
+	a := ip.As4()
+	e.b = append(e.b, a[:]...)
+}
+
+// AddrPort Writes an IPv4 address followed by a port.
+func (e *Encoder) AddrPort(addr netip.AddrPort) {
+	e.IP(addr.Addr())
+	e.Uint16(addr.Port())
+}
+
+// CString Writes a null-terminated string, dropping any null bytes within s.
+func (e *Encoder) CString(s string) {
+	e.b = append(e.b, strings.ReplaceAll(s, "\x00", "")...)
+	e.b = append(e.b, 0)
+}
+
+// FixedString Writes s padded with null bytes to a field of n bytes. Longer strings are truncated.
+func (e *Encoder) FixedString(s string, n int) {
+	s = s[:min(len(s), n)]
+	e.b = append(e.b, s...)
+	e.b = append(e.b, make([]byte, n-len(s))...)
+}
+
+// Keys Writes the keys followed by an empty key.
+func (e *Encoder) Keys(keys []string) {
+	for _, key := range keys {
+		e.CString(key)
+	}
+	e.Uint8(0)
+}
+
+// KeyValues Writes the keys and values in the given order followed by an empty key.
+func (e *Encoder) KeyValues(values []gamespy.KeyValuePair) {
+	for _, value := range values {
+		e.CString(value.Key)
+		e.CString(value.Value)
+	}
+	e.Uint8(0)
+}
+
+// Table Writes the number of rows, the keys and the values of each row in key order. Rows missing a key are written
+// with an empty value.
+func (e *Encoder) Table(keys []string, rows []map[string]string) error {
+	if len(rows) > 0xFFFF {
+		return fmt.Errorf("too many rows: %d", len(rows))
+	}
+
+	e.Uint16(uint16(len(rows)))
+	e.Keys(keys)
+	for _, row := range rows {
+		for _, key := range keys {
+			e.CString(row[key])
+		}
+	}
+	return nil
+}
@@ -0,0 +1,348 @@
+package codec
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dogclan/dumbspy/pkg/gamespy"
+)
+
+// Fixtures mirror the traffic of Battlefield 2 (1.5) servers and clients, keeping the keys, their order and value
+// formats of the GameSpy SDK (local addresses, natneg and state keys precede the game's own keys in heartbeats).
+var (
+	// heartbeatFixture is the data of a QR2 heartbeat (server info, players and teams)
+	heartbeatFixture = []byte("" +
+		"localip0\x00192.168.0.5\x00localport\x0029900\x00natneg\x000\x00statechanged\x001\x00" +
+		"gamename\x00battlefield2\x00hostname\x00dumbspy test server\x00gamever\x001.5.3153-802.0\x00" +
+		"mapname\x00Strike At Karkand\x00gametype\x00gpm_cq\x00gamevariant\x00bf2\x00numplayers\x002\x00" +
+		"maxplayers\x0064\x00gamemode\x00openplaying\x00password\x000\x00timelimit\x000\x00roundtime\x001\x00" +
+		"hostport\x0016567\x00bf2_dedicated\x001\x00bf2_ranked\x000\x00bf2_anticheat\x000\x00bf2_os\x00linux-64\x00" +
+		"bf2_autorec\x000\x00bf2_d_idx\x00\x00bf2_d_dl\x00\x00bf2_voip\x001\x00bf2_autobalanced\x001\x00" +
+		"bf2_friendlyfire\x001\x00bf2_tkmode\x00Punish\x00bf2_startdelay\x0015\x00bf2_spawntime\x0015.000000\x00" +
+		"bf2_sponsortext\x00\x00bf2_sponsorlogo_url\x00\x00bf2_communitylogo_url\x00\x00bf2_scorelimit\x000\x00" +
+		"bf2_ticketratio\x00100\x00bf2_teamratio\x00100.000000\x00bf2_team1\x00MEC\x00bf2_team2\x00US\x00" +
+		"bf2_bots\x000\x00bf2_pure\x000\x00bf2_mapsize\x0064\x00bf2_globalunlocks\x001\x00bf2_fps\x0035.000000\x00" +
+		"bf2_plasma\x000\x00bf2_reservedslots\x000\x00bf2_coopbotratio\x00\x00bf2_coopbotcount\x00\x00" +
+		"bf2_coopbotdiff\x00\x00bf2_novehicles\x000\x00\x00" +
+		"\x00\x02" + "player_\x00score_\x00ping_\x00team_\x00deaths_\x00pid_\x00skill_\x00AIBot_\x00\x00" +
+		"mister249\x0012\x0042\x001\x003\x00500000001\x005\x000\x00" +
+		"someone\x00-1\x00120\x002\x007\x00500000002\x000\x000\x00" +
+		"\x00\x02" + "team_t\x00score_t\x00\x00" + "MEC\x000\x00" + "US\x000\x00",
+	)
+	// listRequestFixture is the body of a server list request (protocol and encoding version, game version, query and
+	// from game, challenge, filter, fields and options)
+	listRequestFixture = []byte("\x01\x03\x00\x00\x00\x00battlefield2\x00battlefield2\x00" + "Z7eEmRa5" +
+		"gamever='1.5.3153-802.0' and numplayers>0\x00" +
+		"\\hostname\\gamever\\numplayers\\maxplayers\\mapname\\gametype\\password\\bf2_ranked\\hostport" +
+		"\\natneg\\localip0\\localport\x00" +
+		"\x00\x00\x00\x00",
+	)
+	// natnegInitFixture is the data of a natneg init packet (port type, client index, use game port, local address
+	// and game name)
+	natnegInitFixture = []byte("\x01\x01\x01\xc0\xa8\x00\x05\x40\xb7battlefield2\x00")
+	// natnegReportFixture is the data of a natneg report packet including the fixed-size game name
+	natnegReportFixture = append(
+		[]byte("\x00\x00\x01\x00\x00\x00\x03\x00\x00\x00\x01battlefield2"),
+		make([]byte, 38)...,
+	)
+)
+
+func TestDecoder_Heartbeat(t *testing.T) {
+	// GIVEN
+	d := NewDecoder(heartbeatFixture)
+
+	// WHEN
+	info := d.KeyValues()
+	players := d.Table()
+	teams := d.Table()
+
+	// THEN
+	require.NoError(t, d.Err())
+	assert.Equal(t, 0, d.Len())
+	require.Len(t, info, 49)
+	assert.Equal(t, []gamespy.KeyValuePair{
+		{Key: "localip0", Value: "192.168.0.5"},
+		{Key: "localport", Value: "29900"},
+		{Key: "natneg", Value: "0"},
+		{Key: "statechanged", Value: "1"},
+		{Key: "gamename", Value: "battlefield2"},
+		{Key: "hostname", Value: "dumbspy test server"},
+	}, info[:6])
+	assert.Equal(t, gamespy.KeyValuePair{Key: "bf2_novehicles", Value: "0"}, info[len(info)-1])
+	assert.Equal(t, []map[string]string{
+		{
+			"player_": "mister249", "score_": "12", "ping_": "42", "team_": "1", "deaths_": "3",
+			"pid_": "500000001", "skill_": "5", "AIBot_": "0",
+		},
+		{
+			"player_": "someone", "score_": "-1", "ping_": "120", "team_": "2", "deaths_": "7",
+			"pid_": "500000002", "skill_": "0", "AIBot_": "0",
+		},
+	}, players)
+	assert.Equal(t, []map[string]string{
+		{"team_t": "MEC", "score_t": "0"},
+		{"team_t": "US", "score_t": "0"},
+	}, teams)
+
+	// WHEN
+	e := NewEncoder(nil)
+	e.KeyValues(info)
+	playerKeys := []string{"player_", "score_", "ping_", "team_", "deaths_", "pid_", "skill_", "AIBot_"}
+	require.NoError(t, e.Table(playerKeys, players))
+	require.NoError(t, e.Table([]string{"team_t", "score_t"}, teams))
+
+	// THEN
+	assert.Equal(t, heartbeatFixture, e.Bytes())
+}
+
+func TestDecoder_KeyValues(t *testing.T) {
+	// GIVEN
+	data := []byte("numplayers\x002\x00gamename\x00battlefield2\x00numplayers\x003\x00\x00")
+	d := NewDecoder(data)
+
+	// WHEN
+	values := d.KeyValues()
+
+	// THEN
+	require.NoError(t, d.Err())
+	assert.Equal(t, []gamespy.KeyValuePair{
+		{Key: "numplayers", Value: "2"},
+		{Key: "gamename", Value: "battlefield2"},
+		{Key: "numplayers", Value: "3"},
+	}, values)
+
+	// WHEN
+	e := NewEncoder(nil)
+	e.KeyValues(values)
+
+	// THEN
+	assert.Equal(t, data, e.Bytes())
+}
+
+func TestDecoder_ListRequest(t *testing.T) {
+	// GIVEN
+	d := NewDecoder(listRequestFixture)
+
+	// WHEN
+	protocolVersion := d.Uint8()
+	encodingVersion := d.Uint8()
+	gameVersion := d.Uint32()
+	queryGame := d.CString()
+	fromGame := d.CString()
+	challenge := d.Raw(8)
+	filter := d.CString()
+	fields := d.CString()
+	options := d.Uint32()
+
+	// THEN
+	require.NoError(t, d.Err())
+	assert.Equal(t, 0, d.Len())
+	assert.Equal(t, uint8(1), protocolVersion)
+	assert.Equal(t, uint8(3), encodingVersion)
+	assert.Equal(t, uint32(0), gameVersion)
+	assert.Equal(t, "battlefield2", queryGame)
+	assert.Equal(t, "battlefield2", fromGame)
+	assert.Equal(t, []byte("Z7eEmRa5"), challenge)
+	assert.Equal(t, "gamever='1.5.3153-802.0' and numplayers>0", filter)
+	assert.Equal(t, `\hostname\gamever\numplayers\maxplayers\mapname\gametype\password\bf2_ranked\hostport`+
+		`\natneg\localip0\localport`, fields)
+	assert.Equal(t, uint32(0), options)
+
+	// WHEN
+	e := NewEncoder(nil)
+	e.Uint8(protocolVersion)
+	e.Uint8(encodingVersion)
+	e.Uint32(gameVersion)
+	e.CString(queryGame)
+	e.CString(fromGame)
+	e.Raw(challenge)
+	e.CString(filter)
+	e.CString(fields)
+	e.Uint32(options)
+
+	// THEN
+	assert.Equal(t, listRequestFixture, e.Bytes())
+}
+
+func TestDecoder_NATNegInit(t *testing.T) {
+	// GIVEN
+	d := NewDecoder(natnegInitFixture)
+
+	// WHEN
+	portType := d.Uint8()
+	clientIndex := d.Uint8()
+	useGamePort := d.Bool()
+	localAddr := d.AddrPort()
+	gameName := d.CString()
+
+	// THEN
+	require.NoError(t, d.Err())
+	assert.Equal(t, uint8(1), portType)
+	assert.Equal(t, uint8(1), clientIndex)
+	assert.True(t, useGamePort)
+	assert.Equal(t, netip.MustParseAddrPort("192.168.0.5:16567"), localAddr)
+	assert.Equal(t, "battlefield2", gameName)
+
+	// WHEN
+	e := NewEncoder(nil)
+	e.Uint8(portType)
+	e.Uint8(clientIndex)
+	e.Bool(useGamePort)
+	e.AddrPort(localAddr)
+	e.CString(gameName)
+
+	// THEN
+	assert.Equal(t, natnegInitFixture, e.Bytes())
+}
+
+func TestDecoder_NATNegReport(t *testing.T) {
+	// GIVEN
+	d := NewDecoder(natnegReportFixture)
+
+	// WHEN
+	header := d.Raw(3)
+	natType := d.Uint32()
+	mappingScheme := d.Uint32()
+	gameName := d.FixedString(50)
+
+	// THEN
+	require.NoError(t, d.Err())
+	assert.Equal(t, []byte{0x00, 0x00, 0x01}, header)
+	assert.Equal(t, uint32(3), natType)
+	assert.Equal(t, uint32(1), mappingScheme)
+	assert.Equal(t, "battlefield2", gameName)
+
+	// WHEN
+	e := NewEncoder(nil)
+	e.Raw(header)
+	e.Uint32(natType)
+	e.Uint32(mappingScheme)
+	e.FixedString(gameName, 50)
+
+	// THEN
+	assert.Equal(t, natnegReportFixture, e.Bytes())
+}
+
+func TestDecoder_Errors(t *testing.T) {
+	type test struct {
+		name            string
+		givenData       []byte
+		decode          func(d *Decoder)
+		wantErrContains string
+	}
+
+	tests := []test{
+		{
+			name:            "fails for short integer",
+			givenData:       []byte{0x00, 0x01, 0x02},
+			decode:          func(d *Decoder) { d.Uint32() },
+			wantErrContains: "data too short",
+		},
+		{
+			name:            "fails for unterminated string",
+			givenData:       []byte("battlefield2"),
+			decode:          func(d *Decoder) { d.CString() },
+			wantErrContains: "missing string terminator",
+		},
+		{
+			name:            "fails for missing value",
+			givenData:       []byte("gamename\x00battlefield2"),
+			decode:          func(d *Decoder) { d.KeyValues() },
+			wantErrContains: "key \"gamename\": missing string terminator",
+		},
+		{
+			name:            "fails for truncated table count",
+			givenData:       []byte{0x00},
+			decode:          func(d *Decoder) { d.Table() },
+			wantErrContains: "data too short",
+		},
+		{
+			name:            "fails for missing table value",
+			givenData:       []byte("\x00\x02player_\x00\x00mister249\x00"),
+			decode:          func(d *Decoder) { d.Table() },
+			wantErrContains: "key \"player_\": missing string terminator",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// GIVEN
+			d := NewDecoder(tt.givenData)
+
+			// WHEN
+			tt.decode(d)
+
+			// THEN
+			require.ErrorContains(t, d.Err(), tt.wantErrContains)
+		})
+	}
+}
+
+func TestDecoder_StickyError(t *testing.T) {
+	// GIVEN
+	d := NewDecoder([]byte("\x01unterminated"))
+
+	// WHEN
+	first := d.Uint8()
+	s := d.CString()
+	// Would succeed if the error was not sticky
+	second := d.Uint8()
+
+	// THEN
+	assert.Equal(t, uint8(1), first)
+	assert.Empty(t, s)
+	assert.Zero(t, second)
+	assert.ErrorIs(t, d.Err(), ErrMissingNulByte)
+	assert.Equal(t, 0, d.Len())
+}
+
+func TestEncoder(t *testing.T) {
+	t.Run("drops null bytes within strings", func(t *testing.T) {
+		// GIVEN
+		e := NewEncoder([]byte{0x01})
+
+		// WHEN
+		e.CString("some\x00value")
+
+		// THEN
+		assert.Equal(t, []byte("\x01somevalue\x00"), e.Bytes())
+	})
+
+	t.Run("truncates fixed strings", func(t *testing.T) {
+		// GIVEN
+		e := NewEncoder(nil)
+
+		// WHEN
+		e.FixedString("battlefield2", 6)
+
+		// THEN
+		assert.Equal(t, []byte("battle"), e.Bytes())
+	})
+
+	t.Run("writes non-IPv4 addresses as zeros", func(t *testing.T) {
+		// GIVEN
+		e := NewEncoder(nil)
+
+		// WHEN
+		e.IP(netip.MustParseAddr("::1"))
+		e.IP(netip.Addr{})
+
+		// THEN
+		assert.Equal(t, make([]byte, 8), e.Bytes())
+	})
+
+	t.Run("writes empty values for missing table keys", func(t *testing.T) {
+		// GIVEN
+		e := NewEncoder(nil)
+
+		// WHEN
+		err := e.Table([]string{"player_", "score_"}, []map[string]string{{"player_": "mister249"}})
+
+		// THEN
+		require.NoError(t, err)
+		assert.Equal(t, []byte("\x00\x01player_\x00score_\x00\x00mister249\x00\x00"), e.Bytes())
+	})
+}
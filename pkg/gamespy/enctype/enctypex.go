@@ -0,0 +1,180 @@
+// Package enctype Implements enctypeX, the encryption GameSpy master servers use to protect server lists sent via the
+// server browsing (SB) v2 protocol.
+package enctype
+
+import (
+	"errors"
+	"fmt"
+)
+
+const (
+	// Header bytes are obfuscated by XORing them with these values
+	headerPaddingMask   = 0xEC
+	headerChallengeMask = 0xEA
+
+	ClientChallengeLength = 8
+)
+
+var (
+	ErrUnsupported  = errors.New("unsupported enctype")
+	ErrShortHeader  = errors.New("enctypeX header too short")
+	ErrEmptyGameKey = errors.New("game key must not be empty")
+)
+
+// XCipher Implements enctypeX following Luigi Auriemma's enctypex_decoder. The cipher state depends on the processed
+// data, so the same instance must be used for a whole stream and cannot be used to both encrypt and decrypt.
+type XCipher struct {
+	state [261]byte
+}
+
+// NewXCipher Returns an enctypeX cipher keyed with the game's secret key, the challenge sent by the client in its
+// request and the server challenge sent in the response header.
+func NewXCipher(gameKey string, clientChallenge [ClientChallengeLength]byte, serverChallenge []byte) (*XCipher, error) {
+	if gameKey == "" {
+		return nil, ErrEmptyGameKey
+	}
+
+	// Mix the server challenge into the client challenge (funcx)
+	validate := clientChallenge
+	for i, b := range serverChallenge {
+		validate[(int(gameKey[i%len(gameKey)])*i)&7] ^= validate[i&7] ^ b
+	}
+
+	c := new(XCipher)
+	c.init(validate[:])
+	return c, nil
+}
+
+// Encrypt Encrypts b in place.
+func (c *XCipher) Encrypt(b []byte) {
+	for i, d := range b {
+		e := c.keyStream() ^ d
+		c.state[260] = e
+		c.state[259] = d
+		b[i] = e
+	}
+}
+
+// Decrypt Decrypts b in place.
+func (c *XCipher) Decrypt(b []byte) {
+	for i, e := range b {
+		d := c.keyStream() ^ e
+		c.state[260] = e
+		c.state[259] = d
+		b[i] = d
+	}
+}
+
+// init Initializes the permutation from the mixed challenge (func4).
+func (c *XCipher) init(id []byte) {
+	s := &c.state
+	for i := range 256 {
+		s[i] = byte(i)
+	}
+
+	var n1, n2 int
+	for i := 255; i >= 0; i-- {
+		j := c.index(i, id, &n1, &n2)
+		s[i], s[j] = s[j], s[i]
+	}
+
+	s[256] = s[1]
+	s[257] = s[3]
+	s[258] = s[5]
+	s[259] = s[7]
+	s[260] = s[n1&0xFF]
+}
+
+// index Returns a pseudo-random index in [0, limit] used to shuffle the permutation (func5).
+func (c *XCipher) index(limit int, id []byte, n1, n2 *int) int {
+	if limit == 0 {
+		return 0
+	}
+
+	mask := 1
+	for mask < limit {
+		mask = mask<<1 + 1
+	}
+
+	var j int
+	for i := 1; ; i++ {
+		*n1 = int(c.state[*n1&0xFF]) + int(id[*n2])
+		*n2++
+		if *n2 >= len(id) {
+			*n2 = 0
+			*n1 += len(id)
+		}
+
+		j = *n1 & mask
+		if i > 11 {
+			j %= limit
+		}
+		if j <= limit {
+			return j
+		}
+	}
+}
+
+// keyStream Advances the cipher state and returns the next key byte (func7). Callers must store the cipher text and
+// plain text byte in state[260] and state[259] respectively.
+func (c *XCipher) keyStream() byte {
+	k := &c.state
+
+	// Follows the reference implementation step by step, since the order of reads and writes matters
+	a, b := k[256], k[257]
+	x := k[a]
+	k[256] = a + 1
+	k[257] = b + x
+
+	a = k[260]
+	b = k[k[257]]
+	x = k[a]
+	k[a] = b
+
+	a = k[k[259]]
+	k[k[257]] = a
+
+	a = k[k[256]]
+	k[k[259]] = a
+
+	k[k[256]] = x
+
+	b = k[258] + k[x]
+	k[258] = b
+
+	a = k[b] + k[k[256]]
+	b = k[k[k[259]]+k[k[257]]+k[k[260]]]
+
+	return k[b] ^ k[a]
+}
+
+// AppendXHeader Appends the response header carrying the server challenge to dst. Clients need the server challenge to
+// initialize their cipher.
+func AppendXHeader(dst []byte, serverChallenge []byte) ([]byte, error) {
+	if len(serverChallenge) > 0xFF {
+		return nil, fmt.Errorf("server challenge too long: %d bytes", len(serverChallenge))
+	}
+
+	// No padding between the first byte and the challenge length
+	dst = append(dst, 0^headerPaddingMask, byte(len(serverChallenge))^headerChallengeMask)
+	return append(dst, serverChallenge...), nil
+}
+
+// ParseXHeader Returns the server challenge contained in a response header and the length of the header.
+func ParseXHeader(b []byte) ([]byte, int, error) {
+	if len(b) < 1 {
+		return nil, 0, ErrShortHeader
+	}
+
+	n := int(b[0]^headerPaddingMask) + 2
+	if len(b) < n {
+		return nil, 0, ErrShortHeader
+	}
+
+	challengeLength := int(b[n-1] ^ headerChallengeMask)
+	if len(b) < n+challengeLength {
+		return nil, 0, ErrShortHeader
+	}
+
+	return b[n : n+challengeLength], n + challengeLength, nil
+}
@@ -0,0 +1,107 @@
+package enctype
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testClientChallenge = [ClientChallengeLength]byte{'a', 'b', 'c', 'd', 'e', 'f', 'g', 'h'}
+
+func TestXCipher(t *testing.T) {
+	t.Run("encrypts known value", func(t *testing.T) {
+		// GIVEN
+		// Regression value generated with this implementation, not taken from a GameSpy capture
+		c, err := NewXCipher("hW6m9a", testClientChallenge, []byte("0123456789abcd"))
+		require.NoError(t, err)
+		b := []byte("\x7f\x00\x00\x01\x19\x64some plain text")
+
+		// WHEN
+		c.Encrypt(b)
+
+		// THEN
+		assert.Equal(t, "284e8d798055f1e34a4c224cc71a2f2d9db35010a6", hex.EncodeToString(b))
+	})
+
+	t.Run("decrypts encrypted stream", func(t *testing.T) {
+		// GIVEN
+		plain := bytes.Repeat([]byte("\x00\xff some server list data"), 100)
+		encrypter, err := NewXCipher("hW6m9a", testClientChallenge, []byte("0123456789abcd"))
+		require.NoError(t, err)
+		decrypter, err := NewXCipher("hW6m9a", testClientChallenge, []byte("0123456789abcd"))
+		require.NoError(t, err)
+
+		// WHEN
+		b := bytes.Clone(plain)
+		// Encrypt in chunks, since state must carry over between calls
+		encrypter.Encrypt(b[:10])
+		encrypter.Encrypt(b[10:])
+		encrypted := bytes.Clone(b)
+		decrypter.Decrypt(b)
+
+		// THEN
+		assert.NotEqual(t, plain, encrypted)
+		assert.Equal(t, plain, b)
+	})
+
+	t.Run("does not decrypt with wrong key", func(t *testing.T) {
+		// GIVEN
+		plain := []byte("some server list data")
+		encrypter, err := NewXCipher("hW6m9a", testClientChallenge, []byte("0123456789abcd"))
+		require.NoError(t, err)
+		decrypter, err := NewXCipher("other", testClientChallenge, []byte("0123456789abcd"))
+		require.NoError(t, err)
+
+		// WHEN
+		b := bytes.Clone(plain)
+		encrypter.Encrypt(b)
+		decrypter.Decrypt(b)
+
+		// THEN
+		assert.NotEqual(t, plain, b)
+	})
+
+	t.Run("fails for empty game key", func(t *testing.T) {
+		// WHEN
+		_, err := NewXCipher("", testClientChallenge, nil)
+
+		// THEN
+		assert.ErrorIs(t, err, ErrEmptyGameKey)
+	})
+}
+
+func TestXHeader(t *testing.T) {
+	t.Run("round trips server challenge", func(t *testing.T) {
+		// WHEN
+		header, err := AppendXHeader([]byte("prefix"), []byte("0123456789abcd"))
+		require.NoError(t, err)
+		challenge, n, err2 := ParseXHeader(append(header[len("prefix"):], "data"...))
+
+		// THEN
+		require.NoError(t, err2)
+		assert.Equal(t, []byte{0xEC, 14 ^ 0xEA}, header[len("prefix"):len("prefix")+2])
+		assert.Equal(t, []byte("0123456789abcd"), challenge)
+		assert.Equal(t, 16, n)
+	})
+
+	t.Run("parses header with padding", func(t *testing.T) {
+		// WHEN
+		challenge, n, err := ParseXHeader([]byte{2 ^ 0xEC, 'x', 'x', 3 ^ 0xEA, 'a', 'b', 'c', 'd'})
+
+		// THEN
+		require.NoError(t, err)
+		assert.Equal(t, []byte("abc"), challenge)
+		assert.Equal(t, 7, n)
+	})
+
+	t.Run("fails for truncated header", func(t *testing.T) {
+		// WHEN
+		_, _, err := ParseXHeader([]byte{0xEC, 14 ^ 0xEA, 'a'})
+
+		// THEN
+		assert.ErrorIs(t, err, ErrShortHeader)
+	})
+}
@@ -0,0 +1,288 @@
+package gamespy
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// ErrorCode A GameSpy Presence (GP) error code. Codes are grouped in ranges of 0x100 by the request they relate to,
+// the first code of each range being the generic error for the request.
+// Following https://github.com/openspy/openspy-core/blob/5993df54c6b289361228920fa0db7209aed4cfe5/code/SharedTasks/src/OS/GPShared.h
+type ErrorCode int
+
+const (
+	// General
+	ErrorCodeGeneral          ErrorCode = 0x0000
+	ErrorCodeParse            ErrorCode = 0x0001
+	ErrorCodeNotLoggedIn      ErrorCode = 0x0002
+	ErrorCodeBadSessionKey    ErrorCode = 0x0003
+	ErrorCodeDatabase         ErrorCode = 0x0004
+	ErrorCodeNetwork          ErrorCode = 0x0005
+	ErrorCodeForcedDisconnect ErrorCode = 0x0006
+	ErrorCodeConnectionClosed ErrorCode = 0x0007
+	ErrorCodeUDPLayer         ErrorCode = 0x0008
+
+	// Login
+	ErrorCodeLogin                   ErrorCode = 0x0100
+	ErrorCodeLoginTimeout            ErrorCode = 0x0101
+	ErrorCodeLoginBadNick            ErrorCode = 0x0102
+	ErrorCodeLoginBadEmail           ErrorCode = 0x0103
+	ErrorCodeLoginBadPassword        ErrorCode = 0x0104
+	ErrorCodeLoginBadProfile         ErrorCode = 0x0105
+	ErrorCodeLoginProfileDeleted     ErrorCode = 0x0106
+	ErrorCodeLoginConnectionFailed   ErrorCode = 0x0107
+	ErrorCodeLoginServerAuthFailed   ErrorCode = 0x0108
+	ErrorCodeLoginBadUniqueNick      ErrorCode = 0x0109
+	ErrorCodeLoginNoUniqueNick       ErrorCode = 0x010A
+	ErrorCodeLoginBadPreAuth         ErrorCode = 0x010B
+	ErrorCodeLoginBadLoginTicket     ErrorCode = 0x010C
+	ErrorCodeLoginExpiredLoginTicket ErrorCode = 0x010D
+
+	// New user
+	ErrorCodeNewUser                  ErrorCode = 0x0200
+	ErrorCodeNewUserBadNick           ErrorCode = 0x0201
+	ErrorCodeNewUserBadPassword       ErrorCode = 0x0202
+	ErrorCodeNewUserUniqueNickInvalid ErrorCode = 0x0203
+	ErrorCodeNewUserUniqueNickInUse   ErrorCode = 0x0204
+
+	// Update user info
+	ErrorCodeUpdateUserInfo         ErrorCode = 0x0300
+	ErrorCodeUpdateUserInfoBadEmail ErrorCode = 0x0301
+
+	// New profile
+	ErrorCodeNewProfile           ErrorCode = 0x0400
+	ErrorCodeNewProfileBadNick    ErrorCode = 0x0401
+	ErrorCodeNewProfileBadOldNick ErrorCode = 0x0402
+
+	// Update profile
+	ErrorCodeUpdateProfile        ErrorCode = 0x0500
+	ErrorCodeUpdateProfileBadNick ErrorCode = 0x0501
+
+	// Add buddy
+	ErrorCodeAddBuddy             ErrorCode = 0x0600
+	ErrorCodeAddBuddyBadFrom      ErrorCode = 0x0601
+	ErrorCodeAddBuddyBadNew       ErrorCode = 0x0602
+	ErrorCodeAddBuddyAlreadyBuddy ErrorCode = 0x0603
+
+	// Authorize buddy request
+	ErrorCodeAuthAdd        ErrorCode = 0x0700
+	ErrorCodeAuthAddBadFrom ErrorCode = 0x0701
+	ErrorCodeAuthAddBadSig  ErrorCode = 0x0702
+
+	// Status
+	ErrorCodeStatus ErrorCode = 0x0800
+
+	// Buddy message
+	ErrorCodeBuddyMessage                    ErrorCode = 0x0900
+	ErrorCodeBuddyMessageNotBuddy            ErrorCode = 0x0901
+	ErrorCodeBuddyMessageExtInfoNotSupported ErrorCode = 0x0902
+	ErrorCodeBuddyMessageBuddyOffline        ErrorCode = 0x0903
+
+	// Get profile
+	ErrorCodeGetProfile           ErrorCode = 0x0A00
+	ErrorCodeGetProfileBadProfile ErrorCode = 0x0A01
+
+	// Delete buddy
+	ErrorCodeDeleteBuddy         ErrorCode = 0x0B00
+	ErrorCodeDeleteBuddyNotBuddy ErrorCode = 0x0B01
+
+	// Delete profile
+	ErrorCodeDeleteProfile            ErrorCode = 0x0C00
+	ErrorCodeDeleteProfileLastProfile ErrorCode = 0x0C01
+
+	// Search
+	ErrorCodeSearch                 ErrorCode = 0x0D00
+	ErrorCodeSearchConnectionFailed ErrorCode = 0x0D01
+	ErrorCodeSearchTimedOut         ErrorCode = 0x0D02
+
+	// Check user
+	ErrorCodeCheck            ErrorCode = 0x0E00
+	ErrorCodeCheckBadEmail    ErrorCode = 0x0E01
+	ErrorCodeCheckBadNick     ErrorCode = 0x0E02
+	ErrorCodeCheckBadPassword ErrorCode = 0x0E03
+
+	// Revoke buddy
+	ErrorCodeRevoke         ErrorCode = 0x0F00
+	ErrorCodeRevokeNotBuddy ErrorCode = 0x0F01
+
+	// Register unique nick
+	ErrorCodeRegisterUniqueNick             ErrorCode = 0x1000
+	ErrorCodeRegisterUniqueNickTaken        ErrorCode = 0x1001
+	ErrorCodeRegisterUniqueNickReserved     ErrorCode = 0x1002
+	ErrorCodeRegisterUniqueNickBadNamespace ErrorCode = 0x1003
+
+	// Register CD key
+	ErrorCodeRegisterCDKey             ErrorCode = 0x1100
+	ErrorCodeRegisterCDKeyBadKey       ErrorCode = 0x1101
+	ErrorCodeRegisterCDKeyAlreadySet   ErrorCode = 0x1102
+	ErrorCodeRegisterCDKeyAlreadyTaken ErrorCode = 0x1103
+
+	// Add block
+	ErrorCodeAddBlock               ErrorCode = 0x1200
+	ErrorCodeAddBlockAlreadyBlocked ErrorCode = 0x1201
+
+	// Remove block
+	ErrorCodeRemoveBlock           ErrorCode = 0x1300
+	ErrorCodeRemoveBlockNotBlocked ErrorCode = 0x1301
+)
+
+type errorDefaults struct {
+	fatal   bool
+	message string
+}
+
+// errorCatalog Contains the fatal flag and default message of every known error code. Fatal errors end the session,
+// so the connection is closed after sending them. Login errors are always fatal, since there is no session yet.
+var errorCatalog = map[ErrorCode]errorDefaults{
+	ErrorCodeGeneral:          {true, "There was an unknown error."},
+	ErrorCodeParse:            {true, "Unexpected data was received from the client."},
+	ErrorCodeNotLoggedIn:      {true, "This request cannot be processed because you are not logged in."},
+	ErrorCodeBadSessionKey:    {true, "This request cannot be processed because the session key is invalid."},
+	ErrorCodeDatabase:         {true, "There was a database error."},
+	ErrorCodeNetwork:          {true, "There was an error connecting a network socket."},
+	ErrorCodeForcedDisconnect: {true, "This profile has been disconnected by another login."},
+	ErrorCodeConnectionClosed: {true, "The server has closed the connection."},
+	ErrorCodeUDPLayer:         {true, "There was a problem with the UDP layer."},
+
+	ErrorCodeLogin:                   {true, "There was an error logging in to the GP backend."},
+	ErrorCodeLoginTimeout:            {true, "The login attempt timed out."},
+	ErrorCodeLoginBadNick:            {true, "The nickname provided was incorrect."},
+	ErrorCodeLoginBadEmail:           {true, "The e-mail address provided was incorrect."},
+	ErrorCodeLoginBadPassword:        {true, "The password provided was incorrect."},
+	ErrorCodeLoginBadProfile:         {true, "The profile provided was incorrect."},
+	ErrorCodeLoginProfileDeleted:     {true, "The profile has been deleted."},
+	ErrorCodeLoginConnectionFailed:   {true, "The server has refused the connection."},
+	ErrorCodeLoginServerAuthFailed:   {true, "The server could not be authenticated."},
+	ErrorCodeLoginBadUniqueNick:      {true, "The unique nickname provided was incorrect."},
+	ErrorCodeLoginNoUniqueNick:       {true, "The profile does not have a unique nickname."},
+	ErrorCodeLoginBadPreAuth:         {true, "The authentication token provided was incorrect."},
+	ErrorCodeLoginBadLoginTicket:     {true, "The login ticket provided was incorrect."},
+	ErrorCodeLoginExpiredLoginTicket: {true, "The login ticket has expired."},
+
+	ErrorCodeNewUser:                  {false, "There was an error creating the user."},
+	ErrorCodeNewUserBadNick:           {false, "A profile with that nickname already exists."},
+	ErrorCodeNewUserBadPassword:       {false, "The password does not match the e-mail address."},
+	ErrorCodeNewUserUniqueNickInvalid: {false, "The unique nickname is invalid."},
+	ErrorCodeNewUserUniqueNickInUse:   {false, "The unique nickname is already in use."},
+
+	ErrorCodeUpdateUserInfo:         {false, "There was an error updating the user information."},
+	ErrorCodeUpdateUserInfoBadEmail: {false, "A user with that e-mail address already exists."},
+
+	ErrorCodeNewProfile:           {false, "There was an error creating the profile."},
+	ErrorCodeNewProfileBadNick:    {false, "The nickname to be replaced does not exist."},
+	ErrorCodeNewProfileBadOldNick: {false, "A profile with the nickname to replace with already exists."},
+
+	ErrorCodeUpdateProfile:        {false, "There was an error updating the profile."},
+	ErrorCodeUpdateProfileBadNick: {false, "A user with that nickname already exists."},
+
+	ErrorCodeAddBuddy:             {false, "There was an error adding the buddy."},
+	ErrorCodeAddBuddyBadFrom:      {false, "The profile requesting to add a buddy is invalid."},
+	ErrorCodeAddBuddyBadNew:       {false, "The profile requested to be added as a buddy is invalid."},
+	ErrorCodeAddBuddyAlreadyBuddy: {false, "The profile is already a buddy."},
+
+	ErrorCodeAuthAdd:        {false, "There was an error authorizing the buddy request."},
+	ErrorCodeAuthAddBadFrom: {false, "The profile being authorized is invalid."},
+	ErrorCodeAuthAddBadSig:  {false, "The signature for the authorization is invalid."},
+
+	ErrorCodeStatus: {false, "There was an error updating the status."},
+
+	ErrorCodeBuddyMessage:                    {false, "There was an error sending the buddy message."},
+	ErrorCodeBuddyMessageNotBuddy:            {false, "The profile the message was sent to is not a buddy."},
+	ErrorCodeBuddyMessageExtInfoNotSupported: {false, "The buddy does not support extended info keys."},
+	ErrorCodeBuddyMessageBuddyOffline:        {false, "The buddy is offline."},
+
+	ErrorCodeGetProfile:           {false, "There was an error getting the profile."},
+	ErrorCodeGetProfileBadProfile: {false, "Unable to get profile."},
+
+	ErrorCodeDeleteBuddy:         {false, "There was an error deleting the buddy."},
+	ErrorCodeDeleteBuddyNotBuddy: {false, "The buddy to be deleted is not a buddy."},
+
+	ErrorCodeDeleteProfile:            {false, "There was an error deleting the profile."},
+	ErrorCodeDeleteProfileLastProfile: {false, "The last profile cannot be deleted."},
+
+	ErrorCodeSearch:                 {false, "There was an error searching for a profile."},
+	ErrorCodeSearchConnectionFailed: {false, "The search attempt failed to connect to the server."},
+	ErrorCodeSearchTimedOut:         {false, "The search did not return in a timely fashion."},
+
+	ErrorCodeCheck:            {false, "There was an error checking the user account."},
+	ErrorCodeCheckBadEmail:    {false, "No account exists with the provided e-mail address."},
+	ErrorCodeCheckBadNick:     {false, "No such profile exists for the provided e-mail address."},
+	ErrorCodeCheckBadPassword: {false, "The password is incorrect."},
+
+	ErrorCodeRevoke:         {false, "There was an error revoking the buddy."},
+	ErrorCodeRevokeNotBuddy: {false, "You are not a buddy of the profile."},
+
+	ErrorCodeRegisterUniqueNick:             {false, "There was an error registering the unique nickname."},
+	ErrorCodeRegisterUniqueNickTaken:        {false, "The unique nickname is already taken."},
+	ErrorCodeRegisterUniqueNickReserved:     {false, "The unique nickname is reserved."},
+	ErrorCodeRegisterUniqueNickBadNamespace: {false, "Tried to register a unique nickname in an invalid namespace."},
+
+	ErrorCodeRegisterCDKey:             {false, "There was an error registering the CD key."},
+	ErrorCodeRegisterCDKeyBadKey:       {false, "The CD key is invalid."},
+	ErrorCodeRegisterCDKeyAlreadySet:   {false, "The profile has already been registered with a different CD key."},
+	ErrorCodeRegisterCDKeyAlreadyTaken: {false, "The CD key has already been registered to another profile."},
+
+	ErrorCodeAddBlock:               {false, "There was an error adding the player to the blocked list."},
+	ErrorCodeAddBlockAlreadyBlocked: {false, "The profile is already blocked."},
+
+	ErrorCodeRemoveBlock:           {false, "There was an error removing the player from the blocked list."},
+	ErrorCodeRemoveBlockNotBlocked: {false, "The profile is not blocked."},
+}
+
+// defaults Returns the fatal flag and default message of the code. Unknown codes inherit the defaults of the generic
+// error of their range.
+func (c ErrorCode) defaults() errorDefaults {
+	if d, ok := errorCatalog[c]; ok {
+		return d
+	}
+	if d, ok := errorCatalog[c&^0xFF]; ok {
+		return d
+	}
+	return errorCatalog[ErrorCodeGeneral]
+}
+
+// Fatal Checks whether the code ends the session.
+func (c ErrorCode) Fatal() bool {
+	return c.defaults().fatal
+}
+
+// Message Returns the default message of the code.
+func (c ErrorCode) Message() string {
+	return c.defaults().message
+}
+
+// Error A GP error as sent to clients in \error\ responses.
+type Error struct {
+	Code    ErrorCode
+	Fatal   bool
+	Message string
+}
+
+// NewError Returns an error with the code's fatal flag and default message.
+func NewError(code ErrorCode) *Error {
+	d := code.defaults()
+	return &Error{
+		Code:    code,
+		Fatal:   d.fatal,
+		Message: d.message,
+	}
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("gamespy error %d: %s", e.Code, e.Message)
+}
+
+// Packet Returns the error response to the request with the given id. The fatal key is only included for fatal
+// errors.
+func (e *Error) Packet(id int) *Packet {
+	packet := NewPacket(
+		KeyValuePair{Key: "error", Value: ""},
+		KeyValuePair{Key: "err", Value: strconv.Itoa(int(e.Code))},
+	)
+	if e.Fatal {
+		packet.Add("fatal", "")
+	}
+	packet.Add("errmsg", e.Message)
+	packet.AddInt("id", id)
+	return packet
+}
@@ -0,0 +1,111 @@
+package gamespy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewError(t *testing.T) {
+	type test struct {
+		name          string
+		givenCode     ErrorCode
+		expectedError *Error
+	}
+
+	tests := []test{
+		{
+			name:      "returns defaults of fatal error",
+			givenCode: ErrorCodeLoginBadPassword,
+			expectedError: &Error{
+				Code:    ErrorCodeLoginBadPassword,
+				Fatal:   true,
+				Message: "The password provided was incorrect.",
+			},
+		},
+		{
+			name:      "returns defaults of non-fatal error",
+			givenCode: ErrorCodeGetProfileBadProfile,
+			expectedError: &Error{
+				Code:    ErrorCodeGetProfileBadProfile,
+				Fatal:   false,
+				Message: "Unable to get profile.",
+			},
+		},
+		{
+			name:      "returns defaults of range for unknown code",
+			givenCode: 0x0A42,
+			expectedError: &Error{
+				Code:    0x0A42,
+				Fatal:   false,
+				Message: "There was an error getting the profile.",
+			},
+		},
+		{
+			name:      "returns general defaults for unknown range",
+			givenCode: 0x4200,
+			expectedError: &Error{
+				Code:    0x4200,
+				Fatal:   true,
+				Message: "There was an unknown error.",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// WHEN
+			err := NewError(tt.givenCode)
+
+			// THEN
+			assert.Equal(t, tt.expectedError, err)
+			assert.Equal(t, tt.expectedError.Fatal, tt.givenCode.Fatal())
+			assert.Equal(t, tt.expectedError.Message, tt.givenCode.Message())
+		})
+	}
+}
+
+func TestError_Packet(t *testing.T) {
+	type test struct {
+		name           string
+		givenError     *Error
+		givenID        int
+		expectedPacket string
+	}
+
+	tests := []test{
+		{
+			name:           "includes fatal key for fatal error",
+			givenError:     NewError(ErrorCodeLogin),
+			givenID:        1,
+			expectedPacket: "\\error\\\\err\\256\\fatal\\\\errmsg\\There was an error logging in to the GP backend.\\id\\1\\final\\",
+		},
+		{
+			name:           "omits fatal key for non-fatal error",
+			givenError:     &Error{Code: ErrorCodeGetProfileBadProfile, Message: "some-message"},
+			givenID:        2,
+			expectedPacket: "\\error\\\\err\\2561\\errmsg\\some-message\\id\\2\\final\\",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// WHEN
+			packet := tt.givenError.Packet(tt.givenID)
+
+			// THEN
+			assert.Equal(t, tt.expectedPacket, packet.String())
+		})
+	}
+}
+
+func TestError_Error(t *testing.T) {
+	// GIVEN
+	err := NewError(ErrorCodeLoginBadNick)
+
+	// WHEN
+	s := err.Error()
+
+	// THEN
+	assert.Equal(t, "gamespy error 258: The nickname provided was incorrect.", s)
+}
@@ -0,0 +1,236 @@
+// Package gpclient implements the client side of the GameSpy Presence (GP) login handshake.
+package gpclient
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/dogclan/dumbspy/pkg/gamespy"
+)
+
+const (
+	network = "tcp4"
+
+	clientChallengeLength = 32
+)
+
+var ErrInvalidProof = errors.New("server proof does not match")
+
+// LoginError Is returned if the server responds to the login request with an error.
+type LoginError struct {
+	Code    int
+	Fatal   bool
+	Message string
+}
+
+func (e *LoginError) Error() string {
+	return fmt.Sprintf("login failed with error %d: %s", e.Code, e.Message)
+}
+
+type Config struct {
+	UniqueNick  string
+	Password    string
+	ProductID   int
+	GameName    string
+	NamespaceID int
+	SDKRevision int
+	Port        int // Local game port reported to the server
+
+	// InsecureSkipProofCheck Skips verifying the server's proof of knowing the password. Servers which do not know the
+	// password (such as dumbspy accepting any login) cannot generate a valid proof.
+	InsecureSkipProofCheck bool
+}
+
+type LoginResult struct {
+	SessionKey  int    `gamespy:"sesskey"`
+	Proof       string `gamespy:"proof"`
+	UserID      int    `gamespy:"userid"`
+	ProfileID   int    `gamespy:"profileid"`
+	UniqueNick  string `gamespy:"uniquenick"`
+	LoginTicket string `gamespy:"lt"`
+}
+
+type challengePrompt struct {
+	LoginCode int    `gamespy:"lc"`
+	Challenge string `gamespy:"challenge"`
+}
+
+type errorResponse struct {
+	Code    int     `gamespy:"err"`
+	Fatal   *string `gamespy:"fatal"`
+	Message string  `gamespy:"errmsg"`
+}
+
+type loginRequest struct {
+	Login       *string `gamespy:"login"`
+	Challenge   string  `gamespy:"challenge"`
+	UniqueNick  string  `gamespy:"uniquenick"`
+	Response    string  `gamespy:"response"`
+	Port        int     `gamespy:"port"`
+	ProductID   int     `gamespy:"productid"`
+	GameName    string  `gamespy:"gamename"`
+	NamespaceID int     `gamespy:"namespaceid"`
+	SDKRevision int     `gamespy:"sdkrevision"`
+	ID          int     `gamespy:"id"`
+}
+
+type Client struct {
+	conn   net.Conn
+	reader *gamespy.Reader
+	writer *gamespy.Writer
+}
+
+// Dial Connects to the GP server at address.
+func Dial(ctx context.Context, address string) (*Client, error) {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, network, address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect: %w", err)
+	}
+	return NewClient(conn), nil
+}
+
+// NewClient Returns a client using an existing connection to a GP server.
+func NewClient(conn net.Conn) *Client {
+	return &Client{
+		conn:   conn,
+		reader: gamespy.NewReader(conn),
+		writer: gamespy.NewWriter(conn),
+	}
+}
+
+// Login Performs the login handshake. Must be called once, right after connecting.
+func (c *Client) Login(ctx context.Context, config Config) (*LoginResult, error) {
+	stop := c.bind(ctx)
+	defer stop()
+
+	packet, err := c.reader.ReadPacket()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read challenge prompt: %w", err)
+	}
+
+	var prompt challengePrompt
+	if err = packet.Bind(&prompt); err != nil {
+		return nil, fmt.Errorf("failed to parse challenge prompt: %w", err)
+	}
+	if prompt.LoginCode != 1 || prompt.Challenge == "" {
+		return nil, fmt.Errorf("unexpected challenge prompt: %s", packet.String())
+	}
+
+	passwordHash := gamespy.ComputeMD5(config.Password)
+	clientChallenge := gamespy.RandString(clientChallengeLength)
+	req, err := gamespy.Marshal(loginRequest{
+		Login:       new(string),
+		Challenge:   clientChallenge,
+		UniqueNick:  config.UniqueNick,
+		Response:    gamespy.GenerateProof(config.UniqueNick, passwordHash, clientChallenge, prompt.Challenge),
+		Port:        config.Port,
+		ProductID:   config.ProductID,
+		GameName:    config.GameName,
+		NamespaceID: config.NamespaceID,
+		SDKRevision: config.SDKRevision,
+		ID:          1,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to build login request: %w", err)
+	}
+
+	if err = c.writer.WritePacket(req); err != nil {
+		return nil, fmt.Errorf("failed to send login request: %w", err)
+	}
+
+	packet, err = c.reader.ReadPacket()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read login response: %w", err)
+	}
+
+	if _, ok := packet.Lookup("error"); ok {
+		var res errorResponse
+		if err = packet.Bind(&res); err != nil {
+			return nil, fmt.Errorf("failed to parse error response: %w", err)
+		}
+		return nil, &LoginError{
+			Code:    res.Code,
+			Fatal:   res.Fatal != nil,
+			Message: res.Message,
+		}
+	}
+
+	if packet.Get("lc") != "2" {
+		return nil, fmt.Errorf("unexpected login response: %s", packet.String())
+	}
+
+	result := new(LoginResult)
+	if err = packet.Bind(result); err != nil {
+		return nil, fmt.Errorf("failed to parse login response: %w", err)
+	}
+
+	if !config.InsecureSkipProofCheck {
+		expected := gamespy.GenerateProof(config.UniqueNick, passwordHash, prompt.Challenge, clientChallenge)
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(result.Proof)) != 1 {
+			return nil, ErrInvalidProof
+		}
+	}
+
+	return result, nil
+}
+
+// Logout Notifies the server that the session is ending.
+func (c *Client) Logout(ctx context.Context, sessionKey int) error {
+	stop := c.bind(ctx)
+	defer stop()
+
+	packet := gamespy.NewPacket(gamespy.KeyValuePair{Key: "logout"})
+	packet.AddInt("sesskey", sessionKey)
+	if err := c.writer.WritePacket(packet); err != nil {
+		return fmt.Errorf("failed to send logout: %w", err)
+	}
+	return nil
+}
+
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+// bind Applies the deadline of ctx to the connection and interrupts any pending reads/writes if ctx is cancelled.
+// The returned function must be called once the operation is done.
+func (c *Client) bind(ctx context.Context) func() {
+	deadline, _ := ctx.Deadline()
+	_ = c.conn.SetDeadline(deadline)
+
+	stop := context.AfterFunc(ctx, func() {
+		_ = c.conn.SetDeadline(time.Now())
+	})
+
+	return func() {
+		stop()
+		_ = c.conn.SetDeadline(time.Time{})
+	}
+}
+
+// Login Connects to the GP server at address, performs the login handshake and logs out again.
+// Useful to check whether a server is accepting logins.
+func Login(ctx context.Context, address string, config Config) (*LoginResult, error) {
+	client, err := Dial(ctx, address)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = client.Close()
+	}()
+
+	result, err := client.Login(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = client.Logout(ctx, result.SessionKey); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
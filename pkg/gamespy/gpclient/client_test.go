@@ -0,0 +1,230 @@
+package gpclient
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dogclan/dumbspy/pkg/gamespy"
+)
+
+const (
+	testNick      = "mister249"
+	testPassword  = "secret"
+	testChallenge = "ABCDEFGHIJ"
+)
+
+// fakeServer Plays the server side of the login handshake on conn, responding with the packet returned by respond.
+func fakeServer(t *testing.T, conn net.Conn, respond func(login *gamespy.Packet) *gamespy.Packet) <-chan *gamespy.Packet {
+	t.Helper()
+	received := make(chan *gamespy.Packet, 1)
+	go func() {
+		defer close(received)
+		reader := gamespy.NewReader(conn)
+		writer := gamespy.NewWriter(conn)
+
+		prompt := gamespy.NewPacket(
+			gamespy.KeyValuePair{Key: "lc", Value: "1"},
+			gamespy.KeyValuePair{Key: "challenge", Value: testChallenge},
+			gamespy.KeyValuePair{Key: "id", Value: "1"},
+		)
+		if err := writer.WritePacket(prompt); err != nil {
+			return
+		}
+
+		login, err := reader.ReadPacket()
+		if err != nil {
+			return
+		}
+		received <- login
+
+		_ = writer.WritePacket(respond(login))
+	}()
+	return received
+}
+
+func loginResponse(proof string) *gamespy.Packet {
+	return gamespy.NewPacket(
+		gamespy.KeyValuePair{Key: "lc", Value: "2"},
+		gamespy.KeyValuePair{Key: "sesskey", Value: "12345"},
+		gamespy.KeyValuePair{Key: "proof", Value: proof},
+		gamespy.KeyValuePair{Key: "userid", Value: "500000001"},
+		gamespy.KeyValuePair{Key: "profileid", Value: "500000001"},
+		gamespy.KeyValuePair{Key: "uniquenick", Value: testNick},
+		gamespy.KeyValuePair{Key: "lt", Value: "ticket__"},
+		gamespy.KeyValuePair{Key: "id", Value: "1"},
+	)
+}
+
+// validProof Returns the proof a server knowing the password would generate for login.
+func validProof(login *gamespy.Packet) string {
+	return gamespy.GenerateProof(testNick, gamespy.ComputeMD5(testPassword), testChallenge, login.Get("challenge"))
+}
+
+func TestClient_Login(t *testing.T) {
+	type test struct {
+		name                 string
+		givenConfig          Config
+		givenRespond         func(login *gamespy.Packet) *gamespy.Packet
+		expectedResult       *LoginResult
+		wantErrContains      string
+		wantErrIs            error
+		wantLoginError       *LoginError
+		expectedLoginRequest map[string]string
+	}
+
+	config := Config{
+		UniqueNick:  testNick,
+		Password:    testPassword,
+		ProductID:   10493,
+		GameName:    "battlefield2",
+		NamespaceID: 12,
+		SDKRevision: 3,
+		Port:        29900,
+	}
+
+	tests := []test{
+		{
+			name:         "logs in with valid proof",
+			givenConfig:  config,
+			givenRespond: func(login *gamespy.Packet) *gamespy.Packet { return loginResponse(validProof(login)) },
+			expectedResult: &LoginResult{
+				SessionKey:  12345,
+				UserID:      500000001,
+				ProfileID:   500000001,
+				UniqueNick:  testNick,
+				LoginTicket: "ticket__",
+			},
+			expectedLoginRequest: map[string]string{
+				"uniquenick":  testNick,
+				"productid":   "10493",
+				"gamename":    "battlefield2",
+				"namespaceid": "12",
+				"sdkrevision": "3",
+				"port":        "29900",
+				"id":          "1",
+			},
+		},
+		{
+			name:         "fails for invalid proof",
+			givenConfig:  config,
+			givenRespond: func(login *gamespy.Packet) *gamespy.Packet { return loginResponse("invalid") },
+			wantErrIs:    ErrInvalidProof,
+		},
+		{
+			name: "logs in with invalid proof if proof check is skipped",
+			givenConfig: func() Config {
+				c := config
+				c.InsecureSkipProofCheck = true
+				return c
+			}(),
+			givenRespond: func(login *gamespy.Packet) *gamespy.Packet { return loginResponse("invalid") },
+			expectedResult: &LoginResult{
+				SessionKey:  12345,
+				UserID:      500000001,
+				ProfileID:   500000001,
+				UniqueNick:  testNick,
+				LoginTicket: "ticket__",
+			},
+		},
+		{
+			name:        "returns login error",
+			givenConfig: config,
+			givenRespond: func(login *gamespy.Packet) *gamespy.Packet {
+				return gamespy.NewPacket(
+					gamespy.KeyValuePair{Key: "error"},
+					gamespy.KeyValuePair{Key: "err", Value: "256"},
+					gamespy.KeyValuePair{Key: "fatal"},
+					gamespy.KeyValuePair{Key: "errmsg", Value: "There was an error logging in to the GP backend."},
+					gamespy.KeyValuePair{Key: "id", Value: "1"},
+				)
+			},
+			wantLoginError: &LoginError{
+				Code:    256,
+				Fatal:   true,
+				Message: "There was an error logging in to the GP backend.",
+			},
+		},
+		{
+			name:        "fails for unexpected response",
+			givenConfig: config,
+			givenRespond: func(login *gamespy.Packet) *gamespy.Packet {
+				return gamespy.NewPacket(gamespy.KeyValuePair{Key: "ka"})
+			},
+			wantErrContains: "unexpected login response",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// GIVEN
+			clientConn, serverConn := net.Pipe()
+			t.Cleanup(func() {
+				_ = clientConn.Close()
+				_ = serverConn.Close()
+			})
+			received := fakeServer(t, serverConn, tt.givenRespond)
+			client := NewClient(clientConn)
+
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+
+			// WHEN
+			result, err := client.Login(ctx, tt.givenConfig)
+
+			// THEN
+			login := <-received
+			require.NotNil(t, login)
+			assert.Len(t, login.Get("challenge"), clientChallengeLength)
+			assert.Equal(t, gamespy.GenerateProof(
+				testNick,
+				gamespy.ComputeMD5(testPassword),
+				login.Get("challenge"),
+				testChallenge,
+			), login.Get("response"))
+			for key, value := range tt.expectedLoginRequest {
+				assert.Equal(t, value, login.Get(key), key)
+			}
+
+			switch {
+			case tt.wantErrIs != nil:
+				assert.ErrorIs(t, err, tt.wantErrIs)
+			case tt.wantErrContains != "":
+				assert.ErrorContains(t, err, tt.wantErrContains)
+			case tt.wantLoginError != nil:
+				var loginErr *LoginError
+				require.ErrorAs(t, err, &loginErr)
+				assert.Equal(t, tt.wantLoginError, loginErr)
+			default:
+				require.NoError(t, err)
+				// Proof is random, since it depends on the client challenge
+				result.Proof = ""
+				assert.Equal(t, tt.expectedResult, result)
+			}
+		})
+	}
+}
+
+func TestClient_Login_ContextDone(t *testing.T) {
+	// GIVEN
+	clientConn, serverConn := net.Pipe()
+	t.Cleanup(func() {
+		_ = clientConn.Close()
+		_ = serverConn.Close()
+	})
+	client := NewClient(clientConn)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	// WHEN
+	// Server never sends a challenge prompt
+	_, err := client.Login(ctx, Config{UniqueNick: testNick})
+
+	// THEN
+	assert.ErrorContains(t, err, "failed to read challenge prompt")
+}
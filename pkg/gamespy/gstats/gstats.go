@@ -0,0 +1,88 @@
+// Package gstats Implements the framing of the GameSpy stats (gstats) protocol, which games use to authenticate
+// players and read/write persistent player data via TCP. Packets are XOR-encoded with a fixed key, except for the
+// \final\ terminator, which is sent in plain text.
+package gstats
+
+import (
+	"bytes"
+	"errors"
+	"io"
+
+	"github.com/dogclan/dumbspy/pkg/gamespy"
+)
+
+const (
+	xorKey = "GameSpy3D"
+
+	final = `\final\`
+	// dataKey is the key of player data, which may contain backslashes and is thus always the last key of a packet
+	dataKey = `\data\`
+)
+
+var ErrMissingTerminator = errors.New("packet is not terminated by final")
+
+// Decode Returns the packet contained in frame, which must include the plain \final\ terminator. Player data may
+// contain backslashes itself, so everything following \data\ is used as its value.
+func Decode(frame []byte) (*gamespy.Packet, error) {
+	if !bytes.HasSuffix(frame, []byte(final)) {
+		return nil, ErrMissingTerminator
+	}
+
+	b := bytes.Clone(frame[:len(frame)-len(final)])
+	gamespy.XOR(b, xorKey)
+
+	i := bytes.Index(b, []byte(dataKey))
+	if i == -1 {
+		return gamespy.NewPacketFromBytes(append(b, final...))
+	}
+
+	packet, err := gamespy.NewPacketFromBytes(append(bytes.Clone(b[:i]), final...))
+	if err != nil {
+		return nil, err
+	}
+	packet.Add("data", string(b[i+len(dataKey):]))
+	return packet, nil
+}
+
+// Encode Returns the encoded packet including the plain \final\ terminator.
+func Encode(packet *gamespy.Packet) []byte {
+	b := packet.Bytes()
+	gamespy.XOR(b[:len(b)-len(final)], xorKey)
+	return b
+}
+
+// Reader Reads encoded packets from a stream.
+type Reader struct {
+	r *gamespy.Reader
+}
+
+// NewReaderSize Returns a Reader which rejects packets larger than maxSize bytes (including the \final\ terminator).
+func NewReaderSize(r io.Reader, maxSize int) *Reader {
+	return &Reader{
+		r: gamespy.NewReaderSize(r, maxSize),
+	}
+}
+
+func (r *Reader) ReadPacket() (*gamespy.Packet, error) {
+	frame, err := r.r.ReadFrame()
+	if err != nil {
+		return nil, err
+	}
+	return Decode(frame)
+}
+
+// Writer Writes encoded packets to a stream.
+type Writer struct {
+	w io.Writer
+}
+
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{
+		w: w,
+	}
+}
+
+func (w *Writer) WritePacket(packet *gamespy.Packet) error {
+	_, err := w.w.Write(Encode(packet))
+	return err
+}
@@ -0,0 +1,118 @@
+package gstats
+
+import (
+	"bytes"
+	"io"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dogclan/dumbspy/pkg/gamespy"
+)
+
+func encode(plain string) []byte {
+	b := []byte(plain)
+	gamespy.XOR(b, "GameSpy3D")
+	return append(b, `\final\`...)
+}
+
+func TestDecode(t *testing.T) {
+	type test struct {
+		name            string
+		givenFrame      []byte
+		expectedPacket  *gamespy.Packet
+		wantErrContains string
+	}
+
+	tests := []test{
+		{
+			name:       "decodes packet",
+			givenFrame: encode(`\getpd\\pid\600000001\ptype\3\dindex\0\keys\\lid\1`),
+			expectedPacket: gamespy.NewPacket(
+				gamespy.KeyValuePair{Key: "getpd"},
+				gamespy.KeyValuePair{Key: "pid", Value: "600000001"},
+				gamespy.KeyValuePair{Key: "ptype", Value: "3"},
+				gamespy.KeyValuePair{Key: "dindex", Value: "0"},
+				gamespy.KeyValuePair{Key: "keys"},
+				gamespy.KeyValuePair{Key: "lid", Value: "1"},
+			),
+		},
+		{
+			name:       "decodes data containing backslashes",
+			givenFrame: encode(`\setpd\\pid\600000001\kv\1\lid\1\length\16\data\\score\10\kills\2`),
+			expectedPacket: gamespy.NewPacket(
+				gamespy.KeyValuePair{Key: "setpd"},
+				gamespy.KeyValuePair{Key: "pid", Value: "600000001"},
+				gamespy.KeyValuePair{Key: "kv", Value: "1"},
+				gamespy.KeyValuePair{Key: "lid", Value: "1"},
+				gamespy.KeyValuePair{Key: "length", Value: "16"},
+				gamespy.KeyValuePair{Key: "data", Value: `\score\10\kills\2`},
+			),
+		},
+		{
+			name:            "fails for frame without terminator",
+			givenFrame:      []byte("abc"),
+			wantErrContains: "packet is not terminated by final",
+		},
+		{
+			name:            "fails for malformed packet",
+			givenFrame:      encode(`auth`),
+			wantErrContains: "malformed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// WHEN
+			packet, err := Decode(tt.givenFrame)
+
+			// THEN
+			if tt.wantErrContains != "" {
+				require.ErrorContains(t, err, tt.wantErrContains)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, slices.Collect(tt.expectedPacket.All()), slices.Collect(packet.All()))
+			}
+		})
+	}
+}
+
+func TestEncode(t *testing.T) {
+	// GIVEN
+	packet := gamespy.NewPacket(
+		gamespy.KeyValuePair{Key: "lc", Value: "1"},
+		gamespy.KeyValuePair{Key: "challenge", Value: "abc"},
+	)
+
+	// WHEN
+	b := Encode(packet)
+
+	// THEN
+	assert.Equal(t, encode(`\lc\1\challenge\abc`), b)
+}
+
+func TestReaderWriter(t *testing.T) {
+	// GIVEN
+	buffer := new(bytes.Buffer)
+	writer := NewWriter(buffer)
+	reader := NewReaderSize(buffer, gamespy.DefaultMaxPacketSize)
+	packet := gamespy.NewPacket(
+		gamespy.KeyValuePair{Key: "authp"},
+		gamespy.KeyValuePair{Key: "pid", Value: "600000001"},
+	)
+
+	// WHEN
+	require.NoError(t, writer.WritePacket(packet))
+	require.NoError(t, writer.WritePacket(packet))
+
+	// THEN
+	for range 2 {
+		read, err := reader.ReadPacket()
+		require.NoError(t, err)
+		assert.Equal(t, slices.Collect(packet.All()), slices.Collect(read.All()))
+	}
+	_, err := reader.ReadPacket()
+	assert.ErrorIs(t, err, io.EOF)
+}
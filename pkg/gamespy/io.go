@@ -0,0 +1,133 @@
+package gamespy
+
+import (
+	"bytes"
+	"errors"
+	"io"
+)
+
+const (
+	DefaultMaxPacketSize = 4096
+
+	minReadSize = 512
+)
+
+var ErrPacketTooLarge = errors.New("gamespy packet exceeds maximum packet size")
+
+// Reader Reads packets from a stream, framing them on the \final\ terminator. Packets may be split across several
+// reads of the underlying io.Reader and a single read may contain several packets.
+type Reader struct {
+	r       io.Reader
+	buf     []byte
+	err     error
+	maxSize int
+}
+
+func NewReader(r io.Reader) *Reader {
+	return NewReaderSize(r, DefaultMaxPacketSize)
+}
+
+// NewReaderSize Returns a Reader which rejects packets larger than maxSize bytes (including the \final\ terminator).
+func NewReaderSize(r io.Reader, maxSize int) *Reader {
+	if maxSize < len(prefix)+len(suffix) {
+		maxSize = DefaultMaxPacketSize
+	}
+
+	return &Reader{
+		r:       r,
+		buf:     make([]byte, 0, min(minReadSize, maxSize)),
+		maxSize: maxSize,
+	}
+}
+
+// ReadPacket Reads until the next \final\ terminator and returns the packet preceding it.
+// Errors returned by the underlying io.Reader are returned as is once all complete packets have been read.
+// Since data read so far is retained, reading may be retried after an error such as a timeout.
+func (r *Reader) ReadPacket() (*Packet, error) {
+	end, err := r.nextFrame()
+	if err != nil {
+		return nil, err
+	}
+
+	// Parsing copies the frame, so it may reference the buffer
+	packet, err := NewPacketFromBytes(r.buf[:end])
+	r.discard(end)
+	return packet, err
+}
+
+// ReadFrame Reads until the next \final\ terminator and returns the raw data up to and including it. Allows reading
+// packets whose content is encoded, but which are still terminated by a plain \final\. Errors are returned like by
+// ReadPacket.
+func (r *Reader) ReadFrame() ([]byte, error) {
+	end, err := r.nextFrame()
+	if err != nil {
+		return nil, err
+	}
+
+	// Frame must not reference the buffer, so remaining data can be moved to the front
+	frame := bytes.Clone(r.buf[:end])
+	r.discard(end)
+	return frame, nil
+}
+
+// nextFrame Reads until the buffer contains a \final\ terminator. Returns the end of the frame within the buffer.
+func (r *Reader) nextFrame() (int, error) {
+	for {
+		if i := bytes.Index(r.buf, []byte(suffix)); i != -1 {
+			end := i + len(suffix)
+			if end > r.maxSize {
+				r.buf = r.buf[:0]
+				return 0, ErrPacketTooLarge
+			}
+			return end, nil
+		}
+
+		if r.err != nil {
+			err := r.err
+			r.err = nil
+			return 0, err
+		}
+
+		if len(r.buf) >= r.maxSize {
+			r.buf = r.buf[:0]
+			return 0, ErrPacketTooLarge
+		}
+
+		if len(r.buf) == cap(r.buf) {
+			grown := make([]byte, len(r.buf), min(cap(r.buf)*2, r.maxSize))
+			copy(grown, r.buf)
+			r.buf = grown
+		}
+
+		n, err := r.r.Read(r.buf[len(r.buf):cap(r.buf)])
+		r.buf = r.buf[:len(r.buf)+n]
+		if err != nil {
+			if n == 0 {
+				return 0, err
+			}
+			// Try to frame a packet from the data received along with the error before returning it
+			r.err = err
+		}
+	}
+}
+
+// discard Removes the first n bytes from the buffer, moving remaining data to the front.
+func (r *Reader) discard(n int) {
+	r.buf = r.buf[:copy(r.buf, r.buf[n:])]
+}
+
+// Writer Writes packets to a stream.
+type Writer struct {
+	w io.Writer
+}
+
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{
+		w: w,
+	}
+}
+
+func (w *Writer) WritePacket(packet *Packet) error {
+	_, err := packet.WriteTo(w.w)
+	return err
+}
@@ -0,0 +1,226 @@
+package gamespy
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReader_ReadPacket(t *testing.T) {
+	type test struct {
+		name            string
+		reader          io.Reader
+		maxSize         int
+		expectedPackets []*Packet
+		wantErr         error
+	}
+
+	tests := []test{
+		{
+			name:   "reads single packet",
+			reader: strings.NewReader("\\ka\\\\final\\"),
+			expectedPackets: []*Packet{
+				{elements: []KeyValuePair{{Key: "ka"}}},
+			},
+			wantErr: io.EOF,
+		},
+		{
+			name:   "reads multiple packets from a single read",
+			reader: strings.NewReader("\\ka\\\\final\\\\logout\\\\sesskey\\123\\final\\"),
+			expectedPackets: []*Packet{
+				{elements: []KeyValuePair{{Key: "ka"}}},
+				{elements: []KeyValuePair{{Key: "logout"}, {Key: "sesskey", Value: "123"}}},
+			},
+			wantErr: io.EOF,
+		},
+		{
+			name:   "reads packet split across reads",
+			reader: iotest.OneByteReader(strings.NewReader("\\status\\1\\sesskey\\123\\statstring\\Online\\final\\")),
+			expectedPackets: []*Packet{
+				{elements: []KeyValuePair{{Key: "status", Value: "1"}, {Key: "sesskey", Value: "123"}, {Key: "statstring", Value: "Online"}}},
+			},
+			wantErr: io.EOF,
+		},
+		{
+			name:   "reads packet larger than initial buffer",
+			reader: strings.NewReader("\\key\\" + strings.Repeat("a", 2*minReadSize) + "\\final\\"),
+			expectedPackets: []*Packet{
+				{elements: []KeyValuePair{{Key: "key", Value: strings.Repeat("a", 2*minReadSize)}}},
+			},
+			wantErr: io.EOF,
+		},
+		{
+			name:   "reads packet received along with error",
+			reader: iotest.DataErrReader(strings.NewReader("\\ka\\\\final\\")),
+			expectedPackets: []*Packet{
+				{elements: []KeyValuePair{{Key: "ka"}}},
+			},
+			wantErr: io.EOF,
+		},
+		{
+			name:    "fails for packet larger than max size",
+			reader:  strings.NewReader("\\key\\" + strings.Repeat("a", 64) + "\\final\\"),
+			maxSize: 32,
+			wantErr: ErrPacketTooLarge,
+		},
+		{
+			name:    "fails for unterminated packet larger than max size",
+			reader:  strings.NewReader("\\key\\" + strings.Repeat("a", 64)),
+			maxSize: 32,
+			wantErr: ErrPacketTooLarge,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// GIVEN
+			var reader *Reader
+			if tt.maxSize != 0 {
+				reader = NewReaderSize(tt.reader, tt.maxSize)
+			} else {
+				reader = NewReader(tt.reader)
+			}
+
+			// WHEN
+			packets := make([]*Packet, 0)
+			var err error
+			for {
+				var packet *Packet
+				packet, err = reader.ReadPacket()
+				if err != nil {
+					break
+				}
+				packets = append(packets, packet)
+			}
+
+			// THEN
+			assert.ErrorIs(t, err, tt.wantErr)
+			if tt.expectedPackets != nil {
+				assert.Equal(t, elementsOf(tt.expectedPackets...), elementsOf(packets...))
+			} else {
+				assert.Empty(t, packets)
+			}
+		})
+	}
+
+	t.Run("continues reading after timeout", func(t *testing.T) {
+		// GIVEN
+		r := &scriptedReader{
+			reads: []scriptedRead{
+				{data: "\\logout\\\\ses"},
+				{err: os.ErrDeadlineExceeded},
+				{data: "skey\\123\\final\\"},
+			},
+		}
+		reader := NewReader(r)
+
+		// WHEN
+		_, err := reader.ReadPacket()
+
+		// THEN
+		require.ErrorIs(t, err, os.ErrDeadlineExceeded)
+
+		// WHEN reading again after the timeout
+		packet, err := reader.ReadPacket()
+
+		// THEN
+		require.NoError(t, err)
+		assert.Equal(t, []KeyValuePair{{Key: "logout"}, {Key: "sesskey", Value: "123"}}, slices.Collect(packet.All()))
+	})
+
+	t.Run("continues reading after malformed packet", func(t *testing.T) {
+		// GIVEN
+		reader := NewReader(strings.NewReader("\\key-without-value\\final\\\\ka\\\\final\\"))
+
+		// WHEN
+		_, err := reader.ReadPacket()
+
+		// THEN
+		require.ErrorContains(t, err, "gamespy packet string contains key without corresponding value")
+
+		// WHEN reading the next packet
+		packet, err := reader.ReadPacket()
+
+		// THEN
+		require.NoError(t, err)
+		assert.Equal(t, []KeyValuePair{{Key: "ka"}}, slices.Collect(packet.All()))
+	})
+}
+
+func TestReader_ReadFrame(t *testing.T) {
+	// GIVEN
+	reader := NewReader(strings.NewReader("encoded\\final\\\\ka\\\\final\\"))
+
+	// WHEN
+	frame, err := reader.ReadFrame()
+
+	// THEN
+	require.NoError(t, err)
+	assert.Equal(t, []byte("encoded\\final\\"), frame)
+
+	// WHEN
+	packet, err := reader.ReadPacket()
+
+	// THEN
+	require.NoError(t, err)
+	assert.Equal(t, []KeyValuePair{{Key: "ka"}}, slices.Collect(packet.All()))
+}
+
+func TestWriter_WritePacket(t *testing.T) {
+	t.Run("writes packet", func(t *testing.T) {
+		// GIVEN
+		buffer := new(bytes.Buffer)
+		writer := NewWriter(buffer)
+
+		// WHEN
+		err := writer.WritePacket(&Packet{elements: []KeyValuePair{{Key: "ka"}}})
+
+		// THEN
+		require.NoError(t, err)
+		assert.Equal(t, "\\ka\\\\final\\", buffer.String())
+	})
+
+	t.Run("returns write error", func(t *testing.T) {
+		// GIVEN
+		writer := NewWriter(errWriter{})
+
+		// WHEN
+		err := writer.WritePacket(&Packet{elements: []KeyValuePair{{Key: "ka"}}})
+
+		// THEN
+		assert.ErrorIs(t, err, io.ErrClosedPipe)
+	})
+}
+
+type scriptedRead struct {
+	data string
+	err  error
+}
+
+// scriptedReader Returns the given reads one after another, followed by io.EOF
+type scriptedReader struct {
+	reads []scriptedRead
+}
+
+func (r *scriptedReader) Read(p []byte) (int, error) {
+	if len(r.reads) == 0 {
+		return 0, io.EOF
+	}
+
+	read := r.reads[0]
+	r.reads = r.reads[1:]
+	return copy(p, read.data), read.err
+}
+
+type errWriter struct{}
+
+func (errWriter) Write([]byte) (int, error) {
+	return 0, io.ErrClosedPipe
+}
@@ -0,0 +1,171 @@
+package gamespy
+
+import (
+	"encoding"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// Marshal Returns a packet containing the values of v, which must be a struct, a struct-slice or a (non-nil) pointer
+// to either. Fields are added in order of declaration using the key from their gamespy tag, fields without a tag are
+// skipped. Nil pointer fields are omitted, pointers to zero values result in keys with an empty value. Fields tagged
+// with the all option are added once per element, remain fields are added as-is. The required and default options
+// do not affect marshalling.
+// The elements of a struct-slice are added one after another, resulting in the repeated key groups read by Packet.Bind.
+func Marshal(v any) (*Packet, error) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return nil, fmt.Errorf("source must be a struct, struct-slice or a non-nil pointer to either")
+		}
+		rv = rv.Elem()
+	}
+
+	packet := NewPacket()
+	if rv.Kind() == reflect.Struct {
+		if err := packet.marshalStruct(rv); err != nil {
+			return nil, err
+		}
+		return packet, nil
+	} else if rv.Kind() == reflect.Slice && rv.Type().Elem().Kind() == reflect.Struct {
+		for i := range rv.Len() {
+			if err := packet.marshalStruct(rv.Index(i)); err != nil {
+				return nil, err
+			}
+		}
+		return packet, nil
+	}
+
+	return nil, fmt.Errorf("source must be a struct, struct-slice or a non-nil pointer to either")
+}
+
+func (p *Packet) marshalStruct(v reflect.Value) error {
+	t := v.Type()
+	for i := range t.NumField() {
+		if !t.Field(i).IsExported() {
+			continue
+		}
+
+		tag, err := parseTag(t, i)
+		if err != nil {
+			return err
+		}
+		if tag.remain {
+			p.marshalRemain(v.Field(i))
+			continue
+		}
+		if tag.key == "" {
+			continue
+		}
+
+		field := v.Field(i)
+		if tag.selection != selectAll {
+			err = p.marshalValue(tag, field)
+		} else {
+			for j := range field.Len() {
+				if err = p.marshalValue(tag, field.Index(j)); err != nil {
+					break
+				}
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("%s.%s: %w", t.Name(), t.Field(i).Name, err)
+		}
+	}
+
+	return nil
+}
+
+func (p *Packet) marshalValue(tag fieldTag, v reflect.Value) error {
+	value, ok, err := formatValue(tag, v)
+	if err != nil {
+		return err
+	}
+	if ok {
+		p.Add(tag.key, value)
+	}
+	return nil
+}
+
+// marshalRemain Adds the elements of v, which must be a map[string]string (added in key order) or []KeyValuePair.
+func (p *Packet) marshalRemain(v reflect.Value) {
+	if v.Type() == keyValuePairListType {
+		p.modify()
+		p.elements = append(p.elements, v.Interface().([]KeyValuePair)...)
+		return
+	}
+
+	m := v.Interface().(map[string]string)
+	for _, key := range slices.Sorted(maps.Keys(m)) {
+		p.Add(key, m[key])
+	}
+}
+
+// Marshaler Is implemented by types that can format themselves as a packet value.
+type Marshaler interface {
+	MarshalGamespy() (string, error)
+}
+
+// formatValue Returns the string representation of v. Returns false if v is a nil pointer and should be omitted.
+func formatValue(tag fieldTag, v reflect.Value) (string, bool, error) {
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return "", false, nil
+		}
+		return formatValue(tag, v.Elem())
+	}
+
+	// time.Time implements encoding.TextMarshaler, but is sent as a unix timestamp
+	if v.Type() != timeType {
+		if m, ok := asInterface[Marshaler](v); ok {
+			s, err := m.MarshalGamespy()
+			return s, err == nil, err
+		}
+		if m, ok := asInterface[encoding.TextMarshaler](v); ok {
+			b, err := m.MarshalText()
+			return string(b), err == nil, err
+		}
+	}
+
+	switch v.Type() {
+	case timeType:
+		return strconv.FormatInt(toUnix(v.Interface().(time.Time), tag.unit), 10), true, nil
+	case durationType:
+		return strconv.FormatInt(v.Int()/int64(tag.unit), 10), true, nil
+	case byteSliceType:
+		return string(v.Bytes()), true, nil
+	}
+
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), true, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10), true, nil
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'f', -1, v.Type().Bits()), true, nil
+	case reflect.Bool:
+		if v.Bool() {
+			return "1", true, nil
+		}
+		return "0", true, nil
+	case reflect.String:
+		return v.String(), true, nil
+	default:
+		return "", false, fmt.Errorf("unsupported field type: %s", v.Type())
+	}
+}
+
+// asInterface Returns v (or a pointer to v, if addressable) as T if it implements T.
+func asInterface[T any](v reflect.Value) (T, bool) {
+	if v.CanAddr() {
+		if t, ok := v.Addr().Interface().(T); ok {
+			return t, true
+		}
+	}
+	t, ok := v.Interface().(T)
+	return t, ok
+}
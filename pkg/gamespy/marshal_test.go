@@ -0,0 +1,322 @@
+package gamespy
+
+import (
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMarshal(t *testing.T) {
+	t.Run("struct", func(t *testing.T) {
+		t.Run("marshals struct data in field order", func(t *testing.T) {
+			// GIVEN
+			type s struct {
+				String        string  `gamespy:"string"`
+				StringPointer *string `gamespy:"string-pointer"`
+				Int           int     `gamespy:"int"`
+				IntPointer    *int    `gamespy:"int-pointer"`
+				Int32         int32   `gamespy:"int32"`
+				Int64         int64   `gamespy:"int64"`
+			}
+			source := s{
+				String:        "some-value",
+				StringPointer: toPointer("other-value"),
+				Int:           1,
+				IntPointer:    toPointer(2),
+				Int32:         -3,
+				Int64:         600000000,
+			}
+
+			// WHEN
+			packet, err := Marshal(source)
+
+			// THEN
+			require.NoError(t, err)
+			assert.Equal(t, &Packet{
+				elements: []KeyValuePair{
+					{Key: "string", Value: "some-value"},
+					{Key: "string-pointer", Value: "other-value"},
+					{Key: "int", Value: "1"},
+					{Key: "int-pointer", Value: "2"},
+					{Key: "int32", Value: "-3"},
+					{Key: "int64", Value: "600000000"},
+				},
+			}, packet)
+		})
+
+		t.Run("marshals struct pointer", func(t *testing.T) {
+			// GIVEN
+			type s struct {
+				Field string `gamespy:"field"`
+			}
+
+			// WHEN
+			packet, err := Marshal(&s{Field: "some-value"})
+
+			// THEN
+			require.NoError(t, err)
+			assert.Equal(t, &Packet{
+				elements: []KeyValuePair{
+					{Key: "field", Value: "some-value"},
+				},
+			}, packet)
+		})
+
+		t.Run("marshals zero values and pointers to zero values as empty values", func(t *testing.T) {
+			// GIVEN
+			type s struct {
+				KeyOnly *string `gamespy:"key-only"`
+				Empty   string  `gamespy:"empty"`
+				Zero    int     `gamespy:"zero"`
+			}
+
+			// WHEN
+			packet, err := Marshal(s{KeyOnly: toPointer("")})
+
+			// THEN
+			require.NoError(t, err)
+			assert.Equal(t, "\\key-only\\\\empty\\\\zero\\0\\final\\", packet.String())
+		})
+
+		t.Run("omits nil pointer field", func(t *testing.T) {
+			// GIVEN
+			type s struct {
+				WithValue *string `gamespy:"with-value"`
+				Nil       *string `gamespy:"nil"`
+			}
+
+			// WHEN
+			packet, err := Marshal(s{WithValue: toPointer("some-value")})
+
+			// THEN
+			require.NoError(t, err)
+			assert.Equal(t, &Packet{
+				elements: []KeyValuePair{
+					{Key: "with-value", Value: "some-value"},
+				},
+			}, packet)
+		})
+
+		t.Run("ignores unexported field", func(t *testing.T) {
+			// GIVEN
+			type s struct {
+				Exported   string `gamespy:"exported"`
+				unexported string `gamespy:"unexported"`
+			}
+
+			// WHEN
+			packet, err := Marshal(s{Exported: "some-value", unexported: "other-value"})
+
+			// THEN
+			require.NoError(t, err)
+			assert.Equal(t, &Packet{
+				elements: []KeyValuePair{
+					{Key: "exported", Value: "some-value"},
+				},
+			}, packet)
+		})
+
+		t.Run("ignores field without tag", func(t *testing.T) {
+			// GIVEN
+			type s struct {
+				WithTag    string `gamespy:"with-tag"`
+				WithoutTag string
+			}
+
+			// WHEN
+			packet, err := Marshal(s{WithTag: "some-value", WithoutTag: "other-value"})
+
+			// THEN
+			require.NoError(t, err)
+			assert.Equal(t, &Packet{
+				elements: []KeyValuePair{
+					{Key: "with-tag", Value: "some-value"},
+				},
+			}, packet)
+		})
+
+		t.Run("marshals extended types", func(t *testing.T) {
+			// GIVEN
+			type s struct {
+				Bool        bool          `gamespy:"bool"`
+				Uint8       uint8         `gamespy:"uint8"`
+				Float32     float32       `gamespy:"float32"`
+				Float64     float64       `gamespy:"float64"`
+				Time        time.Time     `gamespy:"time"`
+				TimeMillis  time.Time     `gamespy:"time-ms,unit=ms"`
+				Duration    time.Duration `gamespy:"duration"`
+				Bytes       []byte        `gamespy:"bytes"`
+				Marshaler   upperString   `gamespy:"marshaler"`
+				Text        netip.Addr    `gamespy:"text"`
+				TextPointer *netip.Addr   `gamespy:"text-pointer"`
+			}
+			source := s{
+				Bool:        true,
+				Uint8:       255,
+				Float32:     1.1,
+				Float64:     -0.25,
+				Time:        time.Unix(1700000000, 0),
+				TimeMillis:  time.UnixMilli(1700000000123),
+				Duration:    90 * time.Second,
+				Bytes:       []byte("some-data"),
+				Marshaler:   "SOME-VALUE",
+				Text:        netip.MustParseAddr("10.0.0.1"),
+				TextPointer: toPointer(netip.MustParseAddr("10.0.0.2")),
+			}
+
+			// WHEN
+			packet, err := Marshal(source)
+
+			// THEN
+			require.NoError(t, err)
+			assert.Equal(t, &Packet{
+				elements: []KeyValuePair{
+					{Key: "bool", Value: "1"},
+					{Key: "uint8", Value: "255"},
+					{Key: "float32", Value: "1.1"},
+					{Key: "float64", Value: "-0.25"},
+					{Key: "time", Value: "1700000000"},
+					{Key: "time-ms", Value: "1700000000123"},
+					{Key: "duration", Value: "90"},
+					{Key: "bytes", Value: "some-data"},
+					{Key: "marshaler", Value: "some-value"},
+					{Key: "text", Value: "10.0.0.1"},
+					{Key: "text-pointer", Value: "10.0.0.2"},
+				},
+			}, packet)
+		})
+
+		t.Run("marshals all and remain fields", func(t *testing.T) {
+			// GIVEN
+			type s struct {
+				All    []int             `gamespy:"all,all"`
+				Remain map[string]string `gamespy:",remain"`
+				Last   string            `gamespy:"last,required"`
+			}
+			source := s{
+				All: []int{1, 2},
+				Remain: map[string]string{
+					"b": "some-value",
+					"a": "other-value",
+				},
+				Last: "last-value",
+			}
+
+			// WHEN
+			packet, err := Marshal(source)
+
+			// THEN
+			require.NoError(t, err)
+			assert.Equal(t, &Packet{
+				elements: []KeyValuePair{
+					{Key: "all", Value: "1"},
+					{Key: "all", Value: "2"},
+					{Key: "a", Value: "other-value"},
+					{Key: "b", Value: "some-value"},
+					{Key: "last", Value: "last-value"},
+				},
+			}, packet)
+		})
+
+		t.Run("fails for unsupported type field", func(t *testing.T) {
+			// GIVEN
+			type s struct {
+				Field map[string]string `gamespy:"field"`
+			}
+
+			// WHEN
+			_, err := Marshal(s{})
+
+			// THEN
+			require.ErrorContains(t, err, "s.Field: unsupported field type: map[string]string")
+		})
+	})
+
+	t.Run("slice", func(t *testing.T) {
+		t.Run("marshals slice as repeated key groups", func(t *testing.T) {
+			// GIVEN
+			type s struct {
+				Nick       string `gamespy:"nick"`
+				UniqueNick string `gamespy:"uniquenick"`
+			}
+			source := []s{
+				{Nick: "a-nick", UniqueNick: "a-uniquenick"},
+				{Nick: "b-nick", UniqueNick: "b-uniquenick"},
+			}
+
+			// WHEN
+			packet, err := Marshal(source)
+
+			// THEN
+			require.NoError(t, err)
+			assert.Equal(t, "\\nick\\a-nick\\uniquenick\\a-uniquenick\\nick\\b-nick\\uniquenick\\b-uniquenick\\final\\", packet.String())
+		})
+
+		t.Run("marshals empty slice as empty packet", func(t *testing.T) {
+			// GIVEN
+			type s struct {
+				Field string `gamespy:"field"`
+			}
+
+			// WHEN
+			packet, err := Marshal([]s{})
+
+			// THEN
+			require.NoError(t, err)
+			assert.Equal(t, NewPacket(), packet)
+		})
+
+		t.Run("round-trips through Bind", func(t *testing.T) {
+			// GIVEN
+			type s struct {
+				String string `gamespy:"string"`
+				Int    int    `gamespy:"int"`
+			}
+			source := []s{
+				{String: "first-value", Int: 1},
+				{String: "second-value", Int: 2},
+			}
+
+			// WHEN
+			packet, err := Marshal(source)
+			require.NoError(t, err)
+			actual := make([]s, 0)
+			err = packet.Bind(&actual)
+
+			// THEN
+			require.NoError(t, err)
+			assert.Equal(t, source, actual)
+		})
+	})
+
+	t.Run("fails for nil pointer source", func(t *testing.T) {
+		// GIVEN
+		type s struct{}
+		var source *s
+
+		// WHEN
+		_, err := Marshal(source)
+
+		// THEN
+		require.ErrorContains(t, err, "source must be a struct, struct-slice or a non-nil pointer to either")
+	})
+
+	t.Run("fails for non-struct source", func(t *testing.T) {
+		// WHEN
+		_, err := Marshal("some-string")
+
+		// THEN
+		require.ErrorContains(t, err, "source must be a struct, struct-slice or a non-nil pointer to either")
+	})
+
+	t.Run("fails for non-struct-slice source", func(t *testing.T) {
+		// WHEN
+		_, err := Marshal([]string{"some-string"})
+
+		// THEN
+		require.ErrorContains(t, err, "source must be a struct, struct-slice or a non-nil pointer to either")
+	})
+}
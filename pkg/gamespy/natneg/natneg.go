@@ -0,0 +1,215 @@
+// Package natneg Implements the server side of the GameSpy NAT negotiation (natneg) protocol, which game clients use
+// to establish peer-to-peer connections through NATs via UDP.
+package natneg
+
+import (
+	"bytes"
+	"errors"
+	"net/netip"
+
+	"github.com/dogclan/dumbspy/pkg/gamespy/codec"
+)
+
+type PacketType byte
+
+// Following https://github.com/openspy/openspy-core/blob/5993df54c6b289361228920fa0db7209aed4cfe5/code/natneg/server/NNDriver.h
+const (
+	PacketInit          PacketType = 0x00
+	PacketInitAck       PacketType = 0x01
+	PacketERTTest       PacketType = 0x02
+	PacketERTAck        PacketType = 0x03
+	PacketStateUpdate   PacketType = 0x04
+	PacketConnect       PacketType = 0x05
+	PacketConnectAck    PacketType = 0x06
+	PacketConnectPing   PacketType = 0x07
+	PacketBackupTest    PacketType = 0x08
+	PacketBackupAck     PacketType = 0x09
+	PacketAddressCheck  PacketType = 0x0A
+	PacketAddressReply  PacketType = 0x0B
+	PacketNATifyRequest PacketType = 0x0C
+	PacketReport        PacketType = 0x0D
+	PacketReportAck     PacketType = 0x0E
+)
+
+// PortType Denotes the socket a client sent a packet from. The game port (GP) is the socket used for game traffic, the
+// others are additional sockets used to determine the NAT's behaviour.
+type PortType byte
+
+const (
+	PortTypeGP  PortType = 0x00
+	PortTypeNN1 PortType = 0x01
+	PortTypeNN2 PortType = 0x02
+	PortTypeNN3 PortType = 0x03
+)
+
+// Results sent in connect packets
+const (
+	FinishedNoError              = 0x00
+	FinishedErrorDeadbeatPartner = 0x01
+	FinishedErrorInitTimedOut    = 0x02
+)
+
+const (
+	// headerLength is the length of the magic, version, packet type and cookie
+	headerLength = 12
+	// initLength is the length of init packet data up to the (optional) game name
+	initLength = 9
+	// reportLength is the length of report packet data up to the game name
+	reportLength = 11
+	// reportGameNameLength is the length of the fixed-size game name field of reports
+	reportGameNameLength = 50
+)
+
+var (
+	magic = []byte{0xFD, 0xFC, 0x1E, 0x66, 0x6A, 0xB2}
+
+	ErrShortPacket   = errors.New("packet too short")
+	ErrInvalidMagic  = errors.New("invalid magic")
+	ErrInvalidClient = errors.New("invalid client index")
+)
+
+// Packet A natneg packet. The cookie is chosen by the clients and identifies the negotiation.
+type Packet struct {
+	Version byte
+	Type    PacketType
+	Cookie  uint32
+	Data    []byte
+}
+
+// ParsePacket Parses the header of a natneg packet. Data references b.
+func ParsePacket(b []byte) (Packet, error) {
+	if len(b) < headerLength {
+		return Packet{}, ErrShortPacket
+	}
+	if !bytes.HasPrefix(b, magic) {
+		return Packet{}, ErrInvalidMagic
+	}
+
+	d := codec.NewDecoder(b[len(magic):])
+	return Packet{
+		Version: d.Uint8(),
+		Type:    PacketType(d.Uint8()),
+		Cookie:  d.Uint32(),
+		Data:    d.Rest(),
+	}, nil
+}
+
+// Init The data of init packets, which are also used for ERT, address check and NATify packets. Clients send one init
+// per port type. The local address is the client's address within its local network.
+type Init struct {
+	PortType    PortType
+	ClientIndex byte // 0 for the client initiating the negotiation, 1 for the other
+	UseGamePort bool // Whether the game port should be used to connect
+	LocalAddr   netip.AddrPort
+	GameName    string // Only sent by version 2+ clients
+}
+
+// ParseInit Parses the data of an init packet.
+func ParseInit(data []byte) (Init, error) {
+	if len(data) < initLength {
+		return Init{}, ErrShortPacket
+	}
+
+	d := codec.NewDecoder(data)
+	init := Init{
+		PortType:    PortType(d.Uint8()),
+		ClientIndex: d.Uint8(),
+		UseGamePort: d.Bool(),
+		LocalAddr:   d.AddrPort(),
+	}
+	if init.ClientIndex > 1 {
+		return Init{}, ErrInvalidClient
+	}
+	// Game name is optional, so a missing terminator is not an error
+	if name := d.CString(); d.Err() == nil {
+		init.GameName = name
+	}
+	return init, nil
+}
+
+// Report The result of a negotiation as reported by a client.
+type Report struct {
+	PortType      PortType
+	ClientIndex   byte
+	Result        byte // 1 if the negotiation succeeded
+	NATType       uint32
+	MappingScheme uint32
+	GameName      string
+}
+
+// ParseReport Parses the data of a report packet.
+func ParseReport(data []byte) (Report, error) {
+	if len(data) < reportLength {
+		return Report{}, ErrShortPacket
+	}
+
+	d := codec.NewDecoder(data)
+	return Report{
+		PortType:      PortType(d.Uint8()),
+		ClientIndex:   d.Uint8(),
+		Result:        d.Uint8(),
+		NATType:       d.Uint32(),
+		MappingScheme: d.Uint32(),
+		GameName:      d.FixedString(reportGameNameLength),
+	}, nil
+}
+
+// NewInitAck Returns the reply acknowledging an init packet.
+func NewInitAck(version byte, cookie uint32, init Init) []byte {
+	e := newPacket(version, PacketInitAck, cookie)
+	encodeInit(e, init)
+	return e.Bytes()
+}
+
+// NewERTTest Returns the external reachability test packet sent in reply to a NATify request.
+func NewERTTest(version byte, cookie uint32, init Init) []byte {
+	e := newPacket(version, PacketERTTest, cookie)
+	encodeInit(e, init)
+	return e.Bytes()
+}
+
+// NewAddressReply Returns the reply to an address check, carrying the public address the check was received from.
+func NewAddressReply(version byte, cookie uint32, init Init, publicAddr netip.AddrPort) []byte {
+	init.LocalAddr = publicAddr
+	e := newPacket(version, PacketAddressReply, cookie)
+	encodeInit(e, init)
+	return e.Bytes()
+}
+
+// NewConnect Returns the packet telling a client to connect to its peer's public address. Peer is ignored unless
+// finished is FinishedNoError.
+func NewConnect(version byte, cookie uint32, peer netip.AddrPort, finished byte) []byte {
+	e := newPacket(version, PacketConnect, cookie)
+	e.AddrPort(peer)
+	// Peers use "got your data" in pings to each other, it is not relevant in packets sent by the server
+	e.Uint8(0)
+	e.Uint8(finished)
+	return e.Bytes()
+}
+
+// NewReportAck Returns the reply acknowledging a report.
+func NewReportAck(version byte, cookie uint32, report Report) []byte {
+	e := newPacket(version, PacketReportAck, cookie)
+	e.Uint8(byte(report.PortType))
+	e.Uint8(report.ClientIndex)
+	e.Uint8(report.Result)
+	e.Uint32(report.NATType)
+	e.Uint32(report.MappingScheme)
+	return e.Bytes()
+}
+
+func newPacket(version byte, t PacketType, cookie uint32) *codec.Encoder {
+	e := codec.NewEncoder(make([]byte, 0, headerLength+initLength))
+	e.Raw(magic)
+	e.Uint8(version)
+	e.Uint8(byte(t))
+	e.Uint32(cookie)
+	return e
+}
+
+func encodeInit(e *codec.Encoder, init Init) {
+	e.Uint8(byte(init.PortType))
+	e.Uint8(init.ClientIndex)
+	e.Bool(init.UseGamePort)
+	e.AddrPort(init.LocalAddr)
+}
@@ -0,0 +1,176 @@
+package natneg
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var header = []byte{0xFD, 0xFC, 0x1E, 0x66, 0x6A, 0xB2, 0x03, 0x00, 0x00, 0x00, 0x04, 0xD2}
+
+func TestParsePacket(t *testing.T) {
+	type test struct {
+		name            string
+		givenBytes      []byte
+		expectedPacket  Packet
+		wantErrContains string
+	}
+
+	tests := []test{
+		{
+			name:       "parses header",
+			givenBytes: append(header, 'a'),
+			expectedPacket: Packet{
+				Version: 3,
+				Type:    PacketInit,
+				Cookie:  1234,
+				Data:    []byte("a"),
+			},
+		},
+		{
+			name:            "fails for short packet",
+			givenBytes:      header[:11],
+			wantErrContains: "packet too short",
+		},
+		{
+			name:            "fails for invalid magic",
+			givenBytes:      append([]byte{0xFE}, header[1:]...),
+			wantErrContains: "invalid magic",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// WHEN
+			p, err := ParsePacket(tt.givenBytes)
+
+			// THEN
+			if tt.wantErrContains != "" {
+				require.ErrorContains(t, err, tt.wantErrContains)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, tt.expectedPacket, p)
+			}
+		})
+	}
+}
+
+func TestParseInit(t *testing.T) {
+	type test struct {
+		name            string
+		givenData       []byte
+		expectedInit    Init
+		wantErrContains string
+	}
+
+	tests := []test{
+		{
+			name:      "parses init with game name",
+			givenData: []byte("\x01\x01\x01\xc0\xa8\x00\x05\x40\xb7battlefield2\x00"),
+			expectedInit: Init{
+				PortType:    PortTypeNN1,
+				ClientIndex: 1,
+				UseGamePort: true,
+				LocalAddr:   netip.MustParseAddrPort("192.168.0.5:16567"),
+				GameName:    "battlefield2",
+			},
+		},
+		{
+			name:      "parses init without game name",
+			givenData: []byte("\x00\x00\x00\xc0\xa8\x00\x05\x40\xb7"),
+			expectedInit: Init{
+				PortType:  PortTypeGP,
+				LocalAddr: netip.MustParseAddrPort("192.168.0.5:16567"),
+			},
+		},
+		{
+			name:            "fails for invalid client index",
+			givenData:       []byte("\x00\x02\x00\xc0\xa8\x00\x05\x40\xb7"),
+			wantErrContains: "invalid client index",
+		},
+		{
+			name:            "fails for short data",
+			givenData:       []byte("\x00\x00\x00\xc0\xa8"),
+			wantErrContains: "packet too short",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// WHEN
+			init, err := ParseInit(tt.givenData)
+
+			// THEN
+			if tt.wantErrContains != "" {
+				require.ErrorContains(t, err, tt.wantErrContains)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, tt.expectedInit, init)
+			}
+		})
+	}
+}
+
+func TestParseReport(t *testing.T) {
+	// GIVEN
+	data := append([]byte("\x01\x00\x01\x00\x00\x00\x02\x00\x00\x00\x01battlefield2\x00"), make([]byte, 37)...)
+
+	// WHEN
+	report, err := ParseReport(data)
+
+	// THEN
+	require.NoError(t, err)
+	assert.Equal(t, Report{
+		PortType:      PortTypeNN1,
+		Result:        1,
+		NATType:       2,
+		MappingScheme: 1,
+		GameName:      "battlefield2",
+	}, report)
+}
+
+func TestNewInitAck(t *testing.T) {
+	// GIVEN
+	init := Init{
+		PortType:    PortTypeNN2,
+		ClientIndex: 1,
+		LocalAddr:   netip.MustParseAddrPort("192.168.0.5:16567"),
+		GameName:    "battlefield2",
+	}
+
+	// WHEN
+	b := NewInitAck(3, 1234, init)
+
+	// THEN
+	expected := []byte{0xFD, 0xFC, 0x1E, 0x66, 0x6A, 0xB2, 0x03, 0x01, 0x00, 0x00, 0x04, 0xD2, 0x02, 0x01, 0x00, 0xC0, 0xA8, 0x00, 0x05, 0x40, 0xB7}
+	assert.Equal(t, expected, b)
+}
+
+func TestNewAddressReply(t *testing.T) {
+	// WHEN
+	b := NewAddressReply(3, 1234, Init{PortType: PortTypeNN1}, netip.MustParseAddrPort("1.2.3.4:5678"))
+
+	// THEN
+	expected := []byte{0xFD, 0xFC, 0x1E, 0x66, 0x6A, 0xB2, 0x03, 0x0B, 0x00, 0x00, 0x04, 0xD2, 0x01, 0x00, 0x00, 0x01, 0x02, 0x03, 0x04, 0x16, 0x2E}
+	assert.Equal(t, expected, b)
+}
+
+func TestNewConnect(t *testing.T) {
+	// WHEN
+	b := NewConnect(3, 1234, netip.MustParseAddrPort("1.2.3.4:5678"), FinishedNoError)
+
+	// THEN
+	expected := []byte{0xFD, 0xFC, 0x1E, 0x66, 0x6A, 0xB2, 0x03, 0x05, 0x00, 0x00, 0x04, 0xD2, 0x01, 0x02, 0x03, 0x04, 0x16, 0x2E, 0x00, 0x00}
+	assert.Equal(t, expected, b)
+}
+
+func TestNewReportAck(t *testing.T) {
+	// WHEN
+	b := NewReportAck(3, 1234, Report{PortType: PortTypeNN1, ClientIndex: 1, Result: 1, NATType: 2, MappingScheme: 1})
+
+	// THEN
+	expected := []byte{0xFD, 0xFC, 0x1E, 0x66, 0x6A, 0xB2, 0x03, 0x0E, 0x00, 0x00, 0x04, 0xD2, 0x01, 0x01, 0x01, 0x00, 0x00, 0x00, 0x02, 0x00, 0x00, 0x00, 0x01}
+	assert.Equal(t, expected, b)
+}
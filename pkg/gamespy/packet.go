@@ -2,12 +2,17 @@ package gamespy
 
 import (
 	"bytes"
+	"encoding"
 	"errors"
 	"fmt"
+	"io"
 	"iter"
 	"reflect"
+	"slices"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 )
 
 const (
@@ -16,6 +21,9 @@ const (
 	separator = "\\"
 )
 
+// ErrMissingKey Is returned by Packet.Bind if a key tagged as required is missing from the packet.
+var ErrMissingKey = errors.New("gamespy packet is missing required key")
+
 type KeyValuePair struct {
 	Key, Value string
 }
@@ -26,11 +34,16 @@ func (p *KeyValuePair) String() string {
 
 type Packet struct {
 	elements []KeyValuePair // Store elements in list to maintain order
+	// Packets parsed from bytes keep the packet body and the spans of its keys and values instead, slicing them from the
+	// body on access. They are converted to elements on their first modification.
+	body  string
+	spans []span
 }
 
+// NewPacket Returns a packet containing a copy of elements, so modifying the packet never modifies the caller's slice.
 func NewPacket(elements ...KeyValuePair) *Packet {
 	return &Packet{
-		elements: elements,
+		elements: slices.Clone(elements),
 	}
 }
 
@@ -41,21 +54,17 @@ func NewPacketFromString(raw string) (*Packet, error) {
 	return NewPacketFromBytes([]byte(raw))
 }
 
+// NewPacketFromBytes Parses a copy of b. Only the offsets of keys and values are determined, they are sliced from the
+// copy once accessed.
 func NewPacketFromBytes(b []byte) (*Packet, error) {
-	if !bytes.HasPrefix(b, []byte(prefix)) || !bytes.HasSuffix(b, []byte(suffix)) {
-		return nil, errors.New("gamespy packet string is malformed")
-	}
-
-	elements := bytes.Split(b[1:len(b)-7], []byte(separator))
-	if len(elements)%2 != 0 {
-		return nil, errors.New("gamespy packet string contains key without corresponding value")
-	}
-
-	packet := NewPacket()
-	for i := 0; i < len(elements); i += 2 {
-		packet.Add(string(elements[i]), string(elements[i+1]))
+	body, spans, err := parseIndex(b)
+	if err != nil {
+		return nil, err
 	}
-	return packet, nil
+	return &Packet{
+		body:  string(body),
+		spans: spans,
+	}, nil
 }
 
 // Set Adds a new KeyValuePair to the packet. If key exists, the existing KeyValuePair is updated instead.
@@ -71,6 +80,7 @@ func (p *Packet) SetInt(key string, value int) {
 
 // Add Adds a new KeyValuePair to the packet, regardless of whether key already exists in packet.
 func (p *Packet) Add(key string, value string) {
+	p.modify()
 	p.elements = append(p.elements, KeyValuePair{Key: key, Value: value})
 }
 
@@ -78,8 +88,27 @@ func (p *Packet) AddInt(key string, value int) {
 	p.Add(key, strconv.Itoa(value))
 }
 
+// Append Adds all KeyValuePair-s of the other packets to the packet, maintaining their order.
+func (p *Packet) Append(others ...*Packet) {
+	p.modify()
+	for _, other := range others {
+		for i := range other.len() {
+			p.elements = append(p.elements, other.at(i))
+		}
+	}
+}
+
 // Lookup Checks if key exists in packet and returns the first value with a matching key.
 func (p *Packet) Lookup(key string) (string, bool) {
+	if p.spans != nil {
+		for i := range p.spans {
+			if p.key(i) == key {
+				return p.value(i), true
+			}
+		}
+		return "", false
+	}
+
 	for _, element := range p.elements {
 		if element.Key == key {
 			return element.Value, true
@@ -106,50 +135,52 @@ func (p *Packet) GetInt(key string) (int, error) {
 
 // GetAll Retrieves all values with a matching key. Returns nil if key does not exist in packet.
 func (p *Packet) GetAll(key string) []string {
-	values := make([]string, 0, len(p.elements))
-	for _, element := range p.elements {
-		if element.Key == key {
-			values = append(values, element.Value)
+	var values []string
+	for i := range p.len() {
+		if p.key(i) == key {
+			values = append(values, p.value(i))
 		}
 	}
-
-	if len(values) > 0 {
-		return values
-	}
-
-	return nil
+	return values
 }
 
 // Remove Removes all KeyValuePair-s which match key.
 func (p *Packet) Remove(key string) {
-	keepers := make([]KeyValuePair, 0, len(p.elements))
-	for _, element := range p.elements {
-		if element.Key != key {
-			keepers = append(keepers, element)
-		}
-	}
-	p.elements = keepers
+	p.modify()
+	p.elements = slices.DeleteFunc(p.elements, func(element KeyValuePair) bool {
+		return element.Key == key
+	})
 }
 
 // Do Calls function f for every KeyValuePair in the Packet.
 // The behavior of Do is undefined if f changes *p.
 func (p *Packet) Do(f func(element KeyValuePair)) {
-	for _, element := range p.elements {
-		f(element)
+	for i := range p.len() {
+		f(p.at(i))
 	}
 }
 
 // All returns an iterator over the packet's key value pairs
 func (p *Packet) All() iter.Seq[KeyValuePair] {
 	return func(yield func(KeyValuePair) bool) {
-		for _, element := range p.elements {
-			if !yield(element) {
+		for i := range p.len() {
+			if !yield(p.at(i)) {
 				return
 			}
 		}
 	}
 }
 
+// Bind Binds the packet's values to target, which must be a pointer to a struct or struct-slice. Fields are bound by
+// their tag in format `gamespy:"key[,option]..."`, supported options are:
+//   - required: fail with ErrMissingKey if the key is missing
+//   - default=value: bind value if the key is missing
+//   - first/last: bind the first/last value of a duplicated key (last is the default)
+//   - all: bind every value of a duplicated key to a slice field
+//   - remain: bind all keys not bound to any other field to a map[string]string or []KeyValuePair field (without key)
+//   - unit=s|ms|us|ns: unit of time.Time (since the unix epoch) and time.Duration values (s is the default)
+//
+// When binding a struct-slice, a repeated key starts a new element, so first, all and remain are not supported.
 func (p *Packet) Bind(target any) error {
 	v := reflect.ValueOf(target)
 	if v.Kind() != reflect.Ptr || v.IsNil() {
@@ -169,76 +200,281 @@ func (p *Packet) Bind(target any) error {
 //
 // Deprecated: Packet may contain duplicate keys, which will not be reflected in the map.
 func (p *Packet) Map() map[string]string {
-	elements := make(map[string]string, len(p.elements))
-	for _, element := range p.elements {
+	elements := make(map[string]string, p.len())
+	for i := range p.len() {
+		element := p.at(i)
 		elements[element.Key] = element.Value
 	}
 	return elements
 }
 
 func (p *Packet) String() string {
-	elements := make([]string, len(p.elements))
-	for i, element := range p.elements {
-		elements[i] = element.String()
+	var sb strings.Builder
+	sb.Grow(p.size())
+	sb.WriteString(prefix)
+	if p.spans != nil {
+		sb.WriteString(p.body)
+	} else {
+		for i, element := range p.elements {
+			if i > 0 {
+				sb.WriteString(separator)
+			}
+			sb.WriteString(element.Key)
+			sb.WriteString(separator)
+			sb.WriteString(element.Value)
+		}
 	}
-	return prefix + strings.Join(elements, separator) + suffix
+	sb.WriteString(suffix)
+	return sb.String()
 }
 
 func (p *Packet) Bytes() []byte {
-	return []byte(p.String())
+	return p.appendTo(make([]byte, 0, p.size()))
+}
+
+var bufferPool = sync.Pool{
+	New: func() any {
+		return new(bytes.Buffer)
+	},
+}
+
+// maxPooledBufferSize is the capacity up to which buffers are returned to the pool, so single large packets do not
+// keep memory reserved
+const maxPooledBufferSize = 64 << 10
+
+// WriteTo Writes the packet to w with a single call to w.Write, using a pooled buffer.
+func (p *Packet) WriteTo(w io.Writer) (int64, error) {
+	buf := bufferPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	defer func() {
+		if buf.Cap() <= maxPooledBufferSize {
+			bufferPool.Put(buf)
+		}
+	}()
+
+	buf.Grow(p.size())
+	buf.Write(p.appendTo(buf.AvailableBuffer()))
+	n, err := w.Write(buf.Bytes())
+	return int64(n), err
+}
+
+// size Returns the length of the packet's string representation.
+func (p *Packet) size() int {
+	n := len(prefix) + len(suffix)
+	if p.spans != nil {
+		return n + len(p.body)
+	}
+	for i, element := range p.elements {
+		if i > 0 {
+			n += len(separator)
+		}
+		n += len(element.Key) + len(separator) + len(element.Value)
+	}
+	return n
+}
+
+// appendTo Appends the packet's string representation to dst.
+func (p *Packet) appendTo(dst []byte) []byte {
+	dst = append(dst, prefix...)
+	if p.spans != nil {
+		dst = append(dst, p.body...)
+		return append(dst, suffix...)
+	}
+	for i, element := range p.elements {
+		if i > 0 {
+			dst = append(dst, separator...)
+		}
+		dst = append(dst, element.Key...)
+		dst = append(dst, separator...)
+		dst = append(dst, element.Value...)
+	}
+	return append(dst, suffix...)
+}
+
+// len Returns the number of key/value pairs.
+func (p *Packet) len() int {
+	if p.spans != nil {
+		return len(p.spans)
+	}
+	return len(p.elements)
+}
+
+// at Returns the i-th key/value pair.
+func (p *Packet) at(i int) KeyValuePair {
+	if p.spans != nil {
+		return KeyValuePair{Key: p.key(i), Value: p.value(i)}
+	}
+	return p.elements[i]
+}
+
+// key Returns the key of the i-th key/value pair.
+func (p *Packet) key(i int) string {
+	if p.spans != nil {
+		return p.body[p.spans[i].keyStart:p.spans[i].keyEnd]
+	}
+	return p.elements[i].Key
+}
+
+// value Returns the value of the i-th key/value pair.
+func (p *Packet) value(i int) string {
+	if p.spans != nil {
+		return p.body[p.spans[i].keyEnd+1 : p.spans[i].valueEnd]
+	}
+	return p.elements[i].Value
+}
+
+// lookupLast Checks if key exists in packet and returns the last value with a matching key.
+func (p *Packet) lookupLast(key string) (string, bool) {
+	for i := p.len() - 1; i >= 0; i-- {
+		if p.key(i) == key {
+			return p.value(i), true
+		}
+	}
+	return "", false
+}
+
+// modify Converts a parsed packet to elements, which may be modified.
+func (p *Packet) modify() {
+	if p.spans == nil {
+		return
+	}
+
+	elements := make([]KeyValuePair, len(p.spans))
+	for i := range elements {
+		elements[i] = p.at(i)
+	}
+	p.elements = elements
+	p.body, p.spans = "", nil
 }
 
 func (p *Packet) bindStruct(v reflect.Value) error {
 	v = v.Elem()
 	t := v.Type()
+
+	remain := -1
+	known := make(map[string]struct{}, t.NumField())
 	for i := range t.NumField() {
 		field := v.Field(i)
 		if !field.CanSet() {
 			continue
 		}
 
-		key := t.Field(i).Tag.Get("gamespy")
-		if key == "" {
+		tag, err := parseTag(t, i)
+		if err != nil {
+			return err
+		}
+		if tag.remain {
+			remain = i
 			continue
 		}
-
-		// Get all values and use last one to mimic default JSON library behavior
-		values := p.GetAll(key)
-		if len(values) == 0 {
+		if tag.key == "" {
 			continue
 		}
-		value := values[len(values)-1]
+		known[tag.key] = struct{}{}
+
+		// Only collect all values if required, sparing an allocation per field otherwise
+		var values []string
+		var value string
+		var ok bool
+		switch tag.selection {
+		case selectAll:
+			values = p.GetAll(tag.key)
+			ok = len(values) > 0
+		case selectFirst:
+			value, ok = p.Lookup(tag.key)
+		default:
+			// Use last value to mimic default JSON library behavior
+			value, ok = p.lookupLast(tag.key)
+		}
+		if !ok {
+			if tag.required {
+				return fmt.Errorf("%s.%s: %w: %q", t.Name(), t.Field(i).Name, ErrMissingKey, tag.key)
+			}
+			if tag.defaultValue == nil {
+				continue
+			}
+			value, values = *tag.defaultValue, []string{*tag.defaultValue}
+		}
 
-		if err := setValue(t, i, field, value); err != nil {
+		if tag.selection == selectAll {
+			err = setValues(t, i, tag, field, values)
+		} else {
+			err = setValue(t, i, tag, field, value)
+		}
+		if err != nil {
 			return err
 		}
 	}
 
+	if remain != -1 {
+		p.bindRemain(v.Field(remain), known)
+	}
+
 	return nil
 }
 
+// bindRemain Binds all elements with keys not contained in known to v, which must be a map[string]string or
+// []KeyValuePair. Leaves v untouched if there are no such elements.
+func (p *Packet) bindRemain(v reflect.Value, known map[string]struct{}) {
+	var elements []KeyValuePair
+	for i := range p.len() {
+		element := p.at(i)
+		if _, ok := known[element.Key]; !ok {
+			elements = append(elements, element)
+		}
+	}
+	if len(elements) == 0 {
+		return
+	}
+
+	if v.Type() == keyValuePairListType {
+		v.Set(reflect.ValueOf(elements))
+		return
+	}
+
+	m := make(map[string]string, len(elements))
+	for _, element := range elements {
+		// Use last value to mimic default JSON library behavior
+		m[element.Key] = element.Value
+	}
+	v.Set(reflect.ValueOf(m))
+}
+
 func (p *Packet) bindSlice(v reflect.Value) error {
 	v = v.Elem()
 	t := v.Type()
 	et := t.Elem()
 
-	// Map tags to element type field indexes
+	// Map keys to element type field indexes
 	indexes := make(map[string]int, et.NumField())
+	tags := make([]fieldTag, et.NumField())
 	for i := range et.NumField() {
-		key := et.Field(i).Tag.Get("gamespy")
-		if key == "" {
+		tag, err := parseTag(et, i)
+		if err != nil {
+			return err
+		}
+		// Repeated keys start a new element, so there is never more than one value per key (and no remainder)
+		if tag.selection != selectLast || tag.remain {
+			return fmt.Errorf("%s.%s: tag options first, all and remain are not supported for slices", et.Name(), et.Field(i).Name)
+		}
+		if tag.key == "" {
 			continue
 		}
 
-		indexes[key] = i
+		indexes[tag.key] = i
+		tags[i] = tag
 	}
 
 	current := reflect.New(et).Elem()
 	keys := make(map[string]struct{})
-	for _, element := range p.elements {
+	for j := range p.len() {
+		element := p.at(j)
 		// Start building a new result when we reach a key we saw before
 		_, seen := keys[element.Key]
 		if seen {
+			if err := completeElement(et, tags, current, keys); err != nil {
+				return err
+			}
 			v.Set(reflect.Append(v, current))
 			current = reflect.New(et).Elem()
 			keys = make(map[string]struct{}, len(keys))
@@ -254,7 +490,7 @@ func (p *Packet) bindSlice(v reflect.Value) error {
 			continue
 		}
 
-		if err := setValue(et, i, field, element.Value); err != nil {
+		if err := setValue(et, i, tags[i], field, element.Value); err != nil {
 			return err
 		}
 
@@ -266,27 +502,134 @@ func (p *Packet) bindSlice(v reflect.Value) error {
 	// Add current result if we found (some) keys, but never found a 2nd result
 	// (we only "flush" current to results on the n+1st result)
 	if len(keys) != 0 {
+		if err := completeElement(et, tags, current, keys); err != nil {
+			return err
+		}
 		v.Set(reflect.Append(v, current))
 	}
 
 	return nil
 }
 
-func setValue(t reflect.Type, i int, v reflect.Value, value string) error {
-	switch v.Kind() {
-	case reflect.Int, reflect.Int32, reflect.Int64:
+// completeElement Checks that a slice element contains all required keys and binds defaults for missing keys.
+func completeElement(t reflect.Type, tags []fieldTag, v reflect.Value, keys map[string]struct{}) error {
+	for i, tag := range tags {
+		if tag.key == "" || !v.Field(i).CanSet() {
+			continue
+		}
+		if _, ok := keys[tag.key]; ok {
+			continue
+		}
+
+		if tag.required {
+			return fmt.Errorf("%s.%s: %w: %q", t.Name(), t.Field(i).Name, ErrMissingKey, tag.key)
+		}
+		if tag.defaultValue != nil {
+			if err := setValue(t, i, tag, v.Field(i), *tag.defaultValue); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// Unmarshaler Is implemented by types that can bind a packet value to themselves.
+type Unmarshaler interface {
+	UnmarshalGamespy(value string) error
+}
+
+var (
+	timeType      = reflect.TypeFor[time.Time]()
+	durationType  = reflect.TypeFor[time.Duration]()
+	byteSliceType = reflect.TypeFor[[]byte]()
+)
+
+// setValues Binds each of the values to an element of the slice v.
+func setValues(t reflect.Type, i int, tag fieldTag, v reflect.Value, values []string) error {
+	s := reflect.MakeSlice(v.Type(), len(values), len(values))
+	for j, value := range values {
+		if err := setValue(t, i, tag, s.Index(j), value); err != nil {
+			return err
+		}
+	}
+	v.Set(s)
+	return nil
+}
+
+func setValue(t reflect.Type, i int, tag fieldTag, v reflect.Value, value string) error {
+	if err := decodeValue(tag, v, value); err != nil {
+		return fmt.Errorf("%s.%s: %w", t.Name(), t.Field(i).Name, err)
+	}
+	return nil
+}
+
+func decodeValue(tag fieldTag, v reflect.Value, value string) error {
+	if v.Kind() == reflect.Pointer {
+		v.Set(reflect.New(v.Type().Elem()))
+		return decodeValue(tag, v.Elem(), value)
+	}
+
+	// time.Time implements encoding.TextUnmarshaler, but is sent as a unix timestamp
+	if v.CanAddr() && v.Type() != timeType {
+		switch u := v.Addr().Interface().(type) {
+		case Unmarshaler:
+			return u.UnmarshalGamespy(value)
+		case encoding.TextUnmarshaler:
+			return u.UnmarshalText([]byte(value))
+		}
+	}
+
+	switch v.Type() {
+	case timeType:
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(fromUnix(n, tag.unit)))
+		return nil
+	case durationType:
 		n, err := strconv.ParseInt(value, 10, 64)
 		if err != nil {
-			return fmt.Errorf("%s.%s: %w", t.Name(), t.Field(i).Name, err)
+			return err
+		}
+		v.SetInt(n * int64(tag.unit))
+		return nil
+	case byteSliceType:
+		v.SetBytes([]byte(value))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, v.Type().Bits())
+		if err != nil {
+			return err
 		}
 		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(value, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(value, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	case reflect.Bool:
+		// Flags are usually sent as 0/1, which ParseBool accepts along with true/false
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
 	case reflect.String:
 		v.SetString(value)
-	case reflect.Pointer:
-		v.Set(reflect.New(v.Type().Elem()))
-		return setValue(t, i, v.Elem(), value)
 	default:
-		return fmt.Errorf("%s.%s: unsupported field type: %s", t.Name(), t.Field(i).Name, v.Type())
+		return fmt.Errorf("unsupported field type: %s", v.Type())
 	}
 
 	return nil
@@ -0,0 +1,132 @@
+package gamespy
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+// benchmarkPacket is a login request as sent by game clients
+var benchmarkPacket = []byte(`\login\\challenge\YJk5UFExKBwn0PEpOpinWHsRCDcfejyJ\uniquenick\mister249\` +
+	`response\1c5a1eb9e75006ec317bd6d8a2c09969\port\2475\productid\10439\gamename\battlefield2\namespaceid\12\` +
+	`sdkrevision\3\quiet\0\sdkrevision\3\firewall\1\partnerid\0\id\1\final\`)
+
+func BenchmarkNewPacketFromBytes(b *testing.B) {
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err := NewPacketFromBytes(benchmarkPacket); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkNewPacketFromBytes_Get(b *testing.B) {
+	b.ReportAllocs()
+	for b.Loop() {
+		packet, err := NewPacketFromBytes(benchmarkPacket)
+		if err != nil {
+			b.Fatal(err)
+		}
+		_ = packet.Get("uniquenick")
+		_ = packet.Get("gamename")
+	}
+}
+
+func BenchmarkNewPacketFromBytes_Bind(b *testing.B) {
+	var login struct {
+		Challenge  string `gamespy:"challenge"`
+		UniqueNick string `gamespy:"uniquenick"`
+		Response   string `gamespy:"response"`
+		Port       int    `gamespy:"port"`
+		GameName   string `gamespy:"gamename"`
+		ID         int    `gamespy:"id"`
+	}
+	b.ReportAllocs()
+	for b.Loop() {
+		packet, err := NewPacketFromBytes(benchmarkPacket)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if err = packet.Bind(&login); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkNewPacketView_Get(b *testing.B) {
+	b.ReportAllocs()
+	for b.Loop() {
+		view, err := NewPacketView(benchmarkPacket)
+		if err != nil {
+			b.Fatal(err)
+		}
+		_ = view.Get("uniquenick")
+		_ = view.Get("gamename")
+	}
+}
+
+func BenchmarkReader_ReadPacket(b *testing.B) {
+	b.ReportAllocs()
+	data := bytes.Repeat(benchmarkPacket, 64)
+	r := bytes.NewReader(data)
+	reader := NewReader(r)
+	for b.Loop() {
+		if _, err := reader.ReadPacket(); err == io.EOF {
+			r.Reset(data)
+		} else if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkPacket_Bytes(b *testing.B) {
+	packet := mustPacket(b)
+	b.ReportAllocs()
+	for b.Loop() {
+		_ = packet.Bytes()
+	}
+}
+
+func BenchmarkPacket_String(b *testing.B) {
+	packet := mustPacket(b)
+	b.ReportAllocs()
+	for b.Loop() {
+		_ = packet.String()
+	}
+}
+
+func BenchmarkWriter_WritePacket(b *testing.B) {
+	packet := mustPacket(b)
+	w := NewWriter(io.Discard)
+	b.ReportAllocs()
+	for b.Loop() {
+		if err := w.WritePacket(packet); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkPacket_GetAll(b *testing.B) {
+	packet := mustPacket(b)
+	b.ReportAllocs()
+	for b.Loop() {
+		_ = packet.GetAll("sdkrevision")
+	}
+}
+
+func BenchmarkPacket_Set(b *testing.B) {
+	packet := mustPacket(b)
+	b.ReportAllocs()
+	for b.Loop() {
+		packet.Set("id", "2")
+	}
+}
+
+func mustPacket(b *testing.B) *Packet {
+	b.Helper()
+	packet, err := NewPacketFromBytes(benchmarkPacket)
+	if err != nil {
+		b.Fatal(err)
+	}
+	return packet
+}
@@ -1,7 +1,11 @@
 package gamespy
 
 import (
+	"net/netip"
+	"slices"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -207,12 +211,22 @@ func TestFromBytes(t *testing.T) {
 				require.ErrorContains(t, err, tt.wantErrContains)
 			} else {
 				require.NoError(t, err)
-				require.Equal(t, tt.expectedPacket, packet)
+				require.Equal(t, elementsOf(tt.expectedPacket), elementsOf(packet))
 			}
 		})
 	}
 }
 
+// elementsOf Returns the key/value pairs of each packet, so parsed packets can be compared to packets built from
+// elements.
+func elementsOf(packets ...*Packet) [][]KeyValuePair {
+	elements := make([][]KeyValuePair, 0, len(packets))
+	for _, packet := range packets {
+		elements = append(elements, slices.Collect(packet.All()))
+	}
+	return elements
+}
+
 func TestGamespyPacket_Set(t *testing.T) {
 	t.Run("adds initial key", func(t *testing.T) {
 		// GIVEN
@@ -279,6 +293,45 @@ func TestGamespyPacket_Set(t *testing.T) {
 			},
 		}, packet.elements)
 	})
+
+	t.Run("does not modify elements passed to NewPacket", func(t *testing.T) {
+		// GIVEN
+		elements := []KeyValuePair{
+			{Key: "a-key", Value: "a-value"},
+			{Key: "b-key", Value: "b-value"},
+		}
+		packet := NewPacket(elements...)
+
+		// WHEN
+		packet.Set("a-key", "new-value")
+		packet.Remove("b-key")
+
+		// THEN
+		assert.Equal(t, []KeyValuePair{
+			{Key: "a-key", Value: "a-value"},
+			{Key: "b-key", Value: "b-value"},
+		}, elements)
+		assert.Equal(t, []KeyValuePair{
+			{Key: "a-key", Value: "new-value"},
+		}, packet.elements)
+	})
+
+	t.Run("updates key of parsed packet", func(t *testing.T) {
+		// GIVEN
+		packet, err := NewPacketFromBytes([]byte(`\a-key\a-value\b-key\b-value\final\`))
+		require.NoError(t, err)
+
+		// WHEN
+		packet.Set("a-key", "new-value")
+
+		// THEN
+		assert.Equal(t, []KeyValuePair{
+			{Key: "b-key", Value: "b-value"},
+			{Key: "a-key", Value: "new-value"},
+		}, packet.elements)
+		assert.Nil(t, packet.spans)
+		assert.Equal(t, `\b-key\b-value\a-key\new-value\final\`, packet.String())
+	})
 }
 
 func TestGamespyPacket_Add(t *testing.T) {
@@ -310,6 +363,63 @@ func TestGamespyPacket_Add(t *testing.T) {
 	})
 }
 
+func TestGamespyPacket_Append(t *testing.T) {
+	t.Run("appends elements of other packets", func(t *testing.T) {
+		// GIVEN
+		packet := &Packet{
+			elements: []KeyValuePair{
+				{
+					Key:   "nr",
+					Value: "0",
+				},
+			},
+		}
+		first := &Packet{
+			elements: []KeyValuePair{
+				{
+					Key:   "nick",
+					Value: "a-nick",
+				},
+				{
+					Key:   "nick",
+					Value: "b-nick",
+				},
+			},
+		}
+		second := &Packet{
+			elements: []KeyValuePair{
+				{
+					Key:   "ndone",
+					Value: "",
+				},
+			},
+		}
+
+		// WHEN
+		packet.Append(first, second)
+
+		// THEN
+		assert.Equal(t, []KeyValuePair{
+			{
+				Key:   "nr",
+				Value: "0",
+			},
+			{
+				Key:   "nick",
+				Value: "a-nick",
+			},
+			{
+				Key:   "nick",
+				Value: "b-nick",
+			},
+			{
+				Key:   "ndone",
+				Value: "",
+			},
+		}, packet.elements)
+	})
+}
+
 func TestGamespyPacket_Lookup(t *testing.T) {
 	const key = "key"
 	const value = "value"
@@ -596,7 +706,62 @@ func TestPacket_Bind(t *testing.T) {
 			require.ErrorContains(t, err, "s.Field: strconv.ParseInt")
 		})
 
-		t.Run("fails for unsupported type field", func(t *testing.T) {
+		t.Run("binds extended types", func(t *testing.T) {
+			// GIVEN
+			type s struct {
+				Bool          bool          `gamespy:"bool"`
+				Uint8         uint8         `gamespy:"uint8"`
+				Uint64        uint64        `gamespy:"uint64"`
+				Float32       float32       `gamespy:"float32"`
+				Float64       *float64      `gamespy:"float64"`
+				Time          time.Time     `gamespy:"time"`
+				TimeMillis    time.Time     `gamespy:"time-ms,unit=ms"`
+				Duration      time.Duration `gamespy:"duration"`
+				DurationMilli time.Duration `gamespy:"duration-ms,unit=ms"`
+				Bytes         []byte        `gamespy:"bytes"`
+				Unmarshaler   upperString   `gamespy:"unmarshaler"`
+				Text          netip.Addr    `gamespy:"text"`
+			}
+			packet := &Packet{
+				elements: []KeyValuePair{
+					{Key: "bool", Value: "1"},
+					{Key: "uint8", Value: "255"},
+					{Key: "uint64", Value: "18446744073709551615"},
+					{Key: "float32", Value: "1.5"},
+					{Key: "float64", Value: "-0.25"},
+					{Key: "time", Value: "1700000000"},
+					{Key: "time-ms", Value: "1700000000123"},
+					{Key: "duration", Value: "90"},
+					{Key: "duration-ms", Value: "1500"},
+					{Key: "bytes", Value: "some\x00data"},
+					{Key: "unmarshaler", Value: "some-value"},
+					{Key: "text", Value: "10.0.0.1"},
+				},
+			}
+
+			// WHEN
+			actual := new(s)
+			err := packet.Bind(actual)
+
+			// THEN
+			require.NoError(t, err)
+			assert.Equal(t, &s{
+				Bool:          true,
+				Uint8:         255,
+				Uint64:        18446744073709551615,
+				Float32:       1.5,
+				Float64:       toPointer(-0.25),
+				Time:          time.Unix(1700000000, 0).UTC(),
+				TimeMillis:    time.UnixMilli(1700000000123).UTC(),
+				Duration:      90 * time.Second,
+				DurationMilli: 1500 * time.Millisecond,
+				Bytes:         []byte("some\x00data"),
+				Unmarshaler:   "SOME-VALUE",
+				Text:          netip.MustParseAddr("10.0.0.1"),
+			}, actual)
+		})
+
+		t.Run("fails for value out of range", func(t *testing.T) {
 			// GIVEN
 			type s struct {
 				Field uint8 `gamespy:"field"`
@@ -605,7 +770,29 @@ func TestPacket_Bind(t *testing.T) {
 				elements: []KeyValuePair{
 					{
 						Key:   "field",
-						Value: "0xff",
+						Value: "256",
+					},
+				},
+			}
+
+			// WHEN
+			actual := new(s)
+			err := packet.Bind(actual)
+
+			// THEN
+			require.ErrorContains(t, err, "s.Field: strconv.ParseUint: parsing \"256\": value out of range")
+		})
+
+		t.Run("fails for invalid unit", func(t *testing.T) {
+			// GIVEN
+			type s struct {
+				Field time.Duration `gamespy:"field,unit=h"`
+			}
+			packet := &Packet{
+				elements: []KeyValuePair{
+					{
+						Key:   "field",
+						Value: "1",
 					},
 				},
 			}
@@ -615,7 +802,180 @@ func TestPacket_Bind(t *testing.T) {
 			err := packet.Bind(actual)
 
 			// THEN
-			require.ErrorContains(t, err, "s.Field: unsupported field type: uint8")
+			require.ErrorContains(t, err, "s.Field: invalid unit: \"h\"")
+		})
+
+		t.Run("binds according to tag options", func(t *testing.T) {
+			// GIVEN
+			type s struct {
+				Required string            `gamespy:"required,required"`
+				Default  int               `gamespy:"default,default=1"`
+				First    string            `gamespy:"first,first"`
+				All      []int             `gamespy:"all,all"`
+				Remain   map[string]string `gamespy:",remain"`
+			}
+			packet := &Packet{
+				elements: []KeyValuePair{
+					{Key: "required", Value: ""},
+					{Key: "first", Value: "first-value"},
+					{Key: "all", Value: "1"},
+					{Key: "unknown", Value: "some-value"},
+					{Key: "first", Value: "second-value"},
+					{Key: "all", Value: "2"},
+				},
+			}
+
+			// WHEN
+			actual := new(s)
+			err := packet.Bind(actual)
+
+			// THEN
+			require.NoError(t, err)
+			assert.Equal(t, &s{
+				Default: 1,
+				First:   "first-value",
+				All:     []int{1, 2},
+				Remain: map[string]string{
+					"unknown": "some-value",
+				},
+			}, actual)
+		})
+
+		t.Run("binds remainder to key value pairs", func(t *testing.T) {
+			// GIVEN
+			type s struct {
+				Field  string         `gamespy:"field"`
+				Remain []KeyValuePair `gamespy:",remain"`
+			}
+			packet := &Packet{
+				elements: []KeyValuePair{
+					{Key: "unknown", Value: "first-value"},
+					{Key: "field", Value: "some-value"},
+					{Key: "unknown", Value: "second-value"},
+				},
+			}
+
+			// WHEN
+			actual := new(s)
+			err := packet.Bind(actual)
+
+			// THEN
+			require.NoError(t, err)
+			assert.Equal(t, &s{
+				Field: "some-value",
+				Remain: []KeyValuePair{
+					{Key: "unknown", Value: "first-value"},
+					{Key: "unknown", Value: "second-value"},
+				},
+			}, actual)
+		})
+
+		t.Run("fails for missing required key", func(t *testing.T) {
+			// GIVEN
+			type s struct {
+				Field string `gamespy:"field,required"`
+			}
+			packet := &Packet{
+				elements: []KeyValuePair{
+					{Key: "other", Value: "some-value"},
+				},
+			}
+
+			// WHEN
+			actual := new(s)
+			err := packet.Bind(actual)
+
+			// THEN
+			require.ErrorIs(t, err, ErrMissingKey)
+			require.ErrorContains(t, err, "s.Field: gamespy packet is missing required key: \"field\"")
+		})
+
+		t.Run("fails for invalid tag options", func(t *testing.T) {
+			type test struct {
+				name            string
+				target          any
+				wantErrContains string
+			}
+
+			tests := []test{
+				{
+					name: "first and all",
+					target: new(struct {
+						Field []string `gamespy:"field,first,all"`
+					}),
+					wantErrContains: "Field: tag options first, last and all are mutually exclusive",
+				},
+				{
+					name: "required and default",
+					target: new(struct {
+						Field string `gamespy:"field,required,default=1"`
+					}),
+					wantErrContains: "Field: tag options required and default are mutually exclusive",
+				},
+				{
+					name: "all for non-slice field",
+					target: new(struct {
+						Field string `gamespy:"field,all"`
+					}),
+					wantErrContains: "Field: tag option all requires a slice field",
+				},
+				{
+					name: "remain with key",
+					target: new(struct {
+						Field map[string]string `gamespy:"field,remain"`
+					}),
+					wantErrContains: "Field: tag option remain cannot be used with a key",
+				},
+				{
+					name: "remain for unsupported field type",
+					target: new(struct {
+						Field map[string]int `gamespy:",remain"`
+					}),
+					wantErrContains: "Field: tag option remain requires a map[string]string or []KeyValuePair field",
+				},
+				{
+					name: "unknown option",
+					target: new(struct {
+						Field string `gamespy:"field,omitempty"`
+					}),
+					wantErrContains: "Field: unknown tag option: \"omitempty\"",
+				},
+			}
+
+			for _, tt := range tests {
+				t.Run(tt.name, func(t *testing.T) {
+					// GIVEN
+					packet := NewPacket(KeyValuePair{Key: "field", Value: "some-value"})
+
+					// WHEN
+					err := packet.Bind(tt.target)
+
+					// THEN
+					require.ErrorContains(t, err, tt.wantErrContains)
+				})
+			}
+		})
+
+		t.Run("fails for unsupported type field", func(t *testing.T) {
+			// GIVEN
+			type s struct {
+				Field complex64 `gamespy:"field"`
+			}
+			packet := &Packet{
+				elements: []KeyValuePair{
+					{
+						Key:   "field",
+						Value: "1+2i",
+					},
+				},
+			}
+
+			// WHEN
+			actual := new(s)
+			err := packet.Bind(actual)
+
+			// THEN
+			require.ErrorContains(t, err, "s.Field: unsupported field type: complex64")
 		})
 	})
 
@@ -802,6 +1162,75 @@ func TestPacket_Bind(t *testing.T) {
 			}, actual)
 		})
 
+		t.Run("binds default for missing key", func(t *testing.T) {
+			// GIVEN
+			type s struct {
+				Name  string `gamespy:"name"`
+				Score int    `gamespy:"score,default=-1"`
+			}
+			packet := &Packet{
+				elements: []KeyValuePair{
+					{Key: "name", Value: "first-name"},
+					{Key: "score", Value: "10"},
+					{Key: "name", Value: "second-name"},
+				},
+			}
+
+			// WHEN
+			actual := make([]s, 0, 2)
+			err := packet.Bind(&actual)
+
+			// THEN
+			require.NoError(t, err)
+			assert.Equal(t, []s{
+				{Name: "first-name", Score: 10},
+				{Name: "second-name", Score: -1},
+			}, actual)
+		})
+
+		t.Run("fails for missing required key", func(t *testing.T) {
+			// GIVEN
+			type s struct {
+				Name  string `gamespy:"name"`
+				Score int    `gamespy:"score,required"`
+			}
+			packet := &Packet{
+				elements: []KeyValuePair{
+					{Key: "name", Value: "first-name"},
+					{Key: "score", Value: "10"},
+					{Key: "name", Value: "second-name"},
+				},
+			}
+
+			// WHEN
+			actual := make([]s, 0, 2)
+			err := packet.Bind(&actual)
+
+			// THEN
+			require.ErrorIs(t, err, ErrMissingKey)
+			require.ErrorContains(t, err, "s.Score")
+		})
+
+		t.Run("fails for unsupported tag option", func(t *testing.T) {
+			// GIVEN
+			type s struct {
+				Field  string            `gamespy:"field"`
+				Remain map[string]string `gamespy:",remain"`
+			}
+			packet := &Packet{
+				elements: []KeyValuePair{
+					{Key: "field", Value: "some-value"},
+				},
+			}
+
+			// WHEN
+			actual := make([]s, 0, 1)
+			err := packet.Bind(&actual)
+
+			// THEN
+			require.ErrorContains(t, err, "s.Remain: tag options first, all and remain are not supported for slices")
+		})
+
 		t.Run("fails for invalid integer value", func(t *testing.T) {
 			// GIVEN
 			type s struct {
@@ -979,6 +1408,47 @@ func TestGamespyPacket_Bytes(t *testing.T) {
 	}
 }
 
+// upperString Implements Unmarshaler by upper-casing and Marshaler by lower-casing values
+type upperString string
+
+func (s *upperString) UnmarshalGamespy(value string) error {
+	*s = upperString(strings.ToUpper(value))
+	return nil
+}
+
+func (s upperString) MarshalGamespy() (string, error) {
+	return strings.ToLower(string(s)), nil
+}
+
 func toPointer[T any](v T) *T {
 	return &v
 }
+
+func TestGamespyPacket_WriteTo(t *testing.T) {
+	// GIVEN
+	packet := NewPacket(
+		KeyValuePair{Key: "lc", Value: "1"},
+		KeyValuePair{Key: "challenge", Value: "TcP1s0FtTB"},
+	)
+	w := &countingWriter{}
+
+	// WHEN
+	n, err := packet.WriteTo(w)
+
+	// THEN
+	require.NoError(t, err)
+	assert.Equal(t, int64(33), n)
+	assert.Equal(t, "\\lc\\1\\challenge\\TcP1s0FtTB\\final\\", w.String())
+	// Packets must be written at once, since (UDP) protocols rely on one write per packet
+	assert.Equal(t, 1, w.writes)
+}
+
+type countingWriter struct {
+	strings.Builder
+	writes int
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.writes++
+	return w.Builder.Write(p)
+}
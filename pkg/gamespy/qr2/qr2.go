@@ -0,0 +1,182 @@
+// Package qr2 Implements the master server side of the GameSpy Query & Reporting 2 (QR2) protocol, which game servers
+// use to report themselves to the master server via UDP.
+package qr2
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/dogclan/dumbspy/pkg/gamespy/codec"
+)
+
+type PacketType byte
+
+// Following https://github.com/openspy/openspy-core/blob/5993df54c6b289361228920fa0db7209aed4cfe5/code/qr/server/QRDriver.h
+const (
+	PacketQuery            PacketType = 0x00
+	PacketChallenge        PacketType = 0x01
+	PacketEcho             PacketType = 0x02
+	PacketHeartbeat        PacketType = 0x03
+	PacketAddError         PacketType = 0x04
+	PacketEchoResponse     PacketType = 0x05
+	PacketClientMessage    PacketType = 0x06
+	PacketClientMessageAck PacketType = 0x07
+	PacketKeepAlive        PacketType = 0x08
+	PacketAvailable        PacketType = 0x09
+	PacketClientRegistered PacketType = 0x0A
+)
+
+// Values of the "statechanged" heartbeat key
+const (
+	StateNormal   = "1"
+	StateExiting  = "2"
+	StateStarting = "3"
+)
+
+// Availability The status of a game as reported in replies to availability checks.
+type Availability byte
+
+const (
+	Available              Availability = 0
+	Unavailable            Availability = 1
+	TemporarilyUnavailable Availability = 2
+)
+
+var availabilityNames = map[Availability]string{
+	Available:              "available",
+	Unavailable:            "unavailable",
+	TemporarilyUnavailable: "temporarily-unavailable",
+}
+
+func (a Availability) String() string {
+	if name, ok := availabilityNames[a]; ok {
+		return name
+	}
+	return fmt.Sprintf("Availability(%d)", byte(a))
+}
+
+// ParseAvailability Returns the availability with the given name (as returned by String).
+func ParseAvailability(s string) (Availability, error) {
+	for availability, name := range availabilityNames {
+		if name == s {
+			return availability, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown availability %q", s)
+}
+
+// headerLength is the length of the packet type and instance key sent by game servers
+const headerLength = 5
+
+var (
+	// magic prefixes all packets sent by the master server
+	magic = []byte{0xFE, 0xFD}
+
+	ErrShortPacket    = errors.New("packet too short")
+	ErrMissingNulByte = codec.ErrMissingNulByte
+)
+
+// Packet A packet sent by a game server. The instance key is chosen by the game server and must be echoed back in
+// replies.
+type Packet struct {
+	Type        PacketType
+	InstanceKey [4]byte
+	Data        []byte
+}
+
+// ParsePacket Parses the header of a packet sent by a game server. Data references b.
+func ParsePacket(b []byte) (Packet, error) {
+	if len(b) < headerLength {
+		return Packet{}, ErrShortPacket
+	}
+
+	p := Packet{
+		Type: PacketType(b[0]),
+		Data: b[headerLength:],
+	}
+	copy(p.InstanceKey[:], b[1:headerLength])
+	return p, nil
+}
+
+// Heartbeat Contains the server info, players and teams reported by a game server. Player and team keys usually carry
+// a suffix of "_" or "_t" (e.g. "player_", "score_t"). Should a server report an info key more than once, Info holds
+// the last value.
+type Heartbeat struct {
+	Info    map[string]string
+	Players []map[string]string
+	Teams   []map[string]string
+}
+
+// ParseHeartbeat Parses the data of a heartbeat packet. The server info section consists of null-terminated key and
+// value strings and is terminated by an empty key. Player and team sections each start with a two byte (big-endian)
+// count, followed by null-terminated keys terminated by an empty key and one value per key for each player/team.
+// Servers may omit player and team sections altogether.
+func ParseHeartbeat(data []byte) (*Heartbeat, error) {
+	d := codec.NewDecoder(data)
+	info := d.KeyValues()
+	if err := d.Err(); err != nil {
+		return nil, fmt.Errorf("server info: %w", err)
+	}
+
+	hb := &Heartbeat{
+		Info: make(map[string]string, len(info)),
+	}
+	for _, pair := range info {
+		hb.Info[pair.Key] = pair.Value
+	}
+
+	if d.Len() > 0 {
+		if hb.Players = d.Table(); d.Err() != nil {
+			return nil, fmt.Errorf("players: %w", d.Err())
+		}
+	}
+	if d.Len() > 0 {
+		if hb.Teams = d.Table(); d.Err() != nil {
+			return nil, fmt.Errorf("teams: %w", d.Err())
+		}
+	}
+
+	return hb, nil
+}
+
+// ParseChallengeResponse Returns the response string of a challenge packet.
+func ParseChallengeResponse(data []byte) (string, error) {
+	d := codec.NewDecoder(data)
+	response := d.CString()
+	return response, d.Err()
+}
+
+// NewChallenge Returns the challenge packet sent to game servers after their first heartbeat.
+func NewChallenge(instanceKey [4]byte, challenge string) []byte {
+	e := newReply(PacketChallenge, instanceKey)
+	e.CString(challenge)
+	return e.Bytes()
+}
+
+// NewClientRegistered Returns the packet confirming that a game server answered the challenge and is now listed.
+func NewClientRegistered(instanceKey [4]byte) []byte {
+	return newReply(PacketClientRegistered, instanceKey).Bytes()
+}
+
+// ParseAvailabilityCheck Returns the game name of an availability check packet. Unlike other packets, these are sent
+// by game clients rather than servers and carry no instance key.
+func ParseAvailabilityCheck(data []byte) (string, error) {
+	d := codec.NewDecoder(data)
+	gameName := d.CString()
+	return gameName, d.Err()
+}
+
+// NewAvailabilityReply Returns the reply to an availability check.
+func NewAvailabilityReply(availability Availability) []byte {
+	b := newReply(PacketAvailable, [4]byte{}).Bytes()
+	// Replies only contain three padding bytes before the status
+	return append(b[:len(b)-1], byte(availability))
+}
+
+func newReply(t PacketType, instanceKey [4]byte) *codec.Encoder {
+	e := codec.NewEncoder(make([]byte, 0, len(magic)+headerLength))
+	e.Raw(magic)
+	e.Uint8(byte(t))
+	e.Raw(instanceKey[:])
+	return e
+}
@@ -0,0 +1,226 @@
+package qr2
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePacket(t *testing.T) {
+	t.Run("parses header", func(t *testing.T) {
+		// WHEN
+		p, err := ParsePacket([]byte{0x08, 0x01, 0x02, 0x03, 0x04, 'a'})
+
+		// THEN
+		require.NoError(t, err)
+		assert.Equal(t, Packet{
+			Type:        PacketKeepAlive,
+			InstanceKey: [4]byte{0x01, 0x02, 0x03, 0x04},
+			Data:        []byte("a"),
+		}, p)
+	})
+
+	t.Run("fails for short packet", func(t *testing.T) {
+		// WHEN
+		_, err := ParsePacket([]byte{0x08, 0x01, 0x02})
+
+		// THEN
+		assert.ErrorIs(t, err, ErrShortPacket)
+	})
+}
+
+func TestParseHeartbeat(t *testing.T) {
+	type test struct {
+		name              string
+		givenData         []byte
+		expectedHeartbeat *Heartbeat
+		wantErrContains   string
+	}
+
+	tests := []test{
+		{
+			name: "parses info, players and teams",
+			givenData: concat(
+				"hostname\x00some server\x00gamename\x00battlefield2\x00statechanged\x003\x00\x00",
+				"\x00\x02", "player_\x00score_\x00\x00", "mister249\x0010\x00", "someone\x00-1\x00",
+				"\x00\x01", "team_t\x00\x00", "MEC\x00",
+			),
+			expectedHeartbeat: &Heartbeat{
+				Info: map[string]string{
+					"hostname":     "some server",
+					"gamename":     "battlefield2",
+					"statechanged": StateStarting,
+				},
+				Players: []map[string]string{
+					{"player_": "mister249", "score_": "10"},
+					{"player_": "someone", "score_": "-1"},
+				},
+				Teams: []map[string]string{
+					{"team_t": "MEC"},
+				},
+			},
+		},
+		{
+			name:      "parses info only",
+			givenData: []byte("gamename\x00battlefield2\x00\x00"),
+			expectedHeartbeat: &Heartbeat{
+				Info: map[string]string{
+					"gamename": "battlefield2",
+				},
+			},
+		},
+		{
+			name:      "keeps last value of duplicate info key",
+			givenData: []byte("numplayers\x002\x00gamename\x00battlefield2\x00numplayers\x003\x00\x00"),
+			expectedHeartbeat: &Heartbeat{
+				Info: map[string]string{
+					"gamename":   "battlefield2",
+					"numplayers": "3",
+				},
+			},
+		},
+		{
+			name:      "parses empty player section",
+			givenData: []byte("gamename\x00battlefield2\x00\x00\x00\x00player_\x00\x00"),
+			expectedHeartbeat: &Heartbeat{
+				Info: map[string]string{
+					"gamename": "battlefield2",
+				},
+				Players: []map[string]string{},
+			},
+		},
+		{
+			name:            "fails for missing info value",
+			givenData:       []byte("gamename\x00battlefield2"),
+			wantErrContains: "server info: key \"gamename\": missing string terminator",
+		},
+		{
+			name:            "fails for unterminated info section",
+			givenData:       []byte("gamename\x00battlefield2\x00"),
+			wantErrContains: "server info: missing string terminator",
+		},
+		{
+			name:            "fails for truncated player count",
+			givenData:       []byte("gamename\x00battlefield2\x00\x00\x00"),
+			wantErrContains: "players: data too short",
+		},
+		{
+			name:            "fails for missing player values",
+			givenData:       concat("gamename\x00battlefield2\x00\x00", "\x00\x02", "player_\x00\x00", "mister249\x00"),
+			wantErrContains: "players: key \"player_\": missing string terminator",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// WHEN
+			hb, err := ParseHeartbeat(tt.givenData)
+
+			// THEN
+			if tt.wantErrContains != "" {
+				require.ErrorContains(t, err, tt.wantErrContains)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, tt.expectedHeartbeat, hb)
+			}
+		})
+	}
+}
+
+func TestParseChallengeResponse(t *testing.T) {
+	// WHEN
+	response, err := ParseChallengeResponse([]byte("Mz4HvTqEXwiIvdk=\x00"))
+
+	// THEN
+	require.NoError(t, err)
+	assert.Equal(t, "Mz4HvTqEXwiIvdk=", response)
+}
+
+func TestNewChallenge(t *testing.T) {
+	// WHEN
+	b := NewChallenge([4]byte{0x01, 0x02, 0x03, 0x04}, "abc")
+
+	// THEN
+	assert.Equal(t, []byte{0xFE, 0xFD, 0x01, 0x01, 0x02, 0x03, 0x04, 'a', 'b', 'c', 0x00}, b)
+}
+
+func TestNewClientRegistered(t *testing.T) {
+	// WHEN
+	b := NewClientRegistered([4]byte{0x01, 0x02, 0x03, 0x04})
+
+	// THEN
+	assert.Equal(t, []byte{0xFE, 0xFD, 0x0A, 0x01, 0x02, 0x03, 0x04}, b)
+}
+
+func TestParseAvailabilityCheck(t *testing.T) {
+	// GIVEN
+	p, err := ParsePacket([]byte("\x09\x00\x00\x00\x00battlefield2\x00"))
+	require.NoError(t, err)
+
+	// WHEN
+	gameName, err := ParseAvailabilityCheck(p.Data)
+
+	// THEN
+	require.NoError(t, err)
+	assert.Equal(t, "battlefield2", gameName)
+}
+
+func TestNewAvailabilityReply(t *testing.T) {
+	// WHEN
+	b := NewAvailabilityReply(TemporarilyUnavailable)
+
+	// THEN
+	assert.Equal(t, []byte{0xFE, 0xFD, 0x09, 0x00, 0x00, 0x00, 0x02}, b)
+}
+
+func TestParseAvailability(t *testing.T) {
+	type test struct {
+		name                 string
+		givenName            string
+		expectedAvailability Availability
+		wantErrContains      string
+	}
+
+	tests := []test{
+		{
+			name:                 "parses available",
+			givenName:            "available",
+			expectedAvailability: Available,
+		},
+		{
+			name:                 "parses temporarily unavailable",
+			givenName:            "temporarily-unavailable",
+			expectedAvailability: TemporarilyUnavailable,
+		},
+		{
+			name:            "fails for unknown availability",
+			givenName:       "down",
+			wantErrContains: "unknown availability \"down\"",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// WHEN
+			availability, err := ParseAvailability(tt.givenName)
+
+			// THEN
+			if tt.wantErrContains != "" {
+				require.ErrorContains(t, err, tt.wantErrContains)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, tt.expectedAvailability, availability)
+				assert.Equal(t, tt.givenName, availability.String())
+			}
+		})
+	}
+}
+
+func concat(parts ...string) []byte {
+	var b []byte
+	for _, part := range parts {
+		b = append(b, part...)
+	}
+	return b
+}
@@ -0,0 +1,388 @@
+package serverbrowser
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Filter A parsed server list filter. Filters use a SQL-like syntax, for example
+// "gametype='ctf' and numplayers>0 and not (hostname like '%test%')". Supported operators are =, ==, !=, <>, <, <=,
+// >, >=, like and not like (with % and _ wildcards) as well as and, or, not and parentheses. Identifiers refer to
+// server info keys, values are compared numerically if both sides are numbers and case-insensitively otherwise.
+// A bare identifier matches if the key's value is neither empty nor "0".
+type Filter struct {
+	root expr
+}
+
+// ParseFilter Parses a filter expression. An empty expression matches all servers.
+func ParseFilter(s string) (*Filter, error) {
+	tokens, err := tokenize(s)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(tokens) == 0 {
+		return &Filter{}, nil
+	}
+
+	p := &parser{tokens: tokens}
+	root, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if !p.done() {
+		return nil, p.unexpected()
+	}
+
+	return &Filter{root: root}, nil
+}
+
+// Match Checks whether a server with the given info matches the filter.
+func (f *Filter) Match(info map[string]string) bool {
+	if f.root == nil {
+		return true
+	}
+	return f.root.match(info)
+}
+
+type expr interface {
+	match(info map[string]string) bool
+}
+
+type andExpr struct {
+	left, right expr
+}
+
+func (e andExpr) match(info map[string]string) bool {
+	return e.left.match(info) && e.right.match(info)
+}
+
+type orExpr struct {
+	left, right expr
+}
+
+func (e orExpr) match(info map[string]string) bool {
+	return e.left.match(info) || e.right.match(info)
+}
+
+type notExpr struct {
+	e expr
+}
+
+func (e notExpr) match(info map[string]string) bool {
+	return !e.e.match(info)
+}
+
+type operand struct {
+	key     string // Server info key (if the operand is an identifier)
+	literal string
+}
+
+func (o operand) value(info map[string]string) string {
+	if o.key != "" {
+		return info[o.key]
+	}
+	return o.literal
+}
+
+func (o operand) match(info map[string]string) bool {
+	v := o.value(info)
+	return v != "" && v != "0"
+}
+
+type comparison struct {
+	left, right operand
+	op          string
+}
+
+func (c comparison) match(info map[string]string) bool {
+	left, right := c.left.value(info), c.right.value(info)
+	switch c.op {
+	case "like":
+		return like(strings.ToLower(left), strings.ToLower(right))
+	case "not like":
+		return !like(strings.ToLower(left), strings.ToLower(right))
+	}
+
+	var cmp int
+	l, err1 := strconv.ParseFloat(left, 64)
+	r, err2 := strconv.ParseFloat(right, 64)
+	if err1 == nil && err2 == nil {
+		switch {
+		case l < r:
+			cmp = -1
+		case l > r:
+			cmp = 1
+		}
+	} else {
+		cmp = strings.Compare(strings.ToLower(left), strings.ToLower(right))
+	}
+
+	switch c.op {
+	case "=", "==":
+		return cmp == 0
+	case "!=", "<>":
+		return cmp != 0
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	default: // ">="
+		return cmp >= 0
+	}
+}
+
+// like Matches s against a SQL like pattern, where % matches any number of characters and _ matches one character.
+// Backtracks to the last % only, so patterns cannot cause exponential run time.
+func like(s, pattern string) bool {
+	si, pi := 0, 0
+	star, next := -1, 0
+	for si < len(s) {
+		switch {
+		case pi < len(pattern) && (pattern[pi] == '_' || pattern[pi] == s[si]):
+			si++
+			pi++
+		case pi < len(pattern) && pattern[pi] == '%':
+			star, next = pi, si
+			pi++
+		case star != -1:
+			// Let the last % consume one more character
+			next++
+			si, pi = next, star+1
+		default:
+			return false
+		}
+	}
+
+	for pi < len(pattern) && pattern[pi] == '%' {
+		pi++
+	}
+	return pi == len(pattern)
+}
+
+type tokenKind int
+
+const (
+	tokenIdentifier tokenKind = iota
+	tokenString
+	tokenNumber
+	tokenOperator
+	tokenKeyword
+	tokenLeftParen
+	tokenRightParen
+)
+
+type token struct {
+	kind  tokenKind
+	value string
+	pos   int
+}
+
+func tokenize(s string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case unicode.IsSpace(rune(c)):
+			i++
+		case c == '(':
+			tokens = append(tokens, token{kind: tokenLeftParen, value: "(", pos: i})
+			i++
+		case c == ')':
+			tokens = append(tokens, token{kind: tokenRightParen, value: ")", pos: i})
+			i++
+		case c == '\'' || c == '"':
+			// Quotes are escaped by doubling them
+			var b strings.Builder
+			j := i + 1
+			for ; ; j++ {
+				if j >= len(s) {
+					return nil, fmt.Errorf("filter: unterminated string at position %d", i)
+				}
+				if s[j] == c {
+					if j+1 < len(s) && s[j+1] == c {
+						b.WriteByte(c)
+						j++
+						continue
+					}
+					break
+				}
+				b.WriteByte(s[j])
+			}
+			tokens = append(tokens, token{kind: tokenString, value: b.String(), pos: i})
+			i = j + 1
+		case strings.ContainsRune("=!<>", rune(c)):
+			op := string(c)
+			if i+1 < len(s) && (s[i+1] == '=' || c == '<' && s[i+1] == '>') {
+				op = s[i : i+2]
+			}
+			if op == "!" {
+				return nil, fmt.Errorf("filter: unexpected \"!\" at position %d", i)
+			}
+			tokens = append(tokens, token{kind: tokenOperator, value: op, pos: i})
+			i += len(op)
+		case c == '-' || c >= '0' && c <= '9':
+			j := i + 1
+			for j < len(s) && (s[j] >= '0' && s[j] <= '9' || s[j] == '.') {
+				j++
+			}
+			if s[i:j] == "-" {
+				return nil, fmt.Errorf("filter: unexpected \"-\" at position %d", i)
+			}
+			tokens = append(tokens, token{kind: tokenNumber, value: s[i:j], pos: i})
+			i = j
+		case c == '_' || unicode.IsLetter(rune(c)):
+			j := i + 1
+			for j < len(s) && (s[j] == '_' || unicode.IsLetter(rune(s[j])) || unicode.IsDigit(rune(s[j]))) {
+				j++
+			}
+			word := s[i:j]
+			switch lower := strings.ToLower(word); lower {
+			case "and", "or", "not", "like":
+				tokens = append(tokens, token{kind: tokenKeyword, value: lower, pos: i})
+			default:
+				tokens = append(tokens, token{kind: tokenIdentifier, value: word, pos: i})
+			}
+			i = j
+		default:
+			return nil, fmt.Errorf("filter: unexpected %q at position %d", c, i)
+		}
+	}
+	return tokens, nil
+}
+
+// parser Parses tokens by recursive descent. Precedence from lowest to highest is or, and, not, comparison.
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) or() (expr, error) {
+	left, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("or") {
+		right, err2 := p.and()
+		if err2 != nil {
+			return nil, err2
+		}
+		left = orExpr{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) and() (expr, error) {
+	left, err := p.not()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("and") {
+		right, err2 := p.not()
+		if err2 != nil {
+			return nil, err2
+		}
+		left = andExpr{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) not() (expr, error) {
+	if p.keyword("not") {
+		e, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+		return notExpr{e: e}, nil
+	}
+	return p.comparison()
+}
+
+func (p *parser) comparison() (expr, error) {
+	if p.done() {
+		return nil, p.unexpected()
+	}
+
+	if p.tokens[p.pos].kind == tokenLeftParen {
+		p.pos++
+		e, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if p.done() || p.tokens[p.pos].kind != tokenRightParen {
+			return nil, p.unexpected()
+		}
+		p.pos++
+		return e, nil
+	}
+
+	left, err := p.operand()
+	if err != nil {
+		return nil, err
+	}
+
+	var op string
+	switch {
+	case !p.done() && p.tokens[p.pos].kind == tokenOperator:
+		op = p.tokens[p.pos].value
+		p.pos++
+	case p.keyword("like"):
+		op = "like"
+	case p.pos+1 < len(p.tokens) && p.tokens[p.pos].kind == tokenKeyword && p.tokens[p.pos].value == "not" &&
+		p.tokens[p.pos+1].kind == tokenKeyword && p.tokens[p.pos+1].value == "like":
+		p.pos += 2
+		op = "not like"
+	default:
+		// Bare operand
+		return left, nil
+	}
+
+	right, err := p.operand()
+	if err != nil {
+		return nil, err
+	}
+	return comparison{left: left, right: right, op: op}, nil
+}
+
+func (p *parser) operand() (operand, error) {
+	if p.done() {
+		return operand{}, p.unexpected()
+	}
+
+	t := p.tokens[p.pos]
+	switch t.kind {
+	case tokenIdentifier:
+		p.pos++
+		return operand{key: t.value}, nil
+	case tokenString, tokenNumber:
+		p.pos++
+		return operand{literal: t.value}, nil
+	default:
+		return operand{}, p.unexpected()
+	}
+}
+
+// keyword Consumes the next token if it is the given keyword.
+func (p *parser) keyword(keyword string) bool {
+	if !p.done() && p.tokens[p.pos].kind == tokenKeyword && p.tokens[p.pos].value == keyword {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) done() bool {
+	return p.pos >= len(p.tokens)
+}
+
+func (p *parser) unexpected() error {
+	if p.done() {
+		return fmt.Errorf("filter: unexpected end of expression")
+	}
+	t := p.tokens[p.pos]
+	return fmt.Errorf("filter: unexpected %q at position %d", t.value, t.pos)
+}
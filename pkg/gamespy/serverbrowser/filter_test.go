@@ -0,0 +1,110 @@
+package serverbrowser
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFilter_Match(t *testing.T) {
+	type test struct {
+		name          string
+		givenFilter   string
+		expectedMatch bool
+	}
+
+	info := map[string]string{
+		"hostname":   "Some CTF Server",
+		"gametype":   "ctf",
+		"numplayers": "12",
+		"maxplayers": "64",
+		"password":   "0",
+		"gamever":    "1.5.3153-802.0",
+	}
+
+	tests := []test{
+		{name: "empty filter", givenFilter: "", expectedMatch: true},
+		{name: "whitespace filter", givenFilter: "  ", expectedMatch: true},
+		{name: "string equality", givenFilter: "gametype='ctf'", expectedMatch: true},
+		{name: "string equality is case-insensitive", givenFilter: "gametype = 'CTF'", expectedMatch: true},
+		{name: "double quoted string", givenFilter: `gametype == "ctf"`, expectedMatch: true},
+		{name: "string inequality", givenFilter: "gametype != 'ctf'", expectedMatch: false},
+		{name: "string inequality (<>)", givenFilter: "gametype <> 'tdm'", expectedMatch: true},
+		{name: "numeric comparison", givenFilter: "numplayers>0", expectedMatch: true},
+		{name: "numeric comparison is not lexicographic", givenFilter: "numplayers > 9", expectedMatch: true},
+		{name: "numeric comparison between keys", givenFilter: "numplayers < maxplayers", expectedMatch: true},
+		{name: "numeric comparison with negative number", givenFilter: "numplayers >= -1", expectedMatch: true},
+		{name: "and", givenFilter: "gametype='ctf' and numplayers>0", expectedMatch: true},
+		{name: "and with false operand", givenFilter: "gametype='ctf' AND numplayers>20", expectedMatch: false},
+		{name: "or", givenFilter: "gametype='tdm' or numplayers>0", expectedMatch: true},
+		{name: "and binds stronger than or", givenFilter: "gametype='tdm' and numplayers>0 or maxplayers=64", expectedMatch: true},
+		{name: "parentheses", givenFilter: "gametype='tdm' and (numplayers>0 or maxplayers=64)", expectedMatch: false},
+		{name: "not", givenFilter: "not gametype='tdm'", expectedMatch: true},
+		{name: "like", givenFilter: "hostname like '%ctf%'", expectedMatch: true},
+		{name: "like with single character wildcard", givenFilter: "gamever like '1._.%'", expectedMatch: true},
+		{name: "like without match", givenFilter: "hostname like 'ctf%'", expectedMatch: false},
+		{name: "not like", givenFilter: "hostname not like '%test%'", expectedMatch: true},
+		{name: "bare key", givenFilter: "password", expectedMatch: false},
+		{name: "negated bare key", givenFilter: "not password", expectedMatch: true},
+		{name: "missing key", givenFilter: "mapname = ''", expectedMatch: true},
+		{name: "escaped quote", givenFilter: "hostname = 'It''s'", expectedMatch: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// GIVEN
+			f, err := ParseFilter(tt.givenFilter)
+			require.NoError(t, err)
+
+			// WHEN
+			match := f.Match(info)
+
+			// THEN
+			assert.Equal(t, tt.expectedMatch, match)
+		})
+	}
+}
+
+func TestParseFilter(t *testing.T) {
+	type test struct {
+		name            string
+		givenFilter     string
+		wantErrContains string
+	}
+
+	tests := []test{
+		{name: "unterminated string", givenFilter: "gametype='ctf", wantErrContains: "filter: unterminated string at position 9"},
+		{name: "missing operand", givenFilter: "gametype=", wantErrContains: "filter: unexpected end of expression"},
+		{name: "dangling and", givenFilter: "gametype='ctf' and", wantErrContains: "filter: unexpected end of expression"},
+		{name: "unclosed parenthesis", givenFilter: "(gametype='ctf'", wantErrContains: "filter: unexpected end of expression"},
+		{name: "unexpected parenthesis", givenFilter: "gametype='ctf')", wantErrContains: "filter: unexpected \")\" at position 14"},
+		{name: "unsupported character", givenFilter: "gametype;", wantErrContains: "filter: unexpected ';' at position 8"},
+		{name: "lone exclamation mark", givenFilter: "!gametype", wantErrContains: "filter: unexpected \"!\" at position 0"},
+		{name: "operator without left operand", givenFilter: "= 'ctf'", wantErrContains: "filter: unexpected \"=\" at position 0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// WHEN
+			_, err := ParseFilter(tt.givenFilter)
+
+			// THEN
+			assert.ErrorContains(t, err, tt.wantErrContains)
+		})
+	}
+}
+
+func TestLike(t *testing.T) {
+	// GIVEN
+	s := strings.Repeat("a", 1000)
+	pattern := strings.Repeat("%a", 50) + "b"
+
+	// WHEN
+	match := like(s, pattern)
+
+	// THEN
+	// Must complete quickly despite many wildcards
+	assert.False(t, match)
+}
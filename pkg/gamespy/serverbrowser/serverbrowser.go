@@ -0,0 +1,232 @@
+// Package serverbrowser Implements the server side of the GameSpy server browsing (SB) protocol version 2, which
+// clients use to fetch server lists from the master server via TCP.
+package serverbrowser
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net/netip"
+	"strings"
+
+	"github.com/dogclan/dumbspy/pkg/gamespy/codec"
+)
+
+type RequestType byte
+
+// Following https://github.com/openspy/openspy-core/blob/5993df54c6b289361228920fa0db7209aed4cfe5/code/serverbrowsing/server/SBDriver.h
+const (
+	RequestServerList     RequestType = 0x00
+	RequestServerInfo     RequestType = 0x01
+	RequestSendMessage    RequestType = 0x02
+	RequestKeepAliveReply RequestType = 0x03
+	RequestMapLoop        RequestType = 0x04
+	RequestPlayerSearch   RequestType = 0x05
+)
+
+// List request options
+const (
+	OptionSendFieldsForAll  = 0x01
+	OptionNoServerList      = 0x02
+	OptionPushUpdates       = 0x04
+	OptionAlternateSourceIP = 0x08
+	OptionSendGroups        = 0x20
+	OptionNoListCache       = 0x40
+	OptionLimitResultCount  = 0x80
+)
+
+// Server flags in list responses
+const (
+	flagUnsolicitedUDP         = 0x01
+	flagPrivateIP              = 0x02
+	flagConnectNegotiate       = 0x04
+	flagICMPIP                 = 0x08
+	flagNonStandardPort        = 0x10
+	flagNonStandardPrivatePort = 0x20
+	flagHasKeys                = 0x40
+	flagHasFullRules           = 0x80
+)
+
+const (
+	// EncodingVersion is the only supported list encoding (enctypeX)
+	EncodingVersion = 3
+
+	// DefaultQueryPort is the query port assumed for servers unless their entry says otherwise
+	DefaultQueryPort = 6500
+
+	keyTypeString = 0x00
+	// valueInline marks a value that is sent inline rather than as an index into the popular values
+	valueInline = 0xFF
+
+	// Length of the length prefix and request type
+	requestHeaderLength = 3
+)
+
+var (
+	ErrShortRequest   = codec.ErrShortData
+	ErrMissingNulByte = codec.ErrMissingNulByte
+)
+
+// ReadRequest Reads a length-prefixed request. Returns the request type and body.
+func ReadRequest(r io.Reader) (RequestType, []byte, error) {
+	var prefix [2]byte
+	if _, err := io.ReadFull(r, prefix[:]); err != nil {
+		return 0, nil, err
+	}
+
+	length := int(binary.BigEndian.Uint16(prefix[:]))
+	if length < requestHeaderLength {
+		return 0, nil, fmt.Errorf("invalid request length: %d", length)
+	}
+
+	b := make([]byte, length-len(prefix))
+	if _, err := io.ReadFull(r, b); err != nil {
+		return 0, nil, err
+	}
+
+	return RequestType(b[0]), b[1:], nil
+}
+
+type ListRequest struct {
+	ProtocolVersion byte
+	EncodingVersion byte
+	GameVersion     uint32
+	QueryGame       string // Game to list servers of
+	FromGame        string // Game sending the request, whose secret key is used to encrypt the list
+	Challenge       [8]byte
+	Filter          string
+	Fields          []string
+	Options         uint32
+	SourceIP        netip.Addr // Only set with OptionAlternateSourceIP
+	MaxResults      int        // Only set with OptionLimitResultCount
+}
+
+// ParseListRequest Parses the body of a server list request.
+func ParseListRequest(b []byte) (*ListRequest, error) {
+	d := codec.NewDecoder(b)
+	req := &ListRequest{
+		ProtocolVersion: d.Uint8(),
+		EncodingVersion: d.Uint8(),
+		GameVersion:     d.Uint32(),
+	}
+	if err := d.Err(); err != nil {
+		return nil, err
+	}
+	if req.QueryGame = d.CString(); d.Err() != nil {
+		return nil, fmt.Errorf("query game: %w", d.Err())
+	}
+	if req.FromGame = d.CString(); d.Err() != nil {
+		return nil, fmt.Errorf("from game: %w", d.Err())
+	}
+	if copy(req.Challenge[:], d.Raw(len(req.Challenge))); d.Err() != nil {
+		return nil, fmt.Errorf("challenge: %w", d.Err())
+	}
+	if req.Filter = d.CString(); d.Err() != nil {
+		return nil, fmt.Errorf("filter: %w", d.Err())
+	}
+
+	fields := d.CString()
+	if err := d.Err(); err != nil {
+		return nil, fmt.Errorf("fields: %w", err)
+	}
+	for _, field := range strings.Split(fields, `\`) {
+		if field != "" {
+			req.Fields = append(req.Fields, field)
+		}
+	}
+
+	if req.Options = d.Uint32(); d.Err() != nil {
+		return nil, fmt.Errorf("options: %w", d.Err())
+	}
+	if req.Options&OptionAlternateSourceIP != 0 {
+		if req.SourceIP = d.IP(); d.Err() != nil {
+			return nil, fmt.Errorf("source ip: %w", d.Err())
+		}
+	}
+	if req.Options&OptionLimitResultCount != 0 {
+		if req.MaxResults = int(d.Uint32()); d.Err() != nil {
+			return nil, fmt.Errorf("max results: %w", d.Err())
+		}
+	}
+
+	return req, nil
+}
+
+// ListServer A server entry in a list response.
+type ListServer struct {
+	Addr         netip.AddrPort // Public query address
+	PrivateAddr  netip.AddrPort // Optional address within the server's local network
+	NATNegotiate bool           // Whether clients need to use NAT negotiation to connect
+	Values       []string       // Values of the requested fields (in request order)
+}
+
+// AppendList Appends the (unencrypted) list response to dst. Fields must match the fields of the request.
+func AppendList(dst []byte, clientIP netip.Addr, fields []string, servers []ListServer) ([]byte, error) {
+	if len(fields) > 0xFF {
+		return nil, fmt.Errorf("too many fields: %d", len(fields))
+	}
+
+	e := codec.NewEncoder(dst)
+	e.AddrPort(netip.AddrPortFrom(clientIP, DefaultQueryPort))
+
+	e.Uint8(byte(len(fields)))
+	for _, field := range fields {
+		e.Uint8(keyTypeString)
+		e.CString(field)
+	}
+
+	// No popular values, all values are sent inline
+	e.Uint8(0)
+
+	for _, server := range servers {
+		if len(server.Values) != len(fields) {
+			return nil, fmt.Errorf("server %s: got %d values for %d fields", server.Addr, len(server.Values), len(fields))
+		}
+
+		var flags byte
+		if server.Addr.Port() != DefaultQueryPort {
+			flags |= flagNonStandardPort
+		}
+		if server.PrivateAddr.IsValid() {
+			flags |= flagPrivateIP
+			if server.PrivateAddr.Port() != DefaultQueryPort {
+				flags |= flagNonStandardPrivatePort
+			}
+		}
+		if server.NATNegotiate {
+			flags |= flagConnectNegotiate
+		}
+		if len(fields) > 0 {
+			flags |= flagHasKeys
+		}
+
+		e.Uint8(flags)
+		e.IP(server.Addr.Addr())
+		if flags&flagNonStandardPort != 0 {
+			e.Uint16(server.Addr.Port())
+		}
+		if flags&flagPrivateIP != 0 {
+			e.IP(server.PrivateAddr.Addr())
+		}
+		if flags&flagNonStandardPrivatePort != 0 {
+			e.Uint16(server.PrivateAddr.Port())
+		}
+		for _, value := range server.Values {
+			e.Uint8(valueInline)
+			e.CString(value)
+		}
+	}
+
+	// Terminated by an entry without flags and a broadcast address
+	e.Uint8(0)
+	e.IP(netip.AddrFrom4([4]byte{0xFF, 0xFF, 0xFF, 0xFF}))
+	return e.Bytes(), nil
+}
+
+// AppendAddress Appends the response to a list request with OptionNoServerList to dst, which only contains the client's
+// public address.
+func AppendAddress(dst []byte, clientIP netip.Addr) []byte {
+	e := codec.NewEncoder(dst)
+	e.AddrPort(netip.AddrPortFrom(clientIP, DefaultQueryPort))
+	return e.Bytes()
+}
@@ -0,0 +1,154 @@
+package serverbrowser
+
+import (
+	"bytes"
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadRequest(t *testing.T) {
+	t.Run("reads request", func(t *testing.T) {
+		// GIVEN
+		r := bytes.NewReader([]byte{0x00, 0x05, 0x00, 'a', 'b', 'c'})
+
+		// WHEN
+		reqType, body, err := ReadRequest(r)
+
+		// THEN
+		require.NoError(t, err)
+		assert.Equal(t, RequestServerList, reqType)
+		assert.Equal(t, []byte("ab"), body)
+		assert.Equal(t, 1, r.Len())
+	})
+
+	t.Run("fails for invalid length", func(t *testing.T) {
+		// WHEN
+		_, _, err := ReadRequest(bytes.NewReader([]byte{0x00, 0x02, 0x00}))
+
+		// THEN
+		assert.ErrorContains(t, err, "invalid request length: 2")
+	})
+}
+
+func TestParseListRequest(t *testing.T) {
+	type test struct {
+		name            string
+		givenBody       []byte
+		expectedRequest *ListRequest
+		wantErrContains string
+	}
+
+	tests := []test{
+		{
+			name:      "parses request",
+			givenBody: listRequestBody(0, nil),
+			expectedRequest: &ListRequest{
+				ProtocolVersion: 1,
+				EncodingVersion: 3,
+				GameVersion:     0,
+				QueryGame:       "battlefield2",
+				FromGame:        "battlefield2",
+				Challenge:       [8]byte{'a', 'b', 'c', 'd', 'e', 'f', 'g', 'h'},
+				Filter:          "numplayers>0",
+				Fields:          []string{"hostname", "numplayers"},
+			},
+		},
+		{
+			name:      "parses optional source ip and result limit",
+			givenBody: listRequestBody(OptionAlternateSourceIP|OptionLimitResultCount, []byte{10, 0, 0, 1, 0, 0, 0, 25}),
+			expectedRequest: &ListRequest{
+				ProtocolVersion: 1,
+				EncodingVersion: 3,
+				QueryGame:       "battlefield2",
+				FromGame:        "battlefield2",
+				Challenge:       [8]byte{'a', 'b', 'c', 'd', 'e', 'f', 'g', 'h'},
+				Filter:          "numplayers>0",
+				Fields:          []string{"hostname", "numplayers"},
+				Options:         OptionAlternateSourceIP | OptionLimitResultCount,
+				SourceIP:        netip.MustParseAddr("10.0.0.1"),
+				MaxResults:      25,
+			},
+		},
+		{
+			name:            "fails for missing result limit",
+			givenBody:       listRequestBody(OptionLimitResultCount, nil),
+			wantErrContains: "max results: data too short",
+		},
+		{
+			name:            "fails for unterminated game name",
+			givenBody:       []byte{0x01, 0x03, 0x00, 0x00, 0x00, 0x00, 'b', 'f'},
+			wantErrContains: "query game: missing string terminator",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// WHEN
+			req, err := ParseListRequest(tt.givenBody)
+
+			// THEN
+			if tt.wantErrContains != "" {
+				require.ErrorContains(t, err, tt.wantErrContains)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, tt.expectedRequest, req)
+			}
+		})
+	}
+}
+
+func TestAppendList(t *testing.T) {
+	t.Run("appends list", func(t *testing.T) {
+		// GIVEN
+		servers := []ListServer{
+			{
+				Addr:   netip.MustParseAddrPort("1.2.3.4:6500"),
+				Values: []string{"server one"},
+			},
+			{
+				Addr:         netip.MustParseAddrPort("5.6.7.8:29900"),
+				PrivateAddr:  netip.MustParseAddrPort("192.168.1.2:6500"),
+				NATNegotiate: true,
+				Values:       []string{"server\x00two"},
+			},
+		}
+
+		// WHEN
+		b, err := AppendList(nil, netip.MustParseAddr("9.9.9.9"), []string{"hostname"}, servers)
+
+		// THEN
+		require.NoError(t, err)
+		expected := []byte{
+			9, 9, 9, 9, 0x19, 0x64, // Client ip and default port
+			1, 0x00, 'h', 'o', 's', 't', 'n', 'a', 'm', 'e', 0x00, // Fields
+			0, // Popular values
+			flagHasKeys, 1, 2, 3, 4,
+			0xFF, 's', 'e', 'r', 'v', 'e', 'r', ' ', 'o', 'n', 'e', 0x00,
+			flagHasKeys | flagConnectNegotiate | flagPrivateIP | flagNonStandardPort, 5, 6, 7, 8, 0x74, 0xCC, 192, 168, 1, 2,
+			0xFF, 's', 'e', 'r', 'v', 'e', 'r', 't', 'w', 'o', 0x00,
+			0x00, 0xFF, 0xFF, 0xFF, 0xFF,
+		}
+		assert.Equal(t, expected, b)
+	})
+
+	t.Run("fails for value count not matching fields", func(t *testing.T) {
+		// GIVEN
+		servers := []ListServer{{Addr: netip.MustParseAddrPort("1.2.3.4:6500")}}
+
+		// WHEN
+		_, err := AppendList(nil, netip.MustParseAddr("9.9.9.9"), []string{"hostname"}, servers)
+
+		// THEN
+		assert.ErrorContains(t, err, "got 0 values for 1 fields")
+	})
+}
+
+func listRequestBody(options uint32, extra []byte) []byte {
+	b := []byte{0x01, 0x03, 0x00, 0x00, 0x00, 0x00}
+	b = append(b, "battlefield2\x00battlefield2\x00abcdefghnumplayers>0\x00\\hostname\\numplayers\x00"...)
+	b = append(b, byte(options>>24), byte(options>>16), byte(options>>8), byte(options))
+	return append(b, extra...)
+}
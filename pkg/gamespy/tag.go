@@ -0,0 +1,131 @@
+package gamespy
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+const tagName = "gamespy"
+
+// selection Determines which of the values of a duplicated key are bound to a field.
+type selection int
+
+const (
+	selectLast  selection = iota // Bind the last value (default, mimics the JSON library)
+	selectFirst                  // Bind the first value
+	selectAll                    // Bind all values to a slice field
+)
+
+var (
+	stringMapType        = reflect.TypeFor[map[string]string]()
+	keyValuePairListType = reflect.TypeFor[[]KeyValuePair]()
+)
+
+// fieldTag The parsed gamespy tag of a struct field in format "key[,option]...".
+type fieldTag struct {
+	key string
+	// unit is the unit of time.Time (since the unix epoch) and time.Duration values, set via the unit option
+	// (one of s, ms, us or ns, defaults to s)
+	unit time.Duration
+	// required fails binding if the key is missing, set via the required option
+	required bool
+	// defaultValue is bound if the key is missing, set via the default=value option
+	defaultValue *string
+	// selection is set via the first, last and all options
+	selection selection
+	// remain collects all keys not bound to any other field, set via the remain option on a field without key
+	// (field must be a map[string]string or []KeyValuePair)
+	remain bool
+}
+
+func parseTag(t reflect.Type, i int) (fieldTag, error) {
+	field := t.Field(i)
+	key, options, _ := strings.Cut(field.Tag.Get(tagName), ",")
+	tag := fieldTag{
+		key:  key,
+		unit: time.Second,
+	}
+
+	selections := 0
+	for option := range strings.SplitSeq(options, ",") {
+		name, value, _ := strings.Cut(option, "=")
+		switch name {
+		case "":
+		case "unit":
+			unit, ok := units[value]
+			if !ok {
+				return fieldTag{}, fmt.Errorf("%s.%s: invalid unit: %q", t.Name(), field.Name, value)
+			}
+			tag.unit = unit
+		case "required":
+			tag.required = true
+		case "default":
+			tag.defaultValue = &value
+		case "first":
+			tag.selection = selectFirst
+			selections++
+		case "last":
+			tag.selection = selectLast
+			selections++
+		case "all":
+			tag.selection = selectAll
+			selections++
+		case "remain":
+			tag.remain = true
+		default:
+			return fieldTag{}, fmt.Errorf("%s.%s: unknown tag option: %q", t.Name(), field.Name, name)
+		}
+	}
+
+	switch {
+	case selections > 1:
+		return fieldTag{}, fmt.Errorf("%s.%s: tag options first, last and all are mutually exclusive", t.Name(), field.Name)
+	case tag.required && tag.defaultValue != nil:
+		return fieldTag{}, fmt.Errorf("%s.%s: tag options required and default are mutually exclusive", t.Name(), field.Name)
+	case tag.selection == selectAll && (field.Type.Kind() != reflect.Slice || field.Type == byteSliceType):
+		return fieldTag{}, fmt.Errorf("%s.%s: tag option all requires a slice field", t.Name(), field.Name)
+	case tag.remain && tag.key != "":
+		return fieldTag{}, fmt.Errorf("%s.%s: tag option remain cannot be used with a key", t.Name(), field.Name)
+	case tag.remain && field.Type != stringMapType && field.Type != keyValuePairListType:
+		return fieldTag{}, fmt.Errorf("%s.%s: tag option remain requires a map[string]string or []KeyValuePair field", t.Name(), field.Name)
+	}
+
+	return tag, nil
+}
+
+var units = map[string]time.Duration{
+	"s":  time.Second,
+	"ms": time.Millisecond,
+	"us": time.Microsecond,
+	"ns": time.Nanosecond,
+}
+
+// fromUnix Returns the time n units after the unix epoch.
+func fromUnix(n int64, unit time.Duration) time.Time {
+	switch unit {
+	case time.Millisecond:
+		return time.UnixMilli(n).UTC()
+	case time.Microsecond:
+		return time.UnixMicro(n).UTC()
+	case time.Nanosecond:
+		return time.Unix(0, n).UTC()
+	default:
+		return time.Unix(n, 0).UTC()
+	}
+}
+
+// toUnix Returns the number of units elapsed since the unix epoch.
+func toUnix(t time.Time, unit time.Duration) int64 {
+	switch unit {
+	case time.Millisecond:
+		return t.UnixMilli()
+	case time.Microsecond:
+		return t.UnixMicro()
+	case time.Nanosecond:
+		return t.UnixNano()
+	default:
+		return t.Unix()
+	}
+}
@@ -51,6 +51,23 @@ func GenerateProof(nick, hash, c1, c2 string) string {
 	return ComputeMD5(b.String())
 }
 
+// GeneratePlayerAuthResponse Returns the response players send to authenticate against the stats server: the MD5 of
+// their password hash followed by the server's challenge.
+func GeneratePlayerAuthResponse(hash, challenge string) string {
+	return ComputeMD5(hash + challenge)
+}
+
+// XOR XORs b with the repeated key in place. Several services use this to obfuscate packets.
+func XOR(b []byte, key string) {
+	if key == "" {
+		return
+	}
+
+	for i := range b {
+		b[i] ^= key[i%len(key)]
+	}
+}
+
 func RandString(n int) string {
 	data := make([]byte, n)
 	for i := 0; i < n; i++ {
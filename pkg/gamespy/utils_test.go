@@ -47,6 +47,36 @@ func TestGenerateProof(t *testing.T) {
 	assert.Equal(t, expected, actual)
 }
 
+func TestGeneratePlayerAuthResponse(t *testing.T) {
+	// GIVEN
+	hash := "131def0e93e67e3e62b39d74d6316511"
+	challenge := "4Jp6A4kK02"
+	expected := "e53846ff5a6127a9cdbc0f9e698da058"
+
+	// WHEN
+	actual := GeneratePlayerAuthResponse(hash, challenge)
+
+	// THEN
+	assert.Equal(t, expected, actual)
+}
+
+func TestXOR(t *testing.T) {
+	// GIVEN
+	b := []byte(`\ka\`)
+
+	// WHEN
+	XOR(b, "gamespy")
+
+	// THEN
+	assert.Equal(t, []byte{0x3B, 0x0A, 0x0C, 0x39}, b)
+
+	// WHEN
+	XOR(b, "gamespy")
+
+	// THEN
+	assert.Equal(t, []byte(`\ka\`), b)
+}
+
 func TestRandString(t *testing.T) {
 	type test struct {
 		name   string
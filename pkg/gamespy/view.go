@@ -0,0 +1,142 @@
+package gamespy
+
+import (
+	"bytes"
+	"errors"
+	"iter"
+	"slices"
+)
+
+// span The offsets of a key/value pair within a packet body. The value starts right after the separator following
+// the key.
+type span struct {
+	keyStart, keyEnd, valueEnd int
+}
+
+// PacketView A read-only packet that references the bytes it was parsed from. Keys and values are only converted to
+// strings when accessed, which avoids allocations for packets of which only a few keys are of interest.
+// The bytes must not be modified while the view is in use.
+type PacketView struct {
+	body  []byte
+	spans []span
+}
+
+// NewPacketView Parses b without copying it.
+func NewPacketView(b []byte) (*PacketView, error) {
+	body, spans, err := parseIndex(b)
+	if err != nil {
+		return nil, err
+	}
+	return &PacketView{
+		body:  body,
+		spans: spans,
+	}, nil
+}
+
+// Len Returns the number of key/value pairs.
+func (v *PacketView) Len() int {
+	return len(v.spans)
+}
+
+// LookupBytes Checks if key exists and returns the first value with a matching key. The value references the parsed
+// bytes.
+func (v *PacketView) LookupBytes(key string) ([]byte, bool) {
+	for _, s := range v.spans {
+		if string(v.body[s.keyStart:s.keyEnd]) == key {
+			return v.body[s.keyEnd+1 : s.valueEnd], true
+		}
+	}
+	return nil, false
+}
+
+// Lookup Checks if key exists and returns the first value with a matching key.
+func (v *PacketView) Lookup(key string) (string, bool) {
+	value, ok := v.LookupBytes(key)
+	return string(value), ok
+}
+
+// Get Retrieves the first value with a matching key.
+func (v *PacketView) Get(key string) string {
+	value, _ := v.Lookup(key)
+	return value
+}
+
+// GetAll Retrieves all values with a matching key. Returns nil if key does not exist in packet.
+func (v *PacketView) GetAll(key string) []string {
+	var values []string
+	for _, s := range v.spans {
+		if string(v.body[s.keyStart:s.keyEnd]) == key {
+			values = append(values, string(v.body[s.keyEnd+1:s.valueEnd]))
+		}
+	}
+	return values
+}
+
+// All Returns an iterator over the packet's keys and values, which reference the parsed bytes.
+func (v *PacketView) All() iter.Seq2[[]byte, []byte] {
+	return func(yield func([]byte, []byte) bool) {
+		for _, s := range v.spans {
+			if !yield(v.body[s.keyStart:s.keyEnd], v.body[s.keyEnd+1:s.valueEnd]) {
+				return
+			}
+		}
+	}
+}
+
+// Packet Returns a copy of the view as a Packet.
+func (v *PacketView) Packet() *Packet {
+	return &Packet{
+		body:  string(v.body),
+		spans: slices.Clone(v.spans),
+	}
+}
+
+// parseIndex Returns the body of the packet b along with the spans of its key/value pairs. The body references b.
+func parseIndex(b []byte) ([]byte, []span, error) {
+	body, n, err := packetBody(b)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	spans := make([]span, n)
+	pos := 0
+	for i := range spans {
+		s := nextSpan(body, pos)
+		spans[i] = s
+		pos = s.valueEnd + 1
+	}
+	return body, spans, nil
+}
+
+// packetBody Returns the part of b between the prefix and the \final\ suffix along with the number of key/value pairs
+// it contains.
+func packetBody(b []byte) ([]byte, int, error) {
+	if len(b) < len(prefix)+len(suffix) || !bytes.HasPrefix(b, []byte(prefix)) || !bytes.HasSuffix(b, []byte(suffix)) {
+		return nil, 0, errors.New("gamespy packet string is malformed")
+	}
+
+	body := b[len(prefix) : len(b)-len(suffix)]
+	elements := bytes.Count(body, []byte(separator)) + 1
+	if elements%2 != 0 {
+		return nil, 0, errors.New("gamespy packet string contains key without corresponding value")
+	}
+
+	return body, elements / 2, nil
+}
+
+// nextSpan Returns the span of the key/value pair starting at pos. The body must contain another pair.
+func nextSpan(body []byte, pos int) span {
+	keyEnd := pos + bytes.IndexByte(body[pos:], separator[0])
+	valueEnd := bytes.IndexByte(body[keyEnd+1:], separator[0])
+	if valueEnd == -1 {
+		valueEnd = len(body)
+	} else {
+		valueEnd += keyEnd + 1
+	}
+
+	return span{
+		keyStart: pos,
+		keyEnd:   keyEnd,
+		valueEnd: valueEnd,
+	}
+}
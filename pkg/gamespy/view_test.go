@@ -0,0 +1,131 @@
+package gamespy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewPacketView(t *testing.T) {
+	type test struct {
+		name            string
+		bytes           []byte
+		expectedPacket  *Packet
+		wantErrContains string
+	}
+
+	tests := []test{
+		{
+			name:  "parses packet",
+			bytes: []byte("\\login\\\\uniquenick\\some-nick\\sdkrevision\\3\\sdkrevision\\4\\id\\1\\final\\"),
+			expectedPacket: &Packet{
+				elements: []KeyValuePair{
+					{Key: "login", Value: ""},
+					{Key: "uniquenick", Value: "some-nick"},
+					{Key: "sdkrevision", Value: "3"},
+					{Key: "sdkrevision", Value: "4"},
+					{Key: "id", Value: "1"},
+				},
+			},
+		},
+		{
+			name:  "parses packet ending with empty value",
+			bytes: []byte("\\lc\\1\\login\\\\final\\"),
+			expectedPacket: &Packet{
+				elements: []KeyValuePair{
+					{Key: "lc", Value: "1"},
+					{Key: "login", Value: ""},
+				},
+			},
+		},
+		{
+			name:            "error for bare terminator",
+			bytes:           []byte("\\final\\"),
+			wantErrContains: "gamespy packet string is malformed",
+		},
+		{
+			name:            "error for packet containing uneven number of elements",
+			bytes:           []byte("\\key\\value\\key-without-value\\final\\"),
+			wantErrContains: "gamespy packet string contains key without corresponding value",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// WHEN
+			view, err := NewPacketView(tt.bytes)
+
+			// THEN
+			if tt.wantErrContains != "" {
+				require.ErrorContains(t, err, tt.wantErrContains)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, len(tt.expectedPacket.elements), view.Len())
+				assert.Equal(t, elementsOf(tt.expectedPacket), elementsOf(view.Packet()))
+			}
+		})
+	}
+}
+
+func TestPacketView_Lookup(t *testing.T) {
+	// GIVEN
+	b := []byte("\\uniquenick\\some-nick\\sdkrevision\\3\\sdkrevision\\4\\final\\")
+	view, err := NewPacketView(b)
+	require.NoError(t, err)
+
+	t.Run("returns first value for existing key", func(t *testing.T) {
+		// WHEN
+		value, ok := view.Lookup("sdkrevision")
+
+		// THEN
+		assert.True(t, ok)
+		assert.Equal(t, "3", value)
+		assert.Equal(t, "some-nick", view.Get("uniquenick"))
+	})
+
+	t.Run("returns all values for duplicate key", func(t *testing.T) {
+		// WHEN
+		values := view.GetAll("sdkrevision")
+
+		// THEN
+		assert.Equal(t, []string{"3", "4"}, values)
+		assert.Nil(t, view.GetAll("missing"))
+	})
+
+	t.Run("returns empty value, false for missing key", func(t *testing.T) {
+		// WHEN
+		value, ok := view.Lookup("missing")
+
+		// THEN
+		assert.False(t, ok)
+		assert.Empty(t, value)
+	})
+
+	t.Run("returns bytes referencing parsed bytes", func(t *testing.T) {
+		// WHEN
+		value, ok := view.LookupBytes("uniquenick")
+
+		// THEN
+		require.True(t, ok)
+		assert.Equal(t, []byte("some-nick"), value)
+		assert.Same(t, &b[12], &value[0])
+	})
+}
+
+func TestPacketView_All(t *testing.T) {
+	// GIVEN
+	view, err := NewPacketView([]byte("\\lc\\1\\challenge\\TcP1s0FtTB\\id\\1\\final\\"))
+	require.NoError(t, err)
+
+	// WHEN
+	var keys, values []string
+	for key, value := range view.All() {
+		keys = append(keys, string(key))
+		values = append(values, string(value))
+	}
+
+	// THEN
+	assert.Equal(t, []string{"lc", "challenge", "id"}, keys)
+	assert.Equal(t, []string{"1", "TcP1s0FtTB", "1"}, values)
+}